@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,8 +10,12 @@ import (
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
 	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/discordsync"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
 	"github.com/gdg-garage/garage-trip-api/internal/handlers"
+	"github.com/gdg-garage/garage-trip-api/internal/mailer"
 	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -31,27 +36,79 @@ func main() {
 		}
 	}
 
-	var discordNotifier *notifier.DiscordNotifier
+	// Cache guild roles shared by the auth checks and the notifier
+	var roleCache *guildcache.Cache
 	if discordSession != nil {
-		discordNotifier = notifier.NewDiscordNotifier(
+		roleCache = guildcache.NewCache(discordSession, cfg.DiscordGuildID, cfg.DiscordCacheTTL)
+		if cfg.DiscordGateway {
+			roleCache.AddHandlers(discordSession)
+			// Role changes made directly in Discord are brought back into the database
+			discordsync.NewSyncer(db, roleCache, cfg.DiscordGuildID).AddHandlers(discordSession)
+			discordSession.Identify.Intents = guildcache.Intents
+			if err := discordSession.Open(); err != nil {
+				log.Fatalf("Failed to connect to the Discord gateway: %v", err)
+			}
+			defer discordSession.Close()
+		}
+	}
+
+	// Initialize Mailer
+	var sender mailer.Sender
+	if cfg.SMTPHost != "" {
+		sender = mailer.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+
+	// Stay nil interfaces without a backend, handlers check for a nil notifier and role manager
+	var discordNotifier notifier.Notifier
+	var roleManager notifier.RoleManager
+	if discordSession != nil {
+		n := notifier.NewDiscordNotifier(
 			discordSession,
+			roleCache,
 			cfg.DiscordAchievementsChannelID,
 			cfg.DiscordRegistrationsChannelID,
 			cfg.DiscordGuildID,
 			cfg.AchievementPrefix,
 		)
+		discordNotifier = n
+		roleManager = n
+	}
+	var appNotifier notifier.Notifier
+	fanout := notifier.NewFanoutNotifier(discordNotifier, notifier.ChannelsFromConfig(cfg, sender)...)
+	notifiers := fanout.Notifiers()
+	if len(notifiers) > 0 {
+		appNotifier = fanout
 	}
 
-	authHandler := auth.NewAuthHandler(cfg, db, discordSession)
-	registrationHandler := handlers.NewRegistrationHandler(db, discordNotifier, authHandler, cfg)
-	achievementHandler := handlers.NewAchievementHandler(db, discordNotifier, authHandler, cfg)
+	authHandler := auth.NewAuthHandler(cfg, db, roleCache)
+	emailLoginHandler := auth.NewEmailLoginHandler(db, cfg, sender, authHandler)
+	registrationHandler := handlers.NewRegistrationHandler(db, appNotifier, roleManager, authHandler, cfg)
+	achievementHandler := handlers.NewAchievementHandler(db, appNotifier, roleManager, authHandler, cfg)
 	apiKeyHandler := handlers.NewAPIKeyHandler(db, authHandler)
+	eventHandler := handlers.NewEventHandler(db, appNotifier, roleManager, authHandler)
+	paymentHandler := handlers.NewPaymentHandler(db, appNotifier, roleManager, authHandler)
+	checkInHandler := handlers.NewCheckInHandler(db, authHandler)
+	roomHandler := handlers.NewRoomHandler(db, authHandler)
+	carpoolHandler := handlers.NewCarpoolHandler(db, appNotifier, authHandler)
+	mealHandler := handlers.NewMealHandler(db, authHandler)
+	expenseHandler := handlers.NewExpenseHandler(db, appNotifier, authHandler)
+	shiftHandler := handlers.NewShiftHandler(db, authHandler)
+	sessionHandler := handlers.NewSessionHandler(db, authHandler)
+	calendarHandler := handlers.NewCalendarHandler(db, authHandler, cfg)
+	inviteHandler := handlers.NewInviteHandler(db, authHandler, cfg)
+	outboxHandler := handlers.NewOutboxHandler(db, authHandler)
+	discordChangeHandler := handlers.NewDiscordChangeHandler(db, authHandler)
+
+	// Deliver the side effects written to the outbox, each notification once per channel
+	if appNotifier != nil {
+		go outbox.NewWorker(db, notifiers, roleManager).Run(context.Background())
+	}
 
 	// Initialize Router
 	r := chi.NewRouter()
 
 	// Register Routes
-	handlers.RegisterRoutes(r, cfg, authHandler, registrationHandler, achievementHandler, apiKeyHandler)
+	handlers.RegisterRoutes(r, cfg, authHandler, registrationHandler, achievementHandler, apiKeyHandler, eventHandler, paymentHandler, checkInHandler, roomHandler, carpoolHandler, mealHandler, expenseHandler, shiftHandler, sessionHandler, calendarHandler, inviteHandler, emailLoginHandler, outboxHandler, discordChangeHandler)
 
 	// Start Server
 	log.Printf("Starting server on port %s", cfg.Port)
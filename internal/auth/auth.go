@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,9 +13,11 @@ import (
 
 	"time"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/expenses"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
@@ -25,16 +30,19 @@ const (
 	DiscordUserAPI           = "https://discord.com/api/users/@me"
 	DiscordUserGuildsAPI     = "https://discord.com/api/users/@me/guilds"
 	TokenDuration            = 24 * time.Hour
+	// StateCookie binds the OAuth2 callback to the browser which started the login
+	StateCookie   = "oauth_state"
+	StateDuration = 10 * time.Minute
 )
 
 type AuthHandler struct {
 	oauthConfig *oauth2.Config
 	db          *gorm.DB
 	cfg         *config.Config
-	discord     *discordgo.Session
+	roles       *guildcache.Cache
 }
 
-func NewAuthHandler(cfg *config.Config, db *gorm.DB, discord *discordgo.Session) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, db *gorm.DB, roles *guildcache.Cache) *AuthHandler {
 	return &AuthHandler{
 		oauthConfig: &oauth2.Config{
 			ClientID:     cfg.DiscordClientID,
@@ -46,36 +54,73 @@ func NewAuthHandler(cfg *config.Config, db *gorm.DB, discord *discordgo.Session)
 				TokenURL: DiscordTokenEndpoint,
 			},
 		},
-		db:      db,
-		cfg:     cfg,
-		discord: discord,
+		db:    db,
+		cfg:   cfg,
+		roles: roles,
 	}
 }
 
 type LoginResponse struct {
-	Status   int    `header:"-" status:"307"`
-	Location string `header:"Location"`
+	Status    int    `header:"-" status:"307"`
+	Location  string `header:"Location"`
+	SetCookie string `header:"Set-Cookie"`
 }
 
 func (h *AuthHandler) HandleLogin(ctx context.Context, input *struct{}) (*LoginResponse, error) {
-	url := h.oauthConfig.AuthCodeURL("state", oauth2.AccessTypeOnline)
+	stateBytes := make([]byte, 32)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate state")
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	url := h.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline)
 	fmt.Printf("Login URL: %s\n", url)
 	return &LoginResponse{
-		Status:   307,
-		Location: url,
+		Status:    307,
+		Location:  url,
+		SetCookie: stateCookie(state, time.Now().Add(StateDuration)),
 	}, nil
 }
 
+// stateCookie returns the Set-Cookie value holding the OAuth2 state, a past expiry clears it
+func stateCookie(state string, expires time.Time) string {
+	cookie := &http.Cookie{
+		Name:     StateCookie,
+		Value:    state,
+		Expires:  expires,
+		HttpOnly: true,
+		Path:     "/auth/discord",
+		// Sent on the top level redirect back from Discord
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+	}
+	return cookie.String()
+}
+
 type CallbackInput struct {
-	Code string `query:"code" doc:"OAuth2 callback code"`
+	AuthInput `doc:"Optional, links the Discord account to a signed in guest"`
+	Code      string `query:"code" doc:"OAuth2 callback code"`
+	State     string `query:"state" doc:"OAuth2 state, must match the state cookie set by the login"`
+}
+
+type MeRegistration struct {
+	models.Registration
+	Balance        billing.Balance         `json:"balance"`
+	Quote          billing.Quote           `json:"quote" doc:"Computed price with its breakdown"`
+	VariableSymbol string                  `json:"variable_symbol" doc:"Variable symbol to use for bank transfers"`
+	PaymentString  string                  `json:"payment_string,omitempty" doc:"SPAYD string settling the outstanding balance"`
+	Ticket         string                  `json:"ticket,omitempty" doc:"Signed check-in ticket, only for confirmed registrations"`
+	Rooms          []models.RoomAssignment `json:"rooms,omitempty" doc:"Assigned rooms with the nights spent in them"`
+	Expenses       *expenses.Summary       `json:"expenses,omitempty" doc:"Balance of the shared expenses and the transfers settling it"`
 }
 
 type MeResponse struct {
 	Body struct {
-		Username      string                `json:"username"`
-		Email         string                `json:"email"`
-		Paid          bool                  `json:"paid"`
-		Registrations []models.Registration `json:"registrations"`
+		Username      string           `json:"username"`
+		Email         string           `json:"email"`
+		Guest         bool             `json:"guest" doc:"Signed up through an invite, Discord can be linked by signing in with it"`
+		Paid          bool             `json:"paid"`
+		Registrations []MeRegistration `json:"registrations"`
 	}
 }
 
@@ -102,18 +147,71 @@ func (h *AuthHandler) HandleMe(ctx context.Context, input *MeRequest) (*MeRespon
 	res := &MeResponse{}
 	res.Body.Username = user.Username
 	res.Body.Email = user.Email
+	res.Body.Guest = user.Guest
 
-	// 1. Check Paid status
-	res.Body.Paid = h.IsPaid(user.DiscordID, input.Event)
-
-	// 2. Fetch Registration
+	// 1. Fetch Registration
 	var regs []models.Registration
 	query := h.db.Preload("User").Where("user_id = ?", user.ID)
 	if input.Event != "" {
 		query = query.Where("event = ?", input.Event)
 	}
-	if err := query.Find(&regs).Error; err == nil {
-		res.Body.Registrations = regs
+	if err := query.Find(&regs).Error; err != nil {
+		return res, nil
+	}
+
+	var events []models.Event
+	eventsByCode := make(map[string]models.Event)
+	if err := h.db.Find(&events).Error; err == nil {
+		for _, e := range events {
+			eventsByCode[e.Code] = e
+		}
+	}
+
+	balances, err := billing.Balances(h.db, eventsByCode, regs)
+	if err != nil {
+		log.Printf("Failed to compute balances: %v\n", err)
+	}
+
+	regIDs := make([]uint, len(regs))
+	for i, reg := range regs {
+		regIDs[i] = reg.ID
+	}
+	var assignments []models.RoomAssignment
+	if len(regIDs) > 0 {
+		if err := h.db.Preload("Room").Where("registration_id IN ?", regIDs).Order("from_date ASC").Find(&assignments).Error; err != nil {
+			log.Printf("Failed to fetch room assignments: %v\n", err)
+		}
+	}
+
+	res.Body.Registrations = make([]MeRegistration, len(regs))
+	for i, reg := range regs {
+		res.Body.Registrations[i] = MeRegistration{
+			Registration:   reg,
+			Balance:        balances[reg.ID],
+			Quote:          billing.Price(eventsByCode[reg.Event], reg),
+			VariableSymbol: reg.VariableSymbol(),
+		}
+		if h.cfg.PaymentIBAN != "" && balances[reg.ID].Outstanding > 0 {
+			res.Body.Registrations[i].PaymentString = h.BankAccount().PaymentFor(reg, balances[reg.ID]).String()
+		}
+		if reg.Confirmed() {
+			res.Body.Registrations[i].Ticket = h.Ticket(reg.ID)
+		}
+		for _, a := range assignments {
+			if a.RegistrationID == reg.ID {
+				res.Body.Registrations[i].Rooms = append(res.Body.Registrations[i].Rooms, a)
+			}
+		}
+		if list, err := expenses.Load(h.db, reg.EventID); err != nil {
+			log.Printf("Failed to fetch expenses: %v\n", err)
+		} else if summary, ok := expenses.UserSummary(list, user.ID); ok {
+			res.Body.Registrations[i].Expenses = &summary
+		}
+
+		// 2. Check Paid status
+		if input.Event != "" && reg.Event == input.Event {
+			res.Body.Paid = h.PaidStatus(reg, eventsByCode[reg.Event], balances[reg.ID])
+		}
 	}
 
 	return res, nil
@@ -143,12 +241,37 @@ func (h *AuthHandler) HandleLogout(ctx context.Context, input *struct{}) (*Logou
 	return res, nil
 }
 
-func (h *AuthHandler) IsPaid(discordID string, event string) bool {
-	if event == "" {
+// BankAccount returns the account attendees pay to
+func (h *AuthHandler) BankAccount() billing.BankAccount {
+	return billing.BankAccount{
+		IBAN:          h.cfg.PaymentIBAN,
+		BIC:           h.cfg.PaymentBIC,
+		RecipientName: h.cfg.PaymentRecipientName,
+	}
+}
+
+// IsPaid reports whether the registration is paid according to the local payment ledger
+func (h *AuthHandler) IsPaid(registration models.Registration, event models.Event) bool {
+	balance, err := billing.RegistrationBalance(h.db, event, registration)
+	if err != nil {
+		log.Printf("Error computing balance of registration %d: %v\n", registration.ID, err)
 		return false
 	}
-	roleName := event + "::paid"
-	hasRole, err := h.CheckRole(discordID, roleName)
+	return h.PaidStatus(registration, event, balance)
+}
+
+// PaidStatus decides the paid status from a computed balance. Registrations without
+// any recorded payment fall back to the Discord paid role granted manually by orgs.
+func (h *AuthHandler) PaidStatus(registration models.Registration, event models.Event, balance billing.Balance) bool {
+	if event.Code == "" {
+		return false
+	}
+	if balance.Payments > 0 {
+		return billing.PaidInFull(registration, balance)
+	}
+
+	roleName := event.PaidRole()
+	hasRole, err := h.CheckRole(registration.User.DiscordID, roleName)
 	if err != nil {
 		log.Printf("Error checking paid role %s: %v\n", roleName, err)
 		return false
@@ -157,45 +280,59 @@ func (h *AuthHandler) IsPaid(discordID string, event string) bool {
 }
 
 func (h *AuthHandler) CheckRole(discordID string, roleName string) (bool, error) {
-	if h.discord == nil || h.cfg.DiscordGuildID == "" {
+	// Guests without a linked Discord account hold no roles
+	if h.roles == nil || h.cfg.DiscordGuildID == "" || discordID == "" {
 		return false, nil
 	}
 
-	// 1. Get Role ID
-	roles, err := h.discord.GuildRoles(h.cfg.DiscordGuildID)
+	hasRole, err := h.roles.HasRoleNamed(discordID, roleName)
 	if err != nil {
-		return false, huma.Error500InternalServerError("Failed to fetch guild roles: " + err.Error())
+		return false, huma.Error500InternalServerError("Failed to check role " + roleName + ": " + err.Error())
 	}
+	return hasRole, nil
+}
 
-	roleID := ""
-	for _, r := range roles {
-		if r.Name == roleName {
-			roleID = r.ID
-			break
-		}
+// PrefetchMembers loads the roles of all guild members at once before checking the roles of many users
+func (h *AuthHandler) PrefetchMembers() {
+	if h.roles == nil || h.cfg.DiscordGuildID == "" {
+		return
+	}
+	// Lookups fall back to fetching members one by one
+	if err := h.roles.LoadMembers(); err != nil {
+		log.Printf("Failed to prefetch guild members: %v\n", err)
 	}
+}
 
-	if roleID == "" {
-		log.Printf("Role: %s not found\n", roleName)
-		return false, nil
+// IsOrg reports whether the user holds the org role
+func (h *AuthHandler) IsOrg(userID uint) (bool, error) {
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return false, huma.Error404NotFound("User not found")
 	}
+	return h.CheckRole(user.DiscordID, h.cfg.OrgRole)
+}
 
-	// 2. Get Member Information
-	member, err := h.discord.GuildMember(h.cfg.DiscordGuildID, discordID)
+// RequireOrg authorizes the request and ensures the user holds the org role
+func (h *AuthHandler) RequireOrg(ctx context.Context, cookieHeader string) (models.User, error) {
+	var user models.User
+	userID, err := h.Authorize(ctx, cookieHeader)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") {
-			return false, nil
-		}
-		return false, huma.Error500InternalServerError("Failed to fetch guild member: " + err.Error())
+		return user, err
 	}
 
-	for _, r := range member.Roles {
-		if r == roleID {
-			return true, nil
-		}
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return user, huma.Error404NotFound("User not found")
+	}
+
+	hasRole, err := h.CheckRole(user.DiscordID, h.cfg.OrgRole)
+	if err != nil {
+		return user, err
+	}
+	if !hasRole {
+		return user, huma.Error403Forbidden("Access denied: missing " + h.cfg.OrgRole + " role")
 	}
 
-	return false, nil
+	return user, nil
 }
 
 // Authorize returns the user ID from context or parses the auth_token from a Cookie header string
@@ -204,16 +341,7 @@ func (h *AuthHandler) Authorize(ctx context.Context, cookieHeader string) (uint,
 		return 0, huma.Error401Unauthorized("Unauthorized: No cookies found")
 	}
 
-	cookieValue := ""
-	parts := strings.Split(cookieHeader, ";")
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if strings.HasPrefix(p, "auth_token=") {
-			cookieValue = strings.TrimPrefix(p, "auth_token=")
-			break
-		}
-	}
-
+	cookieValue := CookieValue(cookieHeader, "auth_token")
 	if cookieValue == "" {
 		return 0, huma.Error401Unauthorized("Unauthorized: No token found")
 	}
@@ -238,10 +366,21 @@ func (h *AuthHandler) Authorize(ctx context.Context, cookieHeader string) (uint,
 	return 0, huma.Error401Unauthorized("Unauthorized")
 }
 
+// CookieValue returns the value of the named cookie from a Cookie header string
+func CookieValue(cookieHeader string, name string) string {
+	for _, p := range strings.Split(cookieHeader, ";") {
+		p = strings.TrimSpace(p)
+		if value, ok := strings.CutPrefix(p, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
 type CallbackResponse struct {
-	Status    int    `header:"-" status:"307"`
-	Location  string `header:"Location"`
-	SetCookie string `header:"Set-Cookie"`
+	Status    int      `header:"-" status:"307"`
+	Location  string   `header:"Location"`
+	SetCookie []string `header:"Set-Cookie"`
 }
 
 func (h *AuthHandler) HandleCallback(ctx context.Context, input *CallbackInput) (*CallbackResponse, error) {
@@ -249,6 +388,12 @@ func (h *AuthHandler) HandleCallback(ctx context.Context, input *CallbackInput)
 		return nil, huma.Error400BadRequest("Code not found")
 	}
 
+	// A callback started by somebody else could link their Discord account to the signed in guest
+	state := CookieValue(input.Cookie, StateCookie)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(input.State)) != 1 {
+		return nil, huma.Error400BadRequest("Invalid OAuth2 state, start the login again")
+	}
+
 	token, err := h.oauthConfig.Exchange(ctx, input.Code)
 	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to exchange token")
@@ -306,6 +451,17 @@ func (h *AuthHandler) HandleCallback(ctx context.Context, input *CallbackInput)
 	if err := h.db.Where("discord_id = ?", discordUser.ID).FirstOrInit(&user).Error; err != nil {
 		return nil, huma.Error500InternalServerError("Database error")
 	}
+
+	// Guests signing in with Discord get their account linked
+	if guest := h.signedInGuest(ctx, input.Cookie); guest != nil {
+		if user.ID != 0 {
+			return nil, huma.Error409Conflict("This Discord account already belongs to another user, sign out of the guest account first")
+		}
+		user = *guest
+		user.Guest = false
+		fmt.Printf("Linking guest %s to Discord ID %s\n", user.Username, discordUser.ID)
+	}
+
 	user.DiscordID = discordUser.ID
 	user.Username = discordUser.Username
 	user.Email = discordUser.Email
@@ -317,10 +473,41 @@ func (h *AuthHandler) HandleCallback(ctx context.Context, input *CallbackInput)
 		return nil, huma.Error500InternalServerError("Failed to save user")
 	}
 
-	// Generate JWT
-	jwtToken, err := h.GenerateToken(user.ID)
+	cookie, err := h.SessionCookie(user.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to generate token")
+		return nil, err
+	}
+
+	res := &CallbackResponse{
+		Status:   307,
+		Location: h.cfg.FrontendURL,
+	}
+	res.SetCookie = []string{cookie, stateCookie("", time.Unix(0, 0))}
+
+	return res, nil
+}
+
+// signedInGuest returns the guest the optional cookie belongs to, nil for anyone else
+func (h *AuthHandler) signedInGuest(ctx context.Context, cookieHeader string) *models.User {
+	if cookieHeader == "" {
+		return nil
+	}
+	userID, err := h.Authorize(ctx, cookieHeader)
+	if err != nil {
+		return nil
+	}
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil || !user.Guest {
+		return nil
+	}
+	return &user
+}
+
+// SessionCookie returns the Set-Cookie value signing the user in
+func (h *AuthHandler) SessionCookie(userID uint) (string, error) {
+	jwtToken, err := h.GenerateToken(userID)
+	if err != nil {
+		return "", huma.Error500InternalServerError("Failed to generate token")
 	}
 
 	cookie := &http.Cookie{
@@ -332,14 +519,7 @@ func (h *AuthHandler) HandleCallback(ctx context.Context, input *CallbackInput)
 		SameSite: http.SameSiteNoneMode,
 		Secure:   true,
 	}
-
-	res := &CallbackResponse{
-		Status:   307,
-		Location: h.cfg.FrontendURL,
-	}
-	res.SetCookie = cookie.String()
-
-	return res, nil
+	return cookie.String(), nil
 }
 
 func (h *AuthHandler) GenerateToken(userID uint) (string, error) {
@@ -2,9 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/danielgtaylor/huma/v2"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,7 +22,7 @@ func TestHandleMe(t *testing.T) {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	db.AutoMigrate(&models.User{}, &models.Registration{})
+	database.Migrate(db)
 
 	user := models.User{
 		DiscordID: "123456",
@@ -88,3 +94,48 @@ func TestHandleMe(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleCallback_State(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	guest := models.User{Username: "guest", Guest: true}
+	db.Create(&guest)
+
+	handler := NewAuthHandler(&config.Config{JWTSecret: "test-secret", DiscordRedirectURL: "https://api.example.com/auth/discord/callback"}, db, nil)
+	login, err := handler.HandleLogin(context.Background(), &struct{}{})
+	if err != nil {
+		t.Fatalf("HandleLogin failed: %v", err)
+	}
+	location, err := url.Parse(login.Location)
+	if err != nil {
+		t.Fatalf("invalid login location: %v", err)
+	}
+	state := location.Query().Get("state")
+	if len(state) != 64 || CookieValue(strings.Split(login.SetCookie, ";")[0], StateCookie) != state {
+		t.Fatalf("expected a random state in the URL and the cookie, got %q and %q", state, login.SetCookie)
+	}
+	if again, _ := handler.HandleLogin(context.Background(), &struct{}{}); again.Location == login.Location {
+		t.Error("expected a new state for every login")
+	}
+
+	guestCookie, _ := handler.SessionCookie(guest.ID)
+	guestCookie = strings.Split(guestCookie, ";")[0]
+	for name, cookie := range map[string]string{
+		"MissingCookie": guestCookie,
+		"OtherState":    guestCookie + "; " + StateCookie + "=" + strings.Repeat("0", 64),
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := &CallbackInput{Code: "code", State: state}
+			input.Cookie = cookie
+			_, err := handler.HandleCallback(context.Background(), input)
+			var statusErr huma.StatusError
+			if !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusBadRequest {
+				t.Errorf("expected the callback to be rejected before any linking, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/mailer"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// LoginLinkDuration is how long an emailed login link stays valid
+const LoginLinkDuration = 15 * time.Minute
+
+// EmailLoginHandler signs users in with single-use links sent to the email of their account
+type EmailLoginHandler struct {
+	db          *gorm.DB
+	cfg         *config.Config
+	sender      mailer.Sender
+	authHandler *AuthHandler
+}
+
+func NewEmailLoginHandler(db *gorm.DB, cfg *config.Config, sender mailer.Sender, authHandler *AuthHandler) *EmailLoginHandler {
+	return &EmailLoginHandler{db: db, cfg: cfg, sender: sender, authHandler: authHandler}
+}
+
+type EmailLoginRequest struct {
+	Body struct {
+		Email string `json:"email" format:"email"`
+	}
+}
+
+type EmailLoginResponse struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+type EmailCallbackInput struct {
+	Token string `query:"token" doc:"Token from the login link"`
+}
+
+type EmailConfirmResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+type EmailCallbackResponse struct {
+	Status    int    `header:"-" status:"303"`
+	Location  string `header:"Location"`
+	SetCookie string `header:"Set-Cookie"`
+}
+
+// confirmPage asks for a click before the token is used, mail scanners opening the link cannot use it up
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Sign in to Garage Trip</title></head>
+<body>
+<form method="post" action="{{.}}">
+<button type="submit">Sign in to Garage Trip</button>
+</form>
+</body>
+</html>
+`))
+
+func hashLoginToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *EmailLoginHandler) loginLink(token string) string {
+	return strings.TrimRight(h.cfg.PublicURL, "/") + "/auth/email/callback?token=" + url.QueryEscape(token)
+}
+
+func (h *EmailLoginHandler) HandleEmailLogin(ctx context.Context, input *EmailLoginRequest) (*EmailLoginResponse, error) {
+	if h.sender == nil {
+		return nil, huma.Error503ServiceUnavailable("Email login is not configured")
+	}
+	address, err := mail.ParseAddress(input.Body.Email)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid email address")
+	}
+
+	// The response is the same whether an account exists or not, so emails cannot be probed
+	res := &EmailLoginResponse{}
+	res.Body.Message = "If an account with this email exists, a login link was sent to it"
+
+	var user models.User
+	result := h.db.Where("LOWER(email) = ?", strings.ToLower(address.Address)).Order("id ASC").Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch user: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return res, nil
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate token")
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Only the latest link works
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.LoginToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LoginToken{UserID: user.ID, TokenHash: hashLoginToken(token), ExpiresAt: time.Now().Add(LoginLinkDuration)}).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create login link: " + err.Error())
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nopen this link to sign in to Garage Trip:\n\n%s\n\nThe link works once and expires in %d minutes. If you did not ask for it, just ignore this email.\n",
+		user.Username, h.loginLink(token), int(LoginLinkDuration.Minutes()))
+	if err := h.sender.Send(user.Email, "Your Garage Trip login link", body); err != nil {
+		// Failing only for existing accounts would tell them apart
+		log.Printf("Failed to send login link to user %d: %v", user.ID, err)
+	}
+	return res, nil
+}
+
+func (h *EmailLoginHandler) HandleEmailConfirm(ctx context.Context, input *EmailCallbackInput) (*EmailConfirmResponse, error) {
+	if input.Token == "" {
+		return nil, huma.Error400BadRequest("Token not found")
+	}
+
+	var page bytes.Buffer
+	if err := confirmPage.Execute(&page, "/auth/email/callback?token="+url.QueryEscape(input.Token)); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to render the confirmation page: " + err.Error())
+	}
+	return &EmailConfirmResponse{ContentType: "text/html; charset=utf-8", Body: page.Bytes()}, nil
+}
+
+func (h *EmailLoginHandler) HandleEmailCallback(ctx context.Context, input *EmailCallbackInput) (*EmailCallbackResponse, error) {
+	if input.Token == "" {
+		return nil, huma.Error400BadRequest("Token not found")
+	}
+
+	var loginToken models.LoginToken
+	if err := h.db.Where("token_hash = ?", hashLoginToken(input.Token)).First(&loginToken).Error; err != nil {
+		return nil, huma.Error401Unauthorized("Invalid login link")
+	}
+	now := time.Now()
+	if loginToken.UsedAt != nil || now.After(loginToken.ExpiresAt) {
+		return nil, huma.Error401Unauthorized("Login link has expired or was already used")
+	}
+
+	// Mark the token used only if nobody else did in the meantime
+	result := h.db.Model(&models.LoginToken{}).Where("id = ? AND used_at IS NULL", loginToken.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to use login link: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, huma.Error401Unauthorized("Login link has expired or was already used")
+	}
+
+	cookie, err := h.authHandler.SessionCookie(loginToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &EmailCallbackResponse{
+		Status:   303,
+		Location: h.cfg.FrontendURL,
+	}
+	res.SetCookie = cookie
+	return res, nil
+}
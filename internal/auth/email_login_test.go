@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type sentEmail struct {
+	to      string
+	subject string
+	body    string
+}
+
+type fakeSender struct {
+	sent []sentEmail
+	err  error
+}
+
+func (s *fakeSender) Send(to string, subject string, body string) error {
+	s.sent = append(s.sent, sentEmail{to: to, subject: subject, body: body})
+	return s.err
+}
+
+func TestEmailLogin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "email-user", Username: "oldtimer", Email: "Old.Timer@example.com"}
+	db.Create(&user)
+
+	cfg := &config.Config{JWTSecret: "test-secret", PublicURL: "https://api.example.com", FrontendURL: "https://trip.example.com"}
+	authHandler := NewAuthHandler(cfg, db, nil)
+	sender := &fakeSender{}
+	handler := NewEmailLoginHandler(db, cfg, sender, authHandler)
+
+	request := func(email string) error {
+		req := &EmailLoginRequest{}
+		req.Body.Email = email
+		_, err := handler.HandleEmailLogin(context.Background(), req)
+		return err
+	}
+	linkToken := func(body string) string {
+		link := regexp.MustCompile(`https://\S+`).FindString(body)
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Path != "/auth/email/callback" {
+			t.Fatalf("unexpected login link %q", link)
+		}
+		return parsed.Query().Get("token")
+	}
+	callback := func(token string) (*EmailCallbackResponse, error) {
+		return handler.HandleEmailCallback(context.Background(), &EmailCallbackInput{Token: token})
+	}
+
+	if err := request("nobody@example.com"); err != nil {
+		t.Fatalf("expected unknown emails to look like known ones, got %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("expected no email for unknown address, got %d", len(sender.sent))
+	}
+
+	if err := request("old.timer@EXAMPLE.com"); err != nil {
+		t.Fatalf("HandleEmailLogin failed: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].to != user.Email {
+		t.Fatalf("expected a login link sent to %s, got %+v", user.Email, sender.sent)
+	}
+	first := linkToken(sender.sent[0].body)
+
+	// A new link replaces the previous one
+	if err := request(user.Email); err != nil {
+		t.Fatalf("HandleEmailLogin failed: %v", err)
+	}
+	second := linkToken(sender.sent[1].body)
+	if _, err := callback(first); err == nil {
+		t.Error("expected the replaced link to be rejected")
+	}
+
+	// Opening the link only shows a page posting the token back
+	page, err := handler.HandleEmailConfirm(context.Background(), &EmailCallbackInput{Token: second})
+	if err != nil {
+		t.Fatalf("HandleEmailConfirm failed: %v", err)
+	}
+	if !strings.Contains(string(page.Body), `method="post"`) || !strings.Contains(string(page.Body), second) {
+		t.Errorf("expected a form posting the token, got %s", page.Body)
+	}
+
+	res, err := callback(second)
+	if err != nil {
+		t.Fatalf("HandleEmailCallback failed: %v", err)
+	}
+	if res.Location != cfg.FrontendURL || !strings.HasPrefix(res.SetCookie, "auth_token=") {
+		t.Errorf("unexpected callback response %+v", res)
+	}
+	cookie := strings.Split(res.SetCookie, ";")[0]
+	if userID, err := authHandler.Authorize(context.Background(), cookie); err != nil || userID != user.ID {
+		t.Errorf("expected the cookie to sign in user %d, got %d %v", user.ID, userID, err)
+	}
+	if _, err := callback(second); err == nil {
+		t.Error("expected the link to work only once")
+	}
+
+	if err := request(user.Email); err != nil {
+		t.Fatalf("HandleEmailLogin failed: %v", err)
+	}
+	expired := linkToken(sender.sent[2].body)
+	db.Model(&models.LoginToken{}).Where("token_hash = ?", hashLoginToken(expired)).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := callback(expired); err == nil {
+		t.Error("expected the expired link to be rejected")
+	}
+
+	// A failed send answers like an unknown address
+	sender.err = errors.New("smtp down")
+	if err := request(user.Email); err != nil {
+		t.Errorf("expected a failed send to look like an unknown address, got %v", err)
+	}
+
+	disabled := NewEmailLoginHandler(db, cfg, nil, authHandler)
+	req := &EmailLoginRequest{}
+	req.Body.Email = user.Email
+	if _, err := disabled.HandleEmailLogin(context.Background(), req); err == nil {
+		t.Error("expected email login to be unavailable without a sender")
+	}
+}
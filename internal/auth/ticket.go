@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ticketPrefix versions the ticket format and separates its signatures from other uses of the secret
+const ticketPrefix = "GT1"
+
+var ErrInvalidTicket = errors.New("invalid ticket")
+
+func ticketSignature(secret string, registrationID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ticketPrefix + ":" + registrationID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignTicket returns the check-in ticket of a registration in the form GT1.<registration ID>.<HMAC>
+func SignTicket(secret string, registrationID uint) string {
+	id := strconv.FormatUint(uint64(registrationID), 10)
+	return ticketPrefix + "." + id + "." + ticketSignature(secret, id)
+}
+
+// VerifyTicket checks the ticket signature and returns the registration ID it was issued for
+func VerifyTicket(secret string, ticket string) (uint, error) {
+	parts := strings.Split(strings.TrimSpace(ticket), ".")
+	if len(parts) != 3 || parts[0] != ticketPrefix {
+		return 0, ErrInvalidTicket
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(ticketSignature(secret, parts[1]))) {
+		return 0, ErrInvalidTicket
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 0)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidTicket
+	}
+	return uint(id), nil
+}
+
+// Ticket returns the check-in ticket of a registration signed with the JWT secret
+func (h *AuthHandler) Ticket(registrationID uint) string {
+	return SignTicket(h.cfg.JWTSecret, registrationID)
+}
+
+// VerifyTicket checks a ticket signed with the JWT secret
+func (h *AuthHandler) VerifyTicket(ticket string) (uint, error) {
+	return VerifyTicket(h.cfg.JWTSecret, ticket)
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTicket(t *testing.T) {
+	ticket := SignTicket("secret", 42)
+	if !strings.HasPrefix(ticket, "GT1.42.") {
+		t.Errorf("unexpected ticket format %s", ticket)
+	}
+
+	id, err := VerifyTicket("secret", ticket)
+	if err != nil || id != 42 {
+		t.Errorf("expected ticket for registration 42, got %d (%v)", id, err)
+	}
+
+	if _, err := VerifyTicket("other-secret", ticket); err == nil {
+		t.Error("expected ticket signed with another secret to be rejected")
+	}
+
+	forged := strings.Replace(ticket, "GT1.42.", "GT1.43.", 1)
+	if _, err := VerifyTicket("secret", forged); err == nil {
+		t.Error("expected ticket with changed registration ID to be rejected")
+	}
+
+	for _, invalid := range []string{"", "GT1.42", "GT2.42." + strings.Split(ticket, ".")[2], "garbage"} {
+		if _, err := VerifyTicket("secret", invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
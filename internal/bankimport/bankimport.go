@@ -0,0 +1,142 @@
+// Package bankimport parses bank statement exports into incoming transactions
+package bankimport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction is a single movement on the bank account, amounts are in minor currency units
+type Transaction struct {
+	ID             string    `json:"id" doc:"Transaction ID assigned by the bank"`
+	Date           time.Time `json:"date"`
+	Amount         int64     `json:"amount" doc:"Negative for outgoing transactions"`
+	Currency       string    `json:"currency"`
+	VariableSymbol string    `json:"variable_symbol"`
+	Counterparty   string    `json:"counterparty"`
+	Message        string    `json:"message"`
+}
+
+// Incoming reports whether the transaction credits the account
+func (t Transaction) Incoming() bool {
+	return t.Amount > 0
+}
+
+// Reference returns a stable identifier of the transaction, used to detect repeated imports
+func (t Transaction) Reference() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	// Exports without transaction IDs are deduplicated by their content
+	return fmt.Sprintf("%s/%d/%s/%s", t.Date.Format("2006-01-02"), t.Amount, t.VariableSymbol, t.Counterparty)
+}
+
+// Parse detects the statement format and parses it, CSV and camt.053 XML are supported
+func Parse(data []byte) ([]Transaction, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("statement is empty")
+	}
+	if trimmed[0] == '<' {
+		return ParseCAMT053(bytes.NewReader(trimmed))
+	}
+	return ParseCSV(bytes.NewReader(data))
+}
+
+// parseAmount converts a decimal amount like "1 234,50" or "-1234.5" into minor currency units
+func parseAmount(value string) (int64, error) {
+	s := strings.NewReplacer(" ", "", " ", "", " ", "", "'", "").Replace(strings.TrimSpace(value))
+	if s == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	// The last separator is the decimal one, the others group thousands
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 <= 2 {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s[:i]) + "." + s[i+1:]
+	} else {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	fraction = (fraction + "00")[:2]
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	cents, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	amount := units*100 + cents
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"02.01.2006",
+	"2.1.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"02/01/2006",
+	"2/1/2006",
+}
+
+// parseDate accepts the date formats commonly found in bank exports
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// normalizeVariableSymbol extracts the digits of a variable symbol, dropping
+// leading zeros and prefixes like "VS" or "/VS/"
+func normalizeVariableSymbol(value string) string {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	value = strings.TrimPrefix(value, "/VS/")
+	value = strings.TrimPrefix(value, "VS:")
+	value = strings.TrimPrefix(value, "VS")
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > 10 {
+		return ""
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	value = strings.TrimLeft(value, "0")
+	return value
+}
+
+var variableSymbolInText = regexp.MustCompile(`(?i)(?:^|[^a-z])vs[:/ ]*(\d{1,10})`)
+
+// findVariableSymbol looks for a variable symbol written in free text, e.g. "/VS/123"
+func findVariableSymbol(text string) string {
+	m := variableSymbolInText.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return normalizeVariableSymbol(m[1])
+}
@@ -0,0 +1,169 @@
+package bankimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const fioCSV = `"accountId";"2000000000"
+"bankId";"2010"
+"currency";"CZK"
+"dateStart";"01.06.2025"
+"dateEnd";"30.06.2025"
+
+"ID pohybu";"Datum";"Objem";"Měna";"Protiúčet";"Název protiúčtu";"VS";"Zpráva pro příjemce"
+"26001";"03.06.2025";"1 500,00";"CZK";"123/0100";"Jan Novák";"0042";"trip"
+"26002";"04.06.2025";"-200,00";"CZK";"";"Fee";"";""
+"26003";"05.06.2025";"750,50";"CZK";"456/0300";"Eva";"";"/VS/7/SS/1"
+`
+
+func TestParseCSV_Fio(t *testing.T) {
+	transactions, err := Parse([]byte("\xef\xbb\xbf" + fioCSV))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(transactions))
+	}
+
+	first := transactions[0]
+	if first.ID != "26001" || first.Amount != 150000 || first.Currency != "CZK" || first.VariableSymbol != "42" || first.Counterparty != "Jan Novák" {
+		t.Errorf("unexpected first transaction %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v", first.Date)
+	}
+	if transactions[1].Incoming() {
+		t.Error("expected negative amount to be outgoing")
+	}
+	if transactions[2].Amount != 75050 || transactions[2].VariableSymbol != "7" {
+		t.Errorf("expected variable symbol from message, got %+v", transactions[2])
+	}
+}
+
+func TestParseCSV_CommaSeparated(t *testing.T) {
+	data := "Date,Amount,Currency,Variable symbol,Message\n2025-06-03,\"1,234.50\",EUR,12,hello\n"
+	transactions, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].Amount != 123450 || transactions[0].VariableSymbol != "12" {
+		t.Errorf("unexpected transactions %+v", transactions)
+	}
+	if transactions[0].Reference() == "" {
+		t.Error("expected a content based reference without transaction ID")
+	}
+}
+
+func TestParseCSV_MissingHeader(t *testing.T) {
+	if _, err := Parse([]byte("foo;bar\n1;2\n")); err == nil {
+		t.Error("expected error without date and amount columns")
+	}
+}
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="CZK">1500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-06-03</Dt></BookgDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>VS0000000042</EndToEndId></Refs>
+            <RltdPties><Dbtr><Pty><Nm>Jan Novák</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf><Ustrd>trip</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CZK">300.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-06-04T10:00:00</DtTm></BookgDt>
+        <AcctSvcrRef>REF-2</AcctSvcrRef>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CZK">100.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2025-06-05</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="CZK">900.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-06-06</Dt></BookgDt>
+        <AcctSvcrRef>BATCH</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="CZK">400.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Strd><CdtrRefInf><Ref>5</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="CZK">500.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Ustrd>/VS/6</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseCAMT053(t *testing.T) {
+	transactions, err := Parse([]byte(camt053))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(transactions) != 4 {
+		t.Fatalf("expected 4 booked transactions, got %d: %+v", len(transactions), transactions)
+	}
+
+	first := transactions[0]
+	if first.ID != "REF-1" || first.Amount != 150000 || first.VariableSymbol != "42" || first.Counterparty != "Jan Novák" || first.Message != "trip" {
+		t.Errorf("unexpected first transaction %+v", first)
+	}
+	if transactions[1].Amount != -30000 || transactions[1].Date.Hour() != 10 {
+		t.Errorf("unexpected debit transaction %+v", transactions[1])
+	}
+
+	batch := transactions[2:]
+	if batch[0].ID != "BATCH/1" || batch[0].Amount != 40000 || batch[0].VariableSymbol != "5" {
+		t.Errorf("unexpected batch transaction %+v", batch[0])
+	}
+	if batch[1].ID != "BATCH/2" || batch[1].Amount != 50000 || batch[1].VariableSymbol != "6" {
+		t.Errorf("unexpected batch transaction %+v", batch[1])
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]int64{
+		"1500":      150000,
+		"1 500,5":   150050,
+		"-1.234,56": -123456,
+		"1,234.56":  123456,
+		"0.99":      99,
+		"+12":       1200,
+		"1.234.567": 123456700,
+		"12,3":      1230,
+		" 7,00 ":    700,
+		"1 000":     100000,
+	}
+	for input, want := range cases {
+		got, err := parseAmount(input)
+		if err != nil {
+			t.Errorf("parseAmount(%q) failed: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseAmount(%q) = %d, want %d", input, got, want)
+		}
+	}
+	if _, err := parseAmount(strings.Repeat("x", 3)); err == nil {
+		t.Error("expected error for invalid amount")
+	}
+}
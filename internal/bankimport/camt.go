@@ -0,0 +1,156 @@
+package bankimport
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtStatus holds the entry status, a plain value before version 6 and a code since
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+type camtTransaction struct {
+	AccountServicerReference string      `xml:"Refs>AcctSvcrRef"`
+	EndToEndID               string      `xml:"Refs>EndToEndId"`
+	Amount                   *camtAmount `xml:"Amt"`
+	TransactionAmount        *camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Debtor                   camtParty   `xml:"RltdPties>Dbtr"`
+	Unstructured             []string    `xml:"RmtInf>Ustrd"`
+	CreditorReference        string      `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+type camtEntry struct {
+	Reference                string            `xml:"NtryRef"`
+	AccountServicerReference string            `xml:"AcctSvcrRef"`
+	Amount                   camtAmount        `xml:"Amt"`
+	CreditDebit              string            `xml:"CdtDbtInd"`
+	Status                   camtStatus        `xml:"Sts"`
+	BookingDate              camtDate          `xml:"BookgDt"`
+	ValueDate                camtDate          `xml:"ValDt"`
+	Transactions             []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	if d.DateTime != "" {
+		return parseDate(d.DateTime)
+	}
+	return parseDate(d.Date)
+}
+
+// ParseCAMT053 parses an ISO 20022 camt.053 bank to customer statement.
+// Entries with several transaction details yield one transaction each.
+func ParseCAMT053(r io.Reader) ([]Transaction, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 document: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("no statement found in camt.053 document")
+	}
+
+	var transactions []Transaction
+	for _, stmt := range doc.Statements {
+		for i, entry := range stmt.Entries {
+			status := strings.TrimSpace(entry.Status.Value + entry.Status.Code)
+			if status != "" && status != "BOOK" {
+				// Pending entries show up again once booked
+				continue
+			}
+
+			date, err := entry.BookingDate.parse()
+			if err != nil {
+				if date, err = entry.ValueDate.parse(); err != nil {
+					return nil, fmt.Errorf("entry %d: %w", i+1, err)
+				}
+			}
+
+			details := entry.Transactions
+			if len(details) == 0 {
+				details = []camtTransaction{{}}
+			}
+			for j, detail := range details {
+				amount := entry.Amount
+				if len(details) > 1 {
+					switch {
+					case detail.TransactionAmount != nil:
+						amount = *detail.TransactionAmount
+					case detail.Amount != nil:
+						amount = *detail.Amount
+					}
+				}
+				value, err := parseAmount(amount.Value)
+				if err != nil {
+					return nil, fmt.Errorf("entry %d: %w", i+1, err)
+				}
+				if entry.CreditDebit == "DBIT" {
+					value = -value
+				}
+
+				id := detail.AccountServicerReference
+				if id == "" {
+					id = entry.AccountServicerReference
+					if id == "" {
+						id = entry.Reference
+					}
+					if id != "" && len(details) > 1 {
+						id = fmt.Sprintf("%s/%d", id, j+1)
+					}
+				}
+
+				message := strings.Join(detail.Unstructured, " ")
+				counterparty := detail.Debtor.Name
+				if counterparty == "" {
+					counterparty = detail.Debtor.PartyName
+				}
+
+				t := Transaction{
+					ID:           id,
+					Date:         date,
+					Amount:       value,
+					Currency:     strings.ToUpper(amount.Currency),
+					Counterparty: counterparty,
+					Message:      message,
+				}
+				// Czech banks put the variable symbol into the end to end ID,
+				// others use the structured creditor reference
+				for _, candidate := range []string{detail.EndToEndID, detail.CreditorReference} {
+					if t.VariableSymbol == "" {
+						t.VariableSymbol = normalizeVariableSymbol(candidate)
+					}
+				}
+				if t.VariableSymbol == "" {
+					t.VariableSymbol = findVariableSymbol(detail.EndToEndID + " " + message)
+				}
+				transactions = append(transactions, t)
+			}
+		}
+	}
+	return transactions, nil
+}
@@ -0,0 +1,145 @@
+package bankimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Column names used by common exports, Fio banka headers included
+var csvColumns = map[string][]string{
+	"id":             {"id pohybu", "id transakce", "id operace", "transaction id", "id"},
+	"date":           {"datum", "datum zaúčtování", "datum provedení", "booking date", "date"},
+	"amount":         {"objem", "částka", "amount"},
+	"currency":       {"měna", "currency"},
+	"variableSymbol": {"vs", "variabilní symbol", "variable symbol"},
+	"counterparty":   {"název protiúčtu", "protiúčet", "counterparty name", "counterparty"},
+	"message":        {"zpráva pro příjemce", "poznámka", "message", "note"},
+}
+
+// detectDelimiter guesses the delimiter from the header line
+func detectDelimiter(header string) rune {
+	best, count := ',', 0
+	for _, d := range []rune{';', ',', '\t'} {
+		if c := strings.Count(header, string(d)); c > count {
+			best, count = d, c
+		}
+	}
+	return best
+}
+
+// headerColumns maps the known columns to their index, preferring aliases listed first.
+// Unknown headers are ignored.
+func headerColumns(header []string) map[string]int {
+	names := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := names[name]; !ok {
+			names[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for column, aliases := range csvColumns {
+		for _, alias := range aliases {
+			if i, ok := names[alias]; ok {
+				columns[column] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// ParseCSV parses a CSV export. Lines before the header (e.g. the account
+// summary in Fio exports) are skipped.
+func ParseCSV(r io.Reader) ([]Transaction, error) {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var columns map[string]int
+	var delimiter rune
+	var rest strings.Builder
+	for lines.Scan() {
+		line := lines.Text()
+		if columns != nil {
+			rest.WriteString(line)
+			rest.WriteByte('\n')
+			continue
+		}
+		d := detectDelimiter(line)
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.Comma = d
+		reader.LazyQuotes = true
+		header, err := reader.Read()
+		if err != nil {
+			continue
+		}
+		found := headerColumns(header)
+		_, hasDate := found["date"]
+		_, hasAmount := found["amount"]
+		if hasDate && hasAmount {
+			columns, delimiter = found, d
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, errors.New("no header with date and amount columns found")
+	}
+
+	reader := csv.NewReader(strings.NewReader(rest.String()))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var transactions []Transaction
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if field(record, "date") == "" && field(record, "amount") == "" {
+			continue
+		}
+
+		date, err := parseDate(field(record, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		amount, err := parseAmount(field(record, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		t := Transaction{
+			ID:             field(record, "id"),
+			Date:           date,
+			Amount:         amount,
+			Currency:       strings.ToUpper(field(record, "currency")),
+			VariableSymbol: normalizeVariableSymbol(field(record, "variableSymbol")),
+			Counterparty:   field(record, "counterparty"),
+			Message:        field(record, "message"),
+		}
+		if t.VariableSymbol == "" {
+			t.VariableSymbol = findVariableSymbol(t.Message)
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
@@ -0,0 +1,92 @@
+package billing
+
+import (
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Balance summarizes what a registration owes, amounts are in minor currency units
+type Balance struct {
+	Due         int64  `json:"due"`
+	Paid        int64  `json:"paid"`
+	Outstanding int64  `json:"outstanding"`
+	Currency    string `json:"currency"`
+	Payments    int    `json:"payments" doc:"Number of recorded payments which are not voided"`
+	Settled     bool   `json:"settled"`
+}
+
+// Currency returns the currency the event is priced in
+func Currency(event models.Event) string {
+	if event.Pricing.Currency == "" {
+		return models.DefaultCurrency
+	}
+	return event.Pricing.Currency
+}
+
+// AmountDue returns what the registration owes for the event, waitlisted attendees owe nothing yet
+func AmountDue(event models.Event, registration models.Registration) int64 {
+	if !registration.Confirmed() {
+		return 0
+	}
+	return Price(event, registration).Total
+}
+
+// NewBalance computes the balance of a registration from its valid payments
+func NewBalance(event models.Event, registration models.Registration, payments []models.Payment) Balance {
+	balance := Balance{
+		Due:      AmountDue(event, registration),
+		Currency: Currency(event),
+	}
+	for _, p := range payments {
+		if p.VoidedAt != nil || p.RegistrationID != registration.ID {
+			continue
+		}
+		balance.Paid += p.Amount
+		balance.Payments++
+	}
+	balance.Outstanding = balance.Due - balance.Paid
+	balance.Settled = balance.Outstanding <= 0
+	return balance
+}
+
+// PaidInFull reports whether the registration settled its balance with recorded payments.
+// Waitlisted and cancelled registrations owe nothing, so their payments never make them paid.
+func PaidInFull(registration models.Registration, balance Balance) bool {
+	return registration.Confirmed() && balance.Payments > 0 && balance.Settled
+}
+
+// RegistrationBalance loads the payments of a registration and computes its balance
+func RegistrationBalance(db *gorm.DB, event models.Event, registration models.Registration) (Balance, error) {
+	var payments []models.Payment
+	if err := db.Where("registration_id = ? AND voided_at IS NULL", registration.ID).Find(&payments).Error; err != nil {
+		return Balance{}, err
+	}
+	return NewBalance(event, registration, payments), nil
+}
+
+// Balances computes balances of many registrations with a single query, keyed by registration ID.
+// Events are looked up by their code.
+func Balances(db *gorm.DB, events map[string]models.Event, registrations []models.Registration) (map[uint]Balance, error) {
+	ids := make([]uint, len(registrations))
+	for i, r := range registrations {
+		ids[i] = r.ID
+	}
+
+	var payments []models.Payment
+	if len(ids) > 0 {
+		if err := db.Where("registration_id IN ? AND voided_at IS NULL", ids).Find(&payments).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byRegistration := make(map[uint][]models.Payment)
+	for _, p := range payments {
+		byRegistration[p.RegistrationID] = append(byRegistration[p.RegistrationID], p)
+	}
+
+	balances := make(map[uint]Balance, len(registrations))
+	for _, r := range registrations {
+		balances[r.ID] = NewBalance(events[r.Event], r, byRegistration[r.ID])
+	}
+	return balances, nil
+}
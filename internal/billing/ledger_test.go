@@ -0,0 +1,53 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestNewBalance(t *testing.T) {
+	event := models.Event{Code: "ev", Pricing: models.EventPricing{FixedFee: 150000}}
+	registration := models.Registration{Model: gorm.Model{ID: 7}, Event: "ev"}
+	voided := time.Now()
+
+	payments := []models.Payment{
+		{RegistrationID: 7, Amount: 100000},
+		{RegistrationID: 7, Amount: 50000, VoidedAt: &voided},
+		{RegistrationID: 8, Amount: 50000},
+	}
+
+	balance := NewBalance(event, registration, payments)
+	if balance.Due != 150000 {
+		t.Errorf("expected due 150000, got %d", balance.Due)
+	}
+	if balance.Paid != 100000 {
+		t.Errorf("expected paid 100000, got %d", balance.Paid)
+	}
+	if balance.Outstanding != 50000 {
+		t.Errorf("expected outstanding 50000, got %d", balance.Outstanding)
+	}
+	if balance.Payments != 1 {
+		t.Errorf("expected 1 payment, got %d", balance.Payments)
+	}
+	if balance.Settled {
+		t.Error("expected balance not to be settled")
+	}
+	if balance.Currency != models.DefaultCurrency {
+		t.Errorf("expected default currency, got %s", balance.Currency)
+	}
+
+	payments = append(payments, models.Payment{RegistrationID: 7, Amount: 60000})
+	balance = NewBalance(event, registration, payments)
+	if !balance.Settled || balance.Outstanding != -10000 {
+		t.Errorf("expected overpaid settled balance, got %+v", balance)
+	}
+
+	registration.Cancelled = true
+	balance = NewBalance(event, registration, nil)
+	if balance.Due != 0 || !balance.Settled {
+		t.Errorf("expected cancelled registration to owe nothing, got %+v", balance)
+	}
+}
@@ -0,0 +1,67 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+// Quote is the computed price of a registration with its breakdown, amounts are in minor currency units
+type Quote struct {
+	Nights            int    `json:"nights"`
+	Adults            int    `json:"adults"`
+	Children          int    `json:"children"`
+	Accommodation     int64  `json:"accommodation" doc:"Nightly prices of all adults and children"`
+	FixedFee          int64  `json:"fixed_fee"`
+	EarlyBirdDiscount int64  `json:"early_bird_discount"`
+	Total             int64  `json:"total"`
+	Currency          string `json:"currency"`
+}
+
+// Nights returns the number of nights between the arrival and departure dates
+func Nights(arrival time.Time, departure time.Time) int {
+	a := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, time.UTC)
+	d := time.Date(departure.Year(), departure.Month(), departure.Day(), 0, 0, 0, 0, time.UTC)
+	nights := int(d.Sub(a).Hours() / 24)
+	if nights < 0 {
+		return 0
+	}
+	return nights
+}
+
+// Calculate evaluates the event pricing rules against the registration fields.
+// The early bird discount applies when the registration was created before the cutoff.
+func Calculate(event models.Event, fields models.RegistrationFields, registeredAt time.Time) Quote {
+	quote := Quote{Currency: Currency(event)}
+	if fields.Cancelled {
+		return quote
+	}
+
+	pricing := event.Pricing
+	quote.Nights = Nights(fields.ArrivalDate, fields.DepartureDate)
+	quote.Adults = fields.Adults()
+	quote.Children = fields.Children()
+	quote.Accommodation = int64(quote.Nights) * (int64(quote.Adults)*pricing.AdultPerNight + int64(quote.Children)*pricing.ChildPerNight)
+	quote.FixedFee = pricing.FixedFee
+
+	subtotal := quote.Accommodation + quote.FixedFee
+	if pricing.EarlyBirdUntil != nil && registeredAt.Before(*pricing.EarlyBirdUntil) && pricing.EarlyBirdDiscountPercent > 0 {
+		quote.EarlyBirdDiscount = subtotal * int64(pricing.EarlyBirdDiscountPercent) / 100
+	}
+	quote.Total = subtotal - quote.EarlyBirdDiscount
+
+	return quote
+}
+
+// RegisteredAt returns the time the registration was first created, now for new ones
+func RegisteredAt(registration models.Registration) time.Time {
+	if registration.CreatedAt.IsZero() {
+		return time.Now()
+	}
+	return registration.CreatedAt
+}
+
+// Price returns the quote for an existing registration
+func Price(event models.Event, registration models.Registration) Quote {
+	return Calculate(event, registration.RegistrationFields, RegisteredAt(registration))
+}
@@ -0,0 +1,65 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func TestNights(t *testing.T) {
+	arrival := time.Date(2026, 7, 10, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		departure time.Time
+		want      int
+	}{
+		{"SameDay", time.Date(2026, 7, 10, 22, 0, 0, 0, time.UTC), 0},
+		{"NextMorning", time.Date(2026, 7, 11, 9, 0, 0, 0, time.UTC), 1},
+		{"ThreeNights", time.Date(2026, 7, 13, 0, 0, 0, 0, time.UTC), 3},
+		{"BeforeArrival", time.Date(2026, 7, 9, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Nights(arrival, tt.departure); got != tt.want {
+				t.Errorf("expected %d nights, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	cutoff := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	event := models.Event{Pricing: models.EventPricing{
+		Currency:                 "CZK",
+		FixedFee:                 20000,
+		AdultPerNight:            50000,
+		ChildPerNight:            25000,
+		EarlyBirdUntil:           &cutoff,
+		EarlyBirdDiscountPercent: 10,
+	}}
+	fields := models.RegistrationFields{
+		ArrivalDate:   time.Date(2026, 7, 10, 0, 0, 0, 0, time.UTC),
+		DepartureDate: time.Date(2026, 7, 12, 0, 0, 0, 0, time.UTC),
+		ChildrenCount: 2,
+	}
+
+	late := Calculate(event, fields, cutoff.Add(time.Hour))
+	// 2 nights * (1 * 500 + 2 * 250) + 200
+	if late.Accommodation != 200000 || late.FixedFee != 20000 || late.Total != 220000 {
+		t.Errorf("unexpected late quote %+v", late)
+	}
+	if late.EarlyBirdDiscount != 0 {
+		t.Errorf("expected no early bird discount, got %d", late.EarlyBirdDiscount)
+	}
+
+	early := Calculate(event, fields, cutoff.Add(-time.Hour))
+	if early.EarlyBirdDiscount != 22000 || early.Total != 198000 {
+		t.Errorf("unexpected early bird quote %+v", early)
+	}
+
+	fields.Cancelled = true
+	if cancelled := Calculate(event, fields, cutoff); cancelled.Total != 0 {
+		t.Errorf("expected cancelled registration to cost nothing, got %d", cancelled.Total)
+	}
+}
@@ -0,0 +1,81 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+// SPAYD describes a Czech "QR Platba" payment, see https://qr-platba.cz/pro-vyvojare/specifikace-formatu/
+type SPAYD struct {
+	IBAN           string
+	BIC            string
+	Amount         int64 // In minor currency units, 0 lets the payer fill it in
+	Currency       string
+	VariableSymbol string
+	Message        string
+	RecipientName  string
+}
+
+// escapeSPAYD makes a value safe to be used inside the asterisk separated format
+func escapeSPAYD(value string, maxRunes int) string {
+	value = strings.ReplaceAll(value, "%", "%25")
+	value = strings.ReplaceAll(value, "*", "%2A")
+	for utf8.RuneCountInString(value) > maxRunes {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
+
+// String encodes the payment in the SPAYD 1.0 format
+func (s SPAYD) String() string {
+	account := strings.ToUpper(strings.ReplaceAll(s.IBAN, " ", ""))
+	if s.BIC != "" {
+		account += "+" + strings.ToUpper(s.BIC)
+	}
+
+	parts := []string{"SPD", "1.0", "ACC:" + account}
+	if s.Amount > 0 {
+		parts = append(parts, fmt.Sprintf("AM:%d.%02d", s.Amount/100, s.Amount%100))
+	}
+	if s.Currency != "" {
+		parts = append(parts, "CC:"+strings.ToUpper(s.Currency))
+	}
+	if s.VariableSymbol != "" {
+		parts = append(parts, "X-VS:"+escapeSPAYD(s.VariableSymbol, 10))
+	}
+	if s.Message != "" {
+		parts = append(parts, "MSG:"+escapeSPAYD(s.Message, 60))
+	}
+	if s.RecipientName != "" {
+		parts = append(parts, "RN:"+escapeSPAYD(s.RecipientName, 35))
+	}
+	return strings.Join(parts, "*")
+}
+
+// BankAccount identifies where attendees send their payments
+type BankAccount struct {
+	IBAN          string
+	BIC           string
+	RecipientName string
+}
+
+// PaymentFor returns the payment settling the outstanding balance of a registration
+func (a BankAccount) PaymentFor(registration models.Registration, balance Balance) SPAYD {
+	message := registration.Event
+	if registration.User.Username != "" {
+		message += " " + registration.User.Username
+	}
+	return SPAYD{
+		IBAN:           a.IBAN,
+		BIC:            a.BIC,
+		Amount:         max(balance.Outstanding, 0),
+		Currency:       balance.Currency,
+		VariableSymbol: registration.VariableSymbol(),
+		Message:        message,
+		RecipientName:  a.RecipientName,
+	}
+}
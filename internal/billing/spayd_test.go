@@ -0,0 +1,43 @@
+package billing
+
+import "testing"
+
+func TestSPAYD_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		spayd SPAYD
+		want  string
+	}{
+		{
+			name: "Full",
+			spayd: SPAYD{
+				IBAN:           "CZ65 0800 0000 1920 0014 5399",
+				BIC:            "gibaczpx",
+				Amount:         48050,
+				Currency:       "czk",
+				VariableSymbol: "42",
+				Message:        "g::t::7.0.0 alice",
+				RecipientName:  "GDG Garage",
+			},
+			want: "SPD*1.0*ACC:CZ6508000000192000145399+GIBACZPX*AM:480.50*CC:CZK*X-VS:42*MSG:g::t::7.0.0 alice*RN:GDG Garage",
+		},
+		{
+			name:  "WithoutAmount",
+			spayd: SPAYD{IBAN: "CZ6508000000192000145399", Currency: "CZK"},
+			want:  "SPD*1.0*ACC:CZ6508000000192000145399*CC:CZK",
+		},
+		{
+			name:  "EscapesSeparator",
+			spayd: SPAYD{IBAN: "CZ6508000000192000145399", Amount: 100, Message: "50% off *now*"},
+			want:  "SPD*1.0*ACC:CZ6508000000192000145399*AM:1.00*MSG:50%25 off %2Anow%2A",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.spayd.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,27 +2,45 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Port                          string   `mapstructure:"PORT"`
-	DatabasePath                  string   `mapstructure:"DATABASE_PATH"`
-	DiscordClientID               string   `mapstructure:"DISCORD_CLIENT_ID"`
-	DiscordClientSecret           string   `mapstructure:"DISCORD_CLIENT_SECRET"`
-	DiscordRedirectURL            string   `mapstructure:"DISCORD_REDIRECT_URL"`
-	DiscordGuildID                string   `mapstructure:"DISCORD_GUILD_ID"`
-	DiscordBotToken               string   `mapstructure:"DISCORD_BOT_TOKEN"`
-	DiscordAchievementsChannelID  string   `mapstructure:"DISCORD_ACHIEVEMENTS_CHANNEL_ID"`
-	DiscordRegistrationsChannelID string   `mapstructure:"DISCORD_REGISTRATIONS_CHANNEL_ID"`
-	JWTSecret                     string   `mapstructure:"JWT_SECRET"`
-	FrontendURL                   string   `mapstructure:"FRONTEND_URL"`
-	AchievementPrefix             string   `mapstructure:"ACHIEVEMENT_PREFIX"`
-	EnableCORS                    bool     `mapstructure:"ENABLE_CORS"`
-	EnabledEvents                 []string `mapstructure:"ENABLED_EVENTS"`
-	UploadDir                     string   `mapstructure:"UPLOAD_DIR"`
-	OrgRole                       string   `mapstructure:"ORG_ROLE"`
+	Port                          string        `mapstructure:"PORT"`
+	DatabasePath                  string        `mapstructure:"DATABASE_PATH"`
+	DiscordClientID               string        `mapstructure:"DISCORD_CLIENT_ID"`
+	DiscordClientSecret           string        `mapstructure:"DISCORD_CLIENT_SECRET"`
+	DiscordRedirectURL            string        `mapstructure:"DISCORD_REDIRECT_URL"`
+	DiscordGuildID                string        `mapstructure:"DISCORD_GUILD_ID"`
+	DiscordBotToken               string        `mapstructure:"DISCORD_BOT_TOKEN"`
+	DiscordAchievementsChannelID  string        `mapstructure:"DISCORD_ACHIEVEMENTS_CHANNEL_ID"`
+	DiscordRegistrationsChannelID string        `mapstructure:"DISCORD_REGISTRATIONS_CHANNEL_ID"`
+	DiscordCacheTTL               time.Duration `mapstructure:"DISCORD_CACHE_TTL"` // How long guild roles and member roles are cached
+	DiscordGateway                bool          `mapstructure:"DISCORD_GATEWAY"`   // Connect to the gateway to keep the cache fresh and sync role changes made in Discord, needs the server members intent
+	JWTSecret                     string        `mapstructure:"JWT_SECRET"`
+	FrontendURL                   string        `mapstructure:"FRONTEND_URL"`
+	AchievementPrefix             string        `mapstructure:"ACHIEVEMENT_PREFIX"`
+	EnableCORS                    bool          `mapstructure:"ENABLE_CORS"`
+	UploadDir                     string        `mapstructure:"UPLOAD_DIR"`
+	OrgRole                       string        `mapstructure:"ORG_ROLE"`
+	PaymentIBAN                   string        `mapstructure:"PAYMENT_IBAN"`
+	PaymentBIC                    string        `mapstructure:"PAYMENT_BIC"`
+	PaymentRecipientName          string        `mapstructure:"PAYMENT_RECIPIENT_NAME"`
+	PublicURL                     string        `mapstructure:"PUBLIC_URL"` // Where the API is reachable, used in calendar feed and login links
+	SMTPHost                      string        `mapstructure:"SMTP_HOST"`  // Email login is disabled without it
+	SMTPPort                      string        `mapstructure:"SMTP_PORT"`
+	SMTPUsername                  string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                  string        `mapstructure:"SMTP_PASSWORD"`
+	SMTPFrom                      string        `mapstructure:"SMTP_FROM"`
+	NotifyEmailTo                 string        `mapstructure:"NOTIFY_EMAIL_TO"` // Comma separated recipients of the notifications, needs SMTP
+	SlackWebhookURL               string        `mapstructure:"SLACK_WEBHOOK_URL"`
+	MatrixHomeserverURL           string        `mapstructure:"MATRIX_HOMESERVER_URL"`
+	MatrixAccessToken             string        `mapstructure:"MATRIX_ACCESS_TOKEN"`
+	MatrixRoomID                  string        `mapstructure:"MATRIX_ROOM_ID"`
+	NotifyWebhookURL              string        `mapstructure:"NOTIFY_WEBHOOK_URL"`
+	NotifyWebhookSecret           string        `mapstructure:"NOTIFY_WEBHOOK_SECRET"` // Signs the webhook bodies when set
 }
 
 func LoadConfig() *Config {
@@ -32,9 +50,11 @@ func LoadConfig() *Config {
 	viper.SetDefault("DISCORD_GUILD_ID", "750810991897608293")
 	viper.SetDefault("FRONTEND_URL", "http://127.0.0.1:4000/register")
 	viper.SetDefault("ACHIEVEMENT_PREFIX", "achievement::")
-	viper.SetDefault("ENABLED_EVENTS", []string{"g::t::7.0.0"})
 	viper.SetDefault("UPLOAD_DIR", "uploads/achievements")
 	viper.SetDefault("ORG_ROLE", "g::t::orgs")
+	viper.SetDefault("PUBLIC_URL", "http://127.0.0.1:8080")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("DISCORD_CACHE_TTL", "5m")
 
 	viper.BindEnv("DISCORD_CLIENT_ID")
 	viper.BindEnv("DISCORD_CLIENT_SECRET")
@@ -42,13 +62,30 @@ func LoadConfig() *Config {
 	viper.BindEnv("DISCORD_BOT_TOKEN")
 	viper.BindEnv("DISCORD_ACHIEVEMENTS_CHANNEL_ID")
 	viper.BindEnv("DISCORD_REGISTRATIONS_CHANNEL_ID")
+	viper.BindEnv("DISCORD_CACHE_TTL")
+	viper.BindEnv("DISCORD_GATEWAY")
 	viper.BindEnv("JWT_SECRET")
 	viper.BindEnv("FRONTEND_URL")
 	viper.BindEnv("ACHIEVEMENT_PREFIX")
 	viper.BindEnv("ENABLE_CORS")
-	viper.BindEnv("ENABLED_EVENTS")
 	viper.BindEnv("UPLOAD_DIR")
 	viper.BindEnv("ORG_ROLE")
+	viper.BindEnv("PAYMENT_IBAN")
+	viper.BindEnv("PAYMENT_BIC")
+	viper.BindEnv("PAYMENT_RECIPIENT_NAME")
+	viper.BindEnv("PUBLIC_URL")
+	viper.BindEnv("SMTP_HOST")
+	viper.BindEnv("SMTP_PORT")
+	viper.BindEnv("SMTP_USERNAME")
+	viper.BindEnv("SMTP_PASSWORD")
+	viper.BindEnv("SMTP_FROM")
+	viper.BindEnv("NOTIFY_EMAIL_TO")
+	viper.BindEnv("SLACK_WEBHOOK_URL")
+	viper.BindEnv("MATRIX_HOMESERVER_URL")
+	viper.BindEnv("MATRIX_ACCESS_TOKEN")
+	viper.BindEnv("MATRIX_ROOM_ID")
+	viper.BindEnv("NOTIFY_WEBHOOK_URL")
+	viper.BindEnv("NOTIFY_WEBHOOK_SECRET")
 
 	viper.AutomaticEnv()
 
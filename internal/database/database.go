@@ -2,6 +2,7 @@ package database
 
 import (
 	"log"
+	"strings"
 
 	"github.com/gdg-garage/garage-trip-api/internal/config"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
@@ -16,10 +17,34 @@ func Connect(cfg *config.Config) *gorm.DB {
 	}
 
 	// Auto Migrate
-	err = db.AutoMigrate(&models.User{}, &models.Registration{}, &models.RegistrationHistory{}, &models.Achievement{}, &models.AchievementGrant{}, &models.APIKey{})
-	if err != nil {
+	if err := Migrate(db); err != nil {
 		log.Fatalf("Failed to auto migrate: %v", err)
 	}
 
 	return db
 }
+
+// Migrate creates or updates the tables of all models
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&models.User{}, &models.Registration{}, &models.RegistrationHistory{}, &models.Achievement{}, &models.AchievementGrant{}, &models.APIKey{}, &models.Event{}, &models.EventQuestion{}, &models.Payment{}, &models.Room{}, &models.RoomAssignment{}, &models.Ride{}, &models.RideRequest{}, &models.Expense{}, &models.ExpenseShare{}, &models.Shift{}, &models.ShiftSignup{}, &models.Session{}, &models.SessionSignup{}, &models.CalendarToken{}, &models.Invite{}, &models.LoginToken{}, &models.OutboxItem{}, &models.DiscordChange{})
+	if err != nil {
+		return err
+	}
+
+	// Guests have no Discord ID, the unique index covering all users was replaced by one covering linked accounts only
+	if db.Migrator().HasIndex(&models.User{}, "idx_users_discord_id") {
+		if err := db.Migrator().DropIndex(&models.User{}, "idx_users_discord_id"); err != nil {
+			return err
+		}
+	}
+	// Event codes are unique among events which are not deleted
+	if db.Migrator().HasIndex(&models.Event{}, "idx_events_code") {
+		return db.Migrator().DropIndex(&models.Event{}, "idx_events_code")
+	}
+	return nil
+}
+
+// IsUniqueViolation reports whether the error comes from breaking a unique index
+func IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,428 @@
+package discordsync
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+// PaidRoleReferencePrefix marks the payments recorded because an org granted the paid role in Discord,
+// it is followed by the role ID
+const PaidRoleReferencePrefix = "discord-role:"
+
+// Syncer brings role changes made directly in Discord back into the database and records each of them
+type Syncer struct {
+	db      *gorm.DB
+	roles   *guildcache.Cache
+	guildID string
+	now     func() time.Time
+}
+
+func NewSyncer(db *gorm.DB, roles *guildcache.Cache, guildID string) *Syncer {
+	return &Syncer{db: db, roles: roles, guildID: guildID, now: time.Now}
+}
+
+// AddHandlers syncs the gateway events of the session, member updates come through the cache
+// which knows the roles held before them
+func (s *Syncer) AddHandlers(session *discordgo.Session) {
+	s.roles.OnMemberUpdate(func(m *discordgo.Member, previous []string, known bool) {
+		logError("member update", s.SyncMember(m, previous, known))
+	})
+	session.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildMemberAdd) {
+		logError("member join", s.MemberJoined(e.Member))
+	})
+	session.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildMemberRemove) {
+		logError("member leave", s.MemberLeft(e.Member))
+	})
+	session.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildRoleDelete) {
+		if e.GuildID == s.guildID {
+			logError("role delete", s.RoleDeleted(e.RoleID))
+		}
+	})
+}
+
+func logError(what string, err error) {
+	if err != nil {
+		log.Printf("Failed to sync Discord %s: %v", what, err)
+	}
+}
+
+// linkedUser finds the user of the guild member, ok is false for members without an account
+func (s *Syncer) linkedUser(tx *gorm.DB, m *discordgo.Member) (user models.User, ok bool, err error) {
+	if m == nil || m.User == nil || m.GuildID != s.guildID {
+		return user, false, nil
+	}
+	result := tx.Where("discord_id = ?", m.User.ID).Limit(1).Find(&user)
+	return user, result.RowsAffected > 0, result.Error
+}
+
+func record(tx *gorm.DB, user models.User, change models.DiscordChange) error {
+	change.DiscordID = user.DiscordID
+	change.UserID = &user.ID
+	return tx.Create(&change).Error
+}
+
+func roleSet(roles []string) map[string]bool {
+	set := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		set[r] = true
+	}
+	return set
+}
+
+// SyncMember makes the payments, achievement grants and profile of the user follow a member update.
+// previous holds the roles before the update, only roles added or removed by it are synced when known.
+func (s *Syncer) SyncMember(m *discordgo.Member, previous []string, known bool) error {
+	user, ok, err := s.linkedUser(s.db, m)
+	if err != nil || !ok {
+		return err
+	}
+	// Payments are only recorded for a role added by this update
+	var paidRoles map[string]string
+	if known {
+		if paidRoles, err = s.paidRoleIDs(user); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, ok, err := s.linkedUser(tx, m)
+		if err != nil || !ok {
+			return err
+		}
+
+		held := roleSet(m.Roles)
+		var had map[string]bool
+		if known {
+			had = roleSet(previous)
+		}
+
+		if err := s.syncProfile(tx, &user, m.User); err != nil {
+			return err
+		}
+		if err := s.syncPaidRoles(tx, user, paidRoles, held, had); err != nil {
+			return err
+		}
+		return s.syncAchievementRoles(tx, user, held, had)
+	})
+}
+
+// paidRoleIDs resolves the paid role of the events the user registered for by event code,
+// Discord may be called so it runs before the transaction
+func (s *Syncer) paidRoleIDs(user models.User) (map[string]string, error) {
+	var events []models.Event
+	registered := s.db.Model(&models.Registration{}).Select("event").Where("user_id = ?", user.ID)
+	if err := s.db.Where("code IN (?)", registered).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	roleIDs := make(map[string]string, len(events))
+	for _, event := range events {
+		roleID, err := s.roles.RoleID(event.PaidRole())
+		if err != nil {
+			return nil, err
+		}
+		roleIDs[event.Code] = roleID
+	}
+	return roleIDs, nil
+}
+
+func (s *Syncer) syncProfile(tx *gorm.DB, user *models.User, discordUser *discordgo.User) error {
+	var changes []string
+	updates := map[string]interface{}{}
+	if discordUser.Username != "" && discordUser.Username != user.Username {
+		changes = append(changes, fmt.Sprintf("username %q to %q", user.Username, discordUser.Username))
+		updates["username"] = discordUser.Username
+	}
+	if discordUser.Avatar != user.Avatar {
+		changes = append(changes, "avatar")
+		updates["avatar"] = discordUser.Avatar
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+	return record(tx, *user, models.DiscordChange{
+		Kind:   models.DiscordChangeProfileUpdated,
+		Detail: "Changed " + strings.Join(changes, ", "),
+	})
+}
+
+// syncPaidRoles records a payment of the outstanding amount when an org grants the paid role of an event
+// and voids such payments when the role is removed. Payments recorded by orgs in the API are left alone.
+func (s *Syncer) syncPaidRoles(tx *gorm.DB, user models.User, paidRoles map[string]string, held map[string]bool, had map[string]bool) error {
+	var registrations []models.Registration
+	if err := tx.Where("user_id = ?", user.ID).Find(&registrations).Error; err != nil {
+		return err
+	}
+
+	for _, registration := range registrations {
+		roleID := paidRoles[registration.Event]
+		if roleID == "" || held[roleID] == had[roleID] {
+			continue
+		}
+		reference := PaidRoleReferencePrefix + roleID
+
+		if held[roleID] {
+			var event models.Event
+			if err := tx.Where("code = ?", registration.Event).First(&event).Error; err != nil {
+				return fmt.Errorf("failed to fetch event %s: %w", registration.Event, err)
+			}
+			balance, err := billing.RegistrationBalance(tx, event, registration)
+			if err != nil {
+				return err
+			}
+			// Also covers the role granted by the API once the payments settled the balance
+			if balance.Outstanding <= 0 {
+				continue
+			}
+			payment := models.Payment{
+				RegistrationID: registration.ID,
+				Amount:         balance.Outstanding,
+				Currency:       balance.Currency,
+				Method:         models.PaymentMethodOther,
+				Reference:      reference,
+				Note:           "Paid role granted in Discord",
+				ReceivedAt:     s.now(),
+			}
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			err = record(tx, user, models.DiscordChange{
+				Kind:      models.DiscordChangePaidRoleAdded,
+				RoleID:    roleID,
+				Event:     event.Code,
+				PaymentID: &payment.ID,
+				Detail:    fmt.Sprintf("Recorded payment of %d %s", payment.Amount, payment.Currency),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var payments []models.Payment
+		if err := tx.Where("registration_id = ? AND reference = ? AND voided_at IS NULL", registration.ID, reference).Find(&payments).Error; err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			now := s.now()
+			if err := tx.Model(&payment).Updates(map[string]interface{}{"voided_at": now, "void_reason": "Paid role removed in Discord"}).Error; err != nil {
+				return err
+			}
+			err := record(tx, user, models.DiscordChange{
+				Kind:      models.DiscordChangePaidRoleRemoved,
+				RoleID:    roleID,
+				Event:     registration.Event,
+				PaymentID: &payment.ID,
+				Detail:    fmt.Sprintf("Voided payment of %d %s", payment.Amount, payment.Currency),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncAchievementRoles grants the achievements whose role was added to the member and revokes the ones whose
+// role was removed, all of them are reconciled when had is unknown. Grants whose role was not delivered
+// to Discord yet are kept.
+func (s *Syncer) syncAchievementRoles(tx *gorm.DB, user models.User, held map[string]bool, had map[string]bool) error {
+	var achievements []models.Achievement
+	if err := tx.Where("discord_role_id <> ''").Find(&achievements).Error; err != nil {
+		return err
+	}
+
+	for _, achievement := range achievements {
+		var grant models.AchievementGrant
+		result := tx.Where("achievement_id = ? AND user_id = ?", achievement.ID, user.ID).Limit(1).Find(&grant)
+		if result.Error != nil {
+			return result.Error
+		}
+		granted := result.RowsAffected > 0
+
+		roleID := achievement.DiscordRoleID
+		added := held[roleID] && (had == nil || !had[roleID])
+		removed := !held[roleID] && (had == nil || had[roleID])
+
+		change := models.DiscordChange{RoleID: roleID, AchievementID: &achievement.ID}
+		switch {
+		case added && !granted:
+			// Nobody in the API granted it
+			grant = models.AchievementGrant{AchievementID: achievement.ID, UserID: user.ID}
+			if err := tx.Create(&grant).Error; err != nil {
+				return err
+			}
+			change.Kind = models.DiscordChangeAchievementRoleAdded
+			change.Detail = "Granted achievement " + achievement.Name
+		case removed && granted:
+			undelivered, err := outbox.RoleGrantUndelivered(tx, user.DiscordID, roleID)
+			if err != nil {
+				return err
+			}
+			if undelivered {
+				continue
+			}
+			if err := tx.Delete(&grant).Error; err != nil {
+				return err
+			}
+			change.Kind = models.DiscordChangeAchievementRoleRemoved
+			change.Detail = "Revoked achievement " + achievement.Name
+		default:
+			continue
+		}
+		if err := record(tx, user, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemberJoined clears the leave of a returning member, syncs the profile and grants the roles of its
+// achievements and paid events again as they were lost when leaving
+func (s *Syncer) MemberJoined(m *discordgo.Member) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, ok, err := s.linkedUser(tx, m)
+		if err != nil || !ok {
+			return err
+		}
+		if err := s.syncProfile(tx, &user, m.User); err != nil {
+			return err
+		}
+		if err := s.restoreRoles(tx, user); err != nil {
+			return err
+		}
+		if user.LeftGuildAt == nil {
+			return nil
+		}
+		if err := tx.Model(&user).Update("left_guild_at", nil).Error; err != nil {
+			return err
+		}
+		return record(tx, user, models.DiscordChange{Kind: models.DiscordChangeMemberJoined, Detail: "Rejoined the guild"})
+	})
+}
+
+// restoreRoles queues the roles the user holds through the API, the achievement roles and the paid roles
+// of the registrations paid in full
+func (s *Syncer) restoreRoles(tx *gorm.DB, user models.User) error {
+	var grants []models.AchievementGrant
+	if err := tx.Preload("Achievement").Where("user_id = ?", user.ID).Find(&grants).Error; err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if grant.Achievement.DiscordRoleID == "" {
+			continue
+		}
+		if err := outbox.GrantRole(tx, user.DiscordID, grant.Achievement.DiscordRoleID); err != nil {
+			return err
+		}
+	}
+
+	var registrations []models.Registration
+	if err := tx.Where("user_id = ?", user.ID).Find(&registrations).Error; err != nil {
+		return err
+	}
+	for _, registration := range registrations {
+		var event models.Event
+		if err := tx.Where("code = ?", registration.Event).First(&event).Error; err != nil {
+			return fmt.Errorf("failed to fetch event %s: %w", registration.Event, err)
+		}
+		balance, err := billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		if !billing.PaidInFull(registration, balance) {
+			continue
+		}
+		if err := outbox.GrantRoleByName(tx, user.DiscordID, event.PaidRole()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemberLeft marks the user as gone from the guild, leaving is not an org taking roles away
+// so grants and payments are kept
+func (s *Syncer) MemberLeft(m *discordgo.Member) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, ok, err := s.linkedUser(tx, m)
+		if err != nil || !ok || user.LeftGuildAt != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("left_guild_at", s.now()).Error; err != nil {
+			return err
+		}
+		return record(tx, user, models.DiscordChange{Kind: models.DiscordChangeMemberLeft, Detail: "Left the guild"})
+	})
+}
+
+// RoleDeleted revokes what the deleted role stood for, the grants of its achievement and the payments
+// recorded because of it
+func (s *Syncer) RoleDeleted(roleID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var achievements []models.Achievement
+		if err := tx.Where("discord_role_id = ?", roleID).Find(&achievements).Error; err != nil {
+			return err
+		}
+		for _, achievement := range achievements {
+			var grants []models.AchievementGrant
+			if err := tx.Preload("User").Where("achievement_id = ?", achievement.ID).Find(&grants).Error; err != nil {
+				return err
+			}
+			for _, grant := range grants {
+				if err := tx.Delete(&grant).Error; err != nil {
+					return err
+				}
+				err := record(tx, grant.User, models.DiscordChange{
+					Kind:          models.DiscordChangeRoleDeleted,
+					RoleID:        roleID,
+					AchievementID: &achievement.ID,
+					Detail:        "Revoked achievement " + achievement.Name + " as its role was deleted",
+				})
+				if err != nil {
+					return err
+				}
+			}
+			// New grants must not try to grant the deleted role
+			if err := tx.Model(&achievement).Update("discord_role_id", "").Error; err != nil {
+				return err
+			}
+		}
+
+		var payments []models.Payment
+		if err := tx.Where("reference = ? AND voided_at IS NULL", PaidRoleReferencePrefix+roleID).Find(&payments).Error; err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			var registration models.Registration
+			if err := tx.Preload("User").First(&registration, payment.RegistrationID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&payment).Updates(map[string]interface{}{"voided_at": s.now(), "void_reason": "Paid role deleted in Discord"}).Error; err != nil {
+				return err
+			}
+			err := record(tx, registration.User, models.DiscordChange{
+				Kind:      models.DiscordChangeRoleDeleted,
+				RoleID:    roleID,
+				Event:     registration.Event,
+				PaymentID: &payment.ID,
+				Detail:    fmt.Sprintf("Voided payment of %d %s as the paid role was deleted", payment.Amount, payment.Currency),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,247 @@
+package discordsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeGuild serves the guild roles, members are never fetched by the syncer
+type fakeGuild struct {
+	guildcache.Source
+	roles []*discordgo.Role
+}
+
+func (g *fakeGuild) GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error) {
+	return g.roles, nil
+}
+
+func member(discordID string, username string, roles ...string) *discordgo.Member {
+	return &discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: discordID, Username: username}, Roles: roles}
+}
+
+func changes(db *gorm.DB, kind string) []models.DiscordChange {
+	var found []models.DiscordChange
+	db.Where("kind = ?", kind).Find(&found)
+	return found
+}
+
+func setup(t *testing.T) (*gorm.DB, *Syncer, models.User, models.Registration) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := models.Event{Code: "gt-2026", Pricing: models.EventPricing{FixedFee: 150000}}
+	db.Create(&event)
+	user := models.User{DiscordID: "alice-id", Username: "alice"}
+	db.Create(&user)
+	registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID}
+	db.Create(&registration)
+
+	guild := &fakeGuild{roles: []*discordgo.Role{{ID: "paid-role", Name: event.PaidRole()}}}
+	syncer := NewSyncer(db, guildcache.NewCache(guild, "guild", time.Minute), "guild")
+	return db, syncer, user, registration
+}
+
+func TestSyncPaidRole(t *testing.T) {
+	db, syncer, user, registration := setup(t)
+
+	// A real payment stays when the role goes
+	db.Create(&models.Payment{RegistrationID: registration.ID, Amount: 50000, Method: models.PaymentMethodCash})
+
+	// Without the roles before the update nothing tells that the role was added now
+	if err := syncer.SyncMember(member(user.DiscordID, "alice", "paid-role"), nil, false); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	var count int64
+	db.Model(&models.Payment{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected no payment for a role not known to be added, got %d payments", count)
+	}
+
+	if err := syncer.SyncMember(member(user.DiscordID, "alice", "paid-role"), nil, true); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	var payment models.Payment
+	db.Where("reference = ?", PaidRoleReferencePrefix+"paid-role").First(&payment)
+	if payment.Amount != 100000 || payment.RegistrationID != registration.ID {
+		t.Errorf("expected the outstanding amount to be recorded, got %+v", payment)
+	}
+	if added := changes(db, models.DiscordChangePaidRoleAdded); len(added) != 1 || *added[0].PaymentID != payment.ID || *added[0].UserID != user.ID {
+		t.Errorf("unexpected change records %+v", added)
+	}
+
+	// Updates keeping the role record nothing, even with an outstanding balance
+	db.Model(&models.Event{}).Where("code = ?", registration.Event).Update("pricing_fixed_fee", 200000)
+	syncer.SyncMember(member(user.DiscordID, "alice", "paid-role"), []string{"paid-role"}, true)
+	db.Model(&models.Payment{}).Where("voided_at IS NULL").Count(&count)
+	if count != 2 {
+		t.Errorf("expected no payment when the role was held before, got %d payments", count)
+	}
+
+	if err := syncer.SyncMember(member(user.DiscordID, "alice"), []string{"paid-role"}, true); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	db.First(&payment, payment.ID)
+	if payment.VoidedAt == nil {
+		t.Error("expected the payment from Discord to be voided with the role")
+	}
+	db.Model(&models.Payment{}).Where("voided_at IS NULL").Count(&count)
+	if count != 1 {
+		t.Errorf("expected the payment recorded in the API to stay, got %d payments", count)
+	}
+	if removed := changes(db, models.DiscordChangePaidRoleRemoved); len(removed) != 1 {
+		t.Errorf("expected one removal record, got %d", len(removed))
+	}
+
+	// Deleting the role voids what it recorded
+	syncer.SyncMember(member(user.DiscordID, "alice", "paid-role"), nil, true)
+	if err := syncer.RoleDeleted("paid-role"); err != nil {
+		t.Fatalf("RoleDeleted failed: %v", err)
+	}
+	db.Model(&models.Payment{}).Where("voided_at IS NULL").Count(&count)
+	if count != 1 || len(changes(db, models.DiscordChangeRoleDeleted)) != 1 {
+		t.Errorf("expected the payment to be voided with the deleted role, got %d payments", count)
+	}
+}
+
+func TestSyncAchievementRoles(t *testing.T) {
+	db, syncer, user, _ := setup(t)
+	owl := models.Achievement{Name: "Night Owl", Code: "owl", DiscordRoleID: "owl-role"}
+	db.Create(&owl)
+	chef := models.Achievement{Name: "Chef", Code: "chef", DiscordRoleID: "chef-role"}
+	db.Create(&chef)
+
+	// Granted in the API, the role is not in Discord yet
+	db.Create(&models.AchievementGrant{AchievementID: chef.ID, UserID: user.ID, GrantedByID: user.ID})
+	outbox.GrantRole(db, user.DiscordID, chef.DiscordRoleID)
+
+	if err := syncer.SyncMember(member(user.DiscordID, "alice", "owl-role"), nil, false); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	var grants []models.AchievementGrant
+	db.Where("user_id = ?", user.ID).Order("achievement_id").Find(&grants)
+	if len(grants) != 2 || grants[0].AchievementID != owl.ID {
+		t.Fatalf("expected the owl grant from Discord and the undelivered chef grant, got %+v", grants)
+	}
+
+	db.Model(&models.OutboxItem{}).Where("kind = ?", outbox.KindGrantRole).Update("status", models.OutboxDelivered)
+	if err := syncer.SyncMember(member(user.DiscordID, "alice", "owl-role"), nil, false); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	var count int64
+	db.Model(&models.AchievementGrant{}).Where("achievement_id = ?", chef.ID).Count(&count)
+	if count != 0 {
+		t.Error("expected the delivered grant to be revoked with the role removed in Discord")
+	}
+	if len(changes(db, models.DiscordChangeAchievementRoleAdded)) != 1 || len(changes(db, models.DiscordChangeAchievementRoleRemoved)) != 1 {
+		t.Error("expected the grant and the revocation to be recorded")
+	}
+
+	if err := syncer.RoleDeleted("owl-role"); err != nil {
+		t.Fatalf("RoleDeleted failed: %v", err)
+	}
+	db.First(&owl, owl.ID)
+	db.Model(&models.AchievementGrant{}).Where("achievement_id = ?", owl.ID).Count(&count)
+	if count != 0 || owl.DiscordRoleID != "" {
+		t.Errorf("expected the deleted role to revoke its grants and be unlinked, got %d grants and role %q", count, owl.DiscordRoleID)
+	}
+}
+
+func TestSyncProfileAndMembership(t *testing.T) {
+	db, syncer, user, _ := setup(t)
+
+	m := member(user.DiscordID, "alice2")
+	m.User.Avatar = "new-avatar"
+	if err := syncer.SyncMember(m, nil, false); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	db.First(&user, user.ID)
+	if user.Username != "alice2" || user.Avatar != "new-avatar" {
+		t.Errorf("expected the profile to be updated, got %+v", user)
+	}
+	if updated := changes(db, models.DiscordChangeProfileUpdated); len(updated) != 1 || updated[0].Detail != `Changed username "alice" to "alice2", avatar` {
+		t.Errorf("unexpected profile change records %+v", updated)
+	}
+
+	// Members of other guilds and strangers are ignored
+	syncer.SyncMember(&discordgo.Member{GuildID: "other", User: &discordgo.User{ID: user.DiscordID, Username: "mallory"}}, nil, false)
+	syncer.SyncMember(member("stranger", "bob"), nil, false)
+	db.First(&user, user.ID)
+	if user.Username != "alice2" {
+		t.Errorf("expected other guilds to be ignored, got %s", user.Username)
+	}
+
+	if err := syncer.MemberLeft(m); err != nil {
+		t.Fatalf("MemberLeft failed: %v", err)
+	}
+	db.First(&user, user.ID)
+	if user.LeftGuildAt == nil {
+		t.Error("expected the leave to be recorded on the user")
+	}
+	if err := syncer.MemberJoined(m); err != nil {
+		t.Fatalf("MemberJoined failed: %v", err)
+	}
+	var rejoined models.User
+	db.First(&rejoined, user.ID)
+	if rejoined.LeftGuildAt != nil {
+		t.Error("expected the rejoin to clear the leave")
+	}
+	if len(changes(db, models.DiscordChangeMemberLeft)) != 1 || len(changes(db, models.DiscordChangeMemberJoined)) != 1 {
+		t.Error("expected the leave and the rejoin to be recorded")
+	}
+}
+
+func TestSyncRejoin(t *testing.T) {
+	db, syncer, user, _ := setup(t)
+	owl := models.Achievement{Name: "Night Owl", Code: "owl", DiscordRoleID: "owl-role"}
+	db.Create(&owl)
+
+	m := member(user.DiscordID, "alice", "paid-role", "owl-role")
+	if err := syncer.SyncMember(m, nil, true); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+
+	// Leaving takes the roles, the member comes back without them
+	if err := syncer.MemberLeft(m); err != nil {
+		t.Fatalf("MemberLeft failed: %v", err)
+	}
+	if err := syncer.MemberJoined(member(user.DiscordID, "alice")); err != nil {
+		t.Fatalf("MemberJoined failed: %v", err)
+	}
+	var grants []models.OutboxItem
+	db.Where("kind = ?", outbox.KindGrantRole).Order("id").Find(&grants)
+	if len(grants) != 2 || grants[0].Payload != `{"discord_id":"alice-id","role_id":"owl-role"}` || grants[1].Payload != `{"discord_id":"alice-id","role_name":"gt-2026::paid"}` {
+		t.Fatalf("expected the achievement and paid roles to be granted again, got %+v", grants)
+	}
+
+	// The first update after the rejoin holds no roles, before and after the cache knows the member
+	if err := syncer.SyncMember(member(user.DiscordID, "alice"), nil, true); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	if err := syncer.SyncMember(member(user.DiscordID, "alice"), nil, false); err != nil {
+		t.Fatalf("SyncMember failed: %v", err)
+	}
+	var count int64
+	db.Model(&models.Payment{}).Where("voided_at IS NULL").Count(&count)
+	if count != 1 {
+		t.Error("expected the payment from Discord to stay after the rejoin")
+	}
+	db.Model(&models.AchievementGrant{}).Where("achievement_id = ?", owl.ID).Count(&count)
+	if count != 1 {
+		t.Error("expected the achievement to stay after the rejoin")
+	}
+	if len(changes(db, models.DiscordChangePaidRoleRemoved)) != 0 || len(changes(db, models.DiscordChangeAchievementRoleRemoved)) != 0 {
+		t.Error("expected no removals recorded after the rejoin")
+	}
+}
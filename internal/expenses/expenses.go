@@ -0,0 +1,160 @@
+// Package expenses splits expenses shared by attendees and computes how to settle them up
+package expenses
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserBalance summarizes the shared expenses of a user, amounts are in minor currency units
+type UserBalance struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Paid     int64  `json:"paid" doc:"Total paid for others and themselves"`
+	Share    int64  `json:"share" doc:"Total share of the expenses"`
+	Net      int64  `json:"net" doc:"Positive when others owe the user, negative when the user owes others"`
+}
+
+// Summary is the part of the settlement of an event concerning a single user
+type Summary struct {
+	UserBalance
+	Currency  string            `json:"currency"`
+	Transfers []models.Transfer `json:"transfers" doc:"Transfers the user makes or receives to settle up"`
+}
+
+// Split divides the amount by the shares, the remainder goes one unit at a time to the participants
+// with the largest fractions and the earliest position
+func Split(amount int64, shares []int) []int64 {
+	parts := make([]int64, len(shares))
+	var total int64
+	for _, s := range shares {
+		total += int64(s)
+	}
+	if total == 0 {
+		return parts
+	}
+
+	rest := amount
+	fractions := make([]int, len(shares))
+	for i, s := range shares {
+		parts[i] = amount * int64(s) / total
+		rest -= parts[i]
+		fractions[i] = i
+	}
+	slices.SortStableFunc(fractions, func(a int, b int) int {
+		return cmp.Compare(amount*int64(shares[b])%total, amount*int64(shares[a])%total)
+	})
+	for i := 0; rest > 0; i++ {
+		parts[fractions[i%len(fractions)]]++
+		rest--
+	}
+	return parts
+}
+
+// Balances sums what each user paid and owes, shares and the payer must be loaded
+func Balances(expenses []models.Expense) map[uint]UserBalance {
+	balances := make(map[uint]UserBalance)
+	add := func(user models.User, userID uint, paid int64, share int64) {
+		b := balances[userID]
+		b.UserID = userID
+		if user.Username != "" {
+			b.Username = user.Username
+		}
+		b.Paid += paid
+		b.Share += share
+		b.Net = b.Paid - b.Share
+		balances[userID] = b
+	}
+	for _, e := range expenses {
+		add(e.Payer, e.PayerID, e.Amount, 0)
+		for _, s := range e.Shares {
+			add(s.User, s.UserID, 0, s.Amount)
+		}
+	}
+	return balances
+}
+
+// Settle computes transfers settling the balances. Debts matching a credit exactly are paid directly,
+// the rest goes from the largest debtor to the largest creditor, so there are at most n-1 transfers.
+func Settle(balances map[uint]UserBalance) []models.Transfer {
+	var debtors, creditors []UserBalance
+	for _, b := range balances {
+		if b.Net < 0 {
+			debtors = append(debtors, b)
+		} else if b.Net > 0 {
+			creditors = append(creditors, b)
+		}
+	}
+	byAmount := func(a UserBalance, b UserBalance) int {
+		return cmp.Or(cmp.Compare(abs(b.Net), abs(a.Net)), cmp.Compare(a.UserID, b.UserID))
+	}
+
+	var transfers []models.Transfer
+	pay := func(from *UserBalance, to *UserBalance, amount int64) {
+		transfers = append(transfers, models.Transfer{
+			FromID:       from.UserID,
+			FromUsername: from.Username,
+			ToID:         to.UserID,
+			ToUsername:   to.Username,
+			Amount:       amount,
+		})
+		from.Net += amount
+		to.Net -= amount
+	}
+
+	slices.SortFunc(debtors, byAmount)
+	slices.SortFunc(creditors, byAmount)
+	for i := range debtors {
+		for j := range creditors {
+			if creditors[j].Net > 0 && creditors[j].Net == -debtors[i].Net {
+				pay(&debtors[i], &creditors[j], creditors[j].Net)
+				break
+			}
+		}
+	}
+
+	for {
+		debtors = slices.DeleteFunc(debtors, func(b UserBalance) bool { return b.Net == 0 })
+		creditors = slices.DeleteFunc(creditors, func(b UserBalance) bool { return b.Net == 0 })
+		if len(debtors) == 0 || len(creditors) == 0 {
+			return transfers
+		}
+		slices.SortFunc(debtors, byAmount)
+		slices.SortFunc(creditors, byAmount)
+		pay(&debtors[0], &creditors[0], min(-debtors[0].Net, creditors[0].Net))
+	}
+}
+
+// UserSummary returns the balance and the transfers of the user, false when the user shares no expense
+func UserSummary(expenses []models.Expense, userID uint) (Summary, bool) {
+	balances := Balances(expenses)
+	balance, ok := balances[userID]
+	if !ok {
+		return Summary{}, false
+	}
+
+	summary := Summary{UserBalance: balance, Currency: expenses[0].Currency, Transfers: []models.Transfer{}}
+	for _, t := range Settle(balances) {
+		if t.FromID == userID || t.ToID == userID {
+			summary.Transfers = append(summary.Transfers, t)
+		}
+	}
+	return summary, true
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Load fetches the expenses of an event with their payers and participants
+func Load(db *gorm.DB, eventID uint) ([]models.Expense, error) {
+	var expenses []models.Expense
+	err := db.Preload("Payer").Preload("Shares.User").Where("event_id = ?", eventID).Order("spent_at ASC, id ASC").Find(&expenses).Error
+	return expenses, err
+}
@@ -0,0 +1,89 @@
+package expenses
+
+import (
+	"testing"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func TestSplit(t *testing.T) {
+	parts := Split(1000, []int{1, 1, 1})
+	if parts[0] != 334 || parts[1] != 333 || parts[2] != 333 {
+		t.Errorf("expected the remainder to go to the first participant, got %v", parts)
+	}
+	parts = Split(1000, []int{1, 2, 0})
+	if parts[0] != 333 || parts[1] != 667 || parts[2] != 0 {
+		t.Errorf("expected the split by shares, got %v", parts)
+	}
+	if parts := Split(100, []int{0}); parts[0] != 0 {
+		t.Errorf("expected nothing to split without shares, got %v", parts)
+	}
+}
+
+func expense(payer uint, amount int64, participants ...uint) models.Expense {
+	e := models.Expense{PayerID: payer, Amount: amount}
+	shares := make([]int, len(participants))
+	for i := range participants {
+		shares[i] = 1
+	}
+	for i, part := range Split(amount, shares) {
+		e.Shares = append(e.Shares, models.ExpenseShare{UserID: participants[i], Shares: 1, Amount: part})
+	}
+	return e
+}
+
+func TestSettle(t *testing.T) {
+	balances := Balances([]models.Expense{
+		expense(1, 900, 1, 2, 3),
+		expense(2, 300, 2, 4),
+		expense(4, 100, 3),
+	})
+	if b := balances[1]; b.Paid != 900 || b.Share != 300 || b.Net != 600 {
+		t.Errorf("unexpected balance of the first payer %+v", b)
+	}
+
+	transfers := Settle(balances)
+	net := map[uint]int64{}
+	for _, tr := range transfers {
+		if tr.Amount <= 0 {
+			t.Errorf("unexpected transfer %+v", tr)
+		}
+		net[tr.FromID] += tr.Amount
+		net[tr.ToID] -= tr.Amount
+	}
+	for id, b := range balances {
+		if net[id] != -b.Net {
+			t.Errorf("user %d is not settled: balance %d, transferred %d", id, b.Net, net[id])
+		}
+	}
+	if len(transfers) > len(balances)-1 {
+		t.Errorf("expected at most %d transfers, got %v", len(balances)-1, transfers)
+	}
+
+	exact := Settle(map[uint]UserBalance{
+		1: {UserID: 1, Net: 500},
+		2: {UserID: 2, Net: 300},
+		3: {UserID: 3, Net: -300},
+		4: {UserID: 4, Net: -200},
+		5: {UserID: 5, Net: -300},
+	})
+	if len(exact) != 3 || exact[0].FromID != 3 || exact[0].ToID != 2 {
+		t.Errorf("expected the matching debt to be paid directly, got %+v", exact)
+	}
+}
+
+func TestUserSummary(t *testing.T) {
+	list := []models.Expense{expense(1, 600, 1, 2, 3)}
+	list[0].Currency = "CZK"
+
+	summary, ok := UserSummary(list, 2)
+	if !ok || summary.Net != -200 || summary.Currency != "CZK" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if len(summary.Transfers) != 1 || summary.Transfers[0].ToID != 1 {
+		t.Errorf("expected a single transfer to the payer, got %+v", summary.Transfers)
+	}
+	if _, ok := UserSummary(list, 4); ok {
+		t.Error("expected no summary for a user without expenses")
+	}
+}
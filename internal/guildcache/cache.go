@@ -0,0 +1,240 @@
+package guildcache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MembersPageSize is the most members Discord returns in one page of the member list
+const MembersPageSize = 1000
+
+// Source is the part of the Discord REST API the cache reads from, implemented by *discordgo.Session
+type Source interface {
+	GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error)
+	GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	GuildMembers(guildID string, after string, limit int, options ...discordgo.RequestOption) ([]*discordgo.Member, error)
+}
+
+// member holds the roles of a guild member, none for users not in the guild
+type member struct {
+	roles     []string
+	fetchedAt time.Time
+	stale     bool // Invalidated after a role change, fetched again on the next lookup
+}
+
+// Cache keeps the guild roles and the roles of its members in memory so role checks
+// do not cost a Discord call each. Entries expire after the TTL, get invalidated after
+// role changes made by the API and are updated from gateway events when connected.
+// Discord is called without holding the lock, a fetch started before a newer change is not stored.
+type Cache struct {
+	source  Source
+	guildID string
+	TTL     time.Duration
+	now     func() time.Time
+
+	mu             sync.Mutex
+	roles          map[string]string // Role name to ID
+	rolesAt        time.Time
+	rolesChangedAt time.Time // Last role event or invalidation
+	members        map[string]member
+	membersAt      time.Time // When the whole member list was loaded
+	membersResetAt time.Time // Last invalidation of all members
+	updateHandlers []MemberUpdateHandler
+}
+
+func NewCache(source Source, guildID string, ttl time.Duration) *Cache {
+	return &Cache{
+		source:  source,
+		guildID: guildID,
+		TTL:     ttl,
+		now:     time.Now,
+		members: make(map[string]member),
+	}
+}
+
+func (c *Cache) fresh(at time.Time) bool {
+	return !at.IsZero() && c.now().Sub(at) < c.TTL
+}
+
+// RoleID returns the ID of the guild role with the name, empty when there is none
+func (c *Cache) RoleID(name string) (string, error) {
+	c.mu.Lock()
+	if c.roles != nil && c.fresh(c.rolesAt) {
+		id := c.roles[name]
+		c.mu.Unlock()
+		return id, nil
+	}
+	c.mu.Unlock()
+
+	fetchedAt := c.now()
+	list, err := c.source.GuildRoles(c.guildID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch guild roles: %w", err)
+	}
+	roles := make(map[string]string, len(list))
+	for _, r := range list {
+		// The first role wins when several share a name
+		if _, ok := roles[r.Name]; !ok {
+			roles[r.Name] = r.ID
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !fetchedAt.Before(c.rolesChangedAt) {
+		c.roles = roles
+		c.rolesAt = fetchedAt
+	}
+	return roles[name], nil
+}
+
+// MemberRoles returns the role IDs of the guild member, none when the user is not in the guild
+func (c *Cache) MemberRoles(discordID string) ([]string, error) {
+	c.mu.Lock()
+	m, ok := c.members[discordID]
+	if ok && !m.stale && c.fresh(m.fetchedAt) {
+		c.mu.Unlock()
+		return m.roles, nil
+	}
+	// Anyone missing from a fresh member list is not in the guild
+	if !ok && c.fresh(c.membersAt) {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	c.mu.Unlock()
+
+	fetchedAt := c.now()
+	var roles []string
+	fetched, err := c.source.GuildMember(c.guildID, discordID)
+	if err == nil {
+		roles = fetched.Roles
+	} else {
+		var restErr *discordgo.RESTError
+		if !errors.As(err, &restErr) || restErr.Response == nil || restErr.Response.StatusCode != http.StatusNotFound {
+			return nil, fmt.Errorf("failed to fetch guild member: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(discordID, member{roles: roles, fetchedAt: fetchedAt})
+	return roles, nil
+}
+
+// store keeps the fetched member unless the cache changed after the fetch started, c.mu must be held
+func (c *Cache) store(discordID string, m member) {
+	if m.fetchedAt.Before(c.membersResetAt) {
+		return
+	}
+	if current, ok := c.members[discordID]; ok && current.fetchedAt.After(m.fetchedAt) {
+		return
+	}
+	c.members[discordID] = m
+}
+
+// HasRole reports whether the guild member holds the role with the ID
+func (c *Cache) HasRole(discordID string, roleID string) (bool, error) {
+	roles, err := c.MemberRoles(discordID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == roleID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasRoleNamed reports whether the guild member holds the role with the name
+func (c *Cache) HasRoleNamed(discordID string, roleName string) (bool, error) {
+	roleID, err := c.RoleID(roleName)
+	if err != nil || roleID == "" {
+		return false, err
+	}
+	return c.HasRole(discordID, roleID)
+}
+
+// LoadMembers fetches the whole member list page by page, which is cheaper than
+// fetching members one by one before checking the roles of many users.
+// Listing members needs the privileged server members intent of the bot.
+func (c *Cache) LoadMembers() error {
+	c.mu.Lock()
+	if c.fresh(c.membersAt) {
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	fetchedAt := c.now()
+	var list []*discordgo.Member
+	after := ""
+	for {
+		page, err := c.source.GuildMembers(c.guildID, after, MembersPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch guild members: %w", err)
+		}
+		for _, m := range page {
+			if m.User == nil {
+				continue
+			}
+			list = append(list, m)
+			after = m.User.ID
+		}
+		if len(page) < MembersPageSize {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fetchedAt.Before(c.membersResetAt) {
+		return nil
+	}
+	// Members updated while the list was fetched keep their newer roles
+	members := make(map[string]member, len(list))
+	for _, m := range list {
+		members[m.User.ID] = member{roles: m.Roles, fetchedAt: fetchedAt}
+	}
+	for id, m := range c.members {
+		if m.fetchedAt.After(fetchedAt) {
+			members[id] = m
+		}
+	}
+	c.members = members
+	c.membersAt = fetchedAt
+	return nil
+}
+
+// Invalidate drops everything so the next lookups go to Discord
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.roles = nil
+	c.rolesChangedAt = now
+	c.members = make(map[string]member)
+	c.membersAt = time.Time{}
+	c.membersResetAt = now
+}
+
+// InvalidateRoles drops the guild roles, call it after creating or renaming roles
+func (c *Cache) InvalidateRoles() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roles = nil
+	c.rolesChangedAt = c.now()
+}
+
+// InvalidateMember drops the roles of the member, call it after granting or removing a role.
+// The member stays listed as stale, a fresh member list would otherwise report them gone.
+func (c *Cache) InvalidateMember(discordID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.members[discordID] = member{fetchedAt: c.now(), stale: true}
+}
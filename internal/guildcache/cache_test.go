@@ -0,0 +1,213 @@
+package guildcache
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeSource serves a guild from memory and counts the calls made to it
+type fakeSource struct {
+	roles       []*discordgo.Role
+	members     map[string][]string
+	order       []string
+	roleCalls   int
+	memberCalls int
+	listCalls   int
+}
+
+func (f *fakeSource) GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error) {
+	f.roleCalls++
+	return f.roles, nil
+}
+
+func (f *fakeSource) GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	f.memberCalls++
+	roles, ok := f.members[userID]
+	if !ok {
+		return nil, &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	}
+	return &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: userID}, Roles: roles}, nil
+}
+
+func (f *fakeSource) GuildMembers(guildID string, after string, limit int, options ...discordgo.RequestOption) ([]*discordgo.Member, error) {
+	f.listCalls++
+	var page []*discordgo.Member
+	for _, id := range f.order {
+		if id > after && len(page) < limit {
+			page = append(page, &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: id}, Roles: f.members[id]})
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeSource) add(userID string, roles ...string) {
+	f.members[userID] = roles
+	f.order = append(f.order, userID)
+}
+
+func newTestCache(source *fakeSource) (*Cache, *time.Time) {
+	now := time.Now()
+	cache := NewCache(source, "guild", time.Minute)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestCacheExpiresAndInvalidates(t *testing.T) {
+	source := &fakeSource{roles: []*discordgo.Role{{ID: "1", Name: "orgs"}, {ID: "2", Name: "paid"}}, members: map[string][]string{}}
+	source.add("alice", "1")
+	cache, now := newTestCache(source)
+
+	for i := 0; i < 3; i++ {
+		if ok, err := cache.HasRoleNamed("alice", "orgs"); err != nil || !ok {
+			t.Fatalf("expected alice to be an org, got %v %v", ok, err)
+		}
+		if ok, _ := cache.HasRoleNamed("bob", "orgs"); ok {
+			t.Error("expected users outside the guild to hold no roles")
+		}
+	}
+	if source.roleCalls != 1 || source.memberCalls != 2 {
+		t.Errorf("expected repeated checks to be cached, got %d role and %d member calls", source.roleCalls, source.memberCalls)
+	}
+	if ok, err := cache.HasRoleNamed("alice", "missing"); err != nil || ok {
+		t.Errorf("expected unknown role to be held by nobody, got %v %v", ok, err)
+	}
+
+	// Role changes made by the API invalidate the member
+	source.members["alice"] = []string{"1", "2"}
+	if ok, _ := cache.HasRoleNamed("alice", "paid"); ok {
+		t.Error("expected the cached roles before invalidation")
+	}
+	cache.InvalidateMember("alice")
+	if ok, _ := cache.HasRoleNamed("alice", "paid"); !ok {
+		t.Error("expected fresh roles after invalidation")
+	}
+
+	*now = now.Add(time.Minute)
+	cache.HasRoleNamed("alice", "paid")
+	if source.roleCalls != 2 || source.memberCalls != 4 {
+		t.Errorf("expected expired entries to be fetched again, got %d role and %d member calls", source.roleCalls, source.memberCalls)
+	}
+}
+
+func TestCacheLoadMembers(t *testing.T) {
+	source := &fakeSource{members: map[string][]string{}}
+	for i := 0; i < MembersPageSize+10; i++ {
+		source.add(fmt.Sprintf("user-%05d", i), "paid")
+	}
+	cache, _ := newTestCache(source)
+
+	if err := cache.LoadMembers(); err != nil {
+		t.Fatalf("LoadMembers failed: %v", err)
+	}
+	if source.listCalls != 2 {
+		t.Errorf("expected the member list in 2 pages, got %d calls", source.listCalls)
+	}
+	if ok, _ := cache.HasRole(source.order[MembersPageSize+5], "paid"); !ok {
+		t.Error("expected members of the second page to be loaded")
+	}
+	if ok, _ := cache.HasRole("stranger", "paid"); ok {
+		t.Error("expected users missing from the member list to hold no roles")
+	}
+	cache.LoadMembers()
+	if source.memberCalls != 0 || source.listCalls != 2 {
+		t.Errorf("expected lookups from the loaded list, got %d member and %d list calls", source.memberCalls, source.listCalls)
+	}
+
+	// Invalidating one member keeps the list for the others
+	cache.InvalidateMember(source.order[0])
+	cache.HasRole(source.order[0], "paid")
+	cache.HasRole(source.order[1], "paid")
+	cache.HasRole("stranger", "paid")
+	if source.memberCalls != 1 || source.listCalls != 2 {
+		t.Errorf("expected only the invalidated member to be fetched, got %d member and %d list calls", source.memberCalls, source.listCalls)
+	}
+}
+
+// blockingSource holds member fetches until released
+type blockingSource struct {
+	*fakeSource
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (b *blockingSource) GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	b.fetching <- struct{}{}
+	<-b.release
+	return b.fakeSource.GuildMember(guildID, userID, options...)
+}
+
+func TestCacheFetchesWithoutLock(t *testing.T) {
+	source := &fakeSource{roles: []*discordgo.Role{{ID: "1", Name: "orgs"}}, members: map[string][]string{}}
+	source.add("alice")
+	blocking := &blockingSource{fakeSource: source, fetching: make(chan struct{}), release: make(chan struct{})}
+	cache := NewCache(blocking, "guild", time.Minute)
+	cache.memberChanged(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "bob"}, Roles: []string{"1"}})
+
+	done := make(chan []string)
+	go func() {
+		roles, _ := cache.MemberRoles("alice")
+		done <- roles
+	}()
+	<-blocking.fetching
+
+	// Other lookups and gateway events go on while Discord answers
+	if ok, err := cache.HasRoleNamed("bob", "orgs"); err != nil || !ok {
+		t.Errorf("expected cached lookups during a fetch, got %v %v", ok, err)
+	}
+	cache.memberChanged(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "alice"}, Roles: []string{"1"}})
+
+	close(blocking.release)
+	<-done
+	// The event is newer than the fetched member
+	if ok, _ := cache.HasRoleNamed("alice", "orgs"); !ok {
+		t.Error("expected the roles from the event to win over the older fetch")
+	}
+}
+
+func TestCacheGatewayEvents(t *testing.T) {
+	source := &fakeSource{roles: []*discordgo.Role{{ID: "1", Name: "orgs"}}, members: map[string][]string{}}
+	source.add("alice")
+	cache, _ := newTestCache(source)
+	cache.HasRoleNamed("alice", "orgs")
+
+	cache.roleChanged(&discordgo.GuildRole{GuildID: "guild", Role: &discordgo.Role{ID: "1", Name: "organizers"}})
+	cache.roleChanged(&discordgo.GuildRole{GuildID: "guild", Role: &discordgo.Role{ID: "2", Name: "paid"}})
+	var updates [][]string
+	cache.OnMemberUpdate(func(m *discordgo.Member, previous []string, known bool) {
+		if known {
+			updates = append(updates, previous)
+		}
+	})
+	cache.memberUpdated(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "alice"}, Roles: []string{"1"}})
+	cache.memberUpdated(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "alice"}, Roles: []string{"1", "2"}})
+	cache.memberUpdated(&discordgo.Member{GuildID: "other", User: &discordgo.User{ID: "alice"}})
+	if len(updates) != 2 || len(updates[0]) != 0 || len(updates[1]) != 1 || updates[1][0] != "1" {
+		t.Errorf("expected the roles held before each update, got %v", updates)
+	}
+
+	if id, _ := cache.RoleID("orgs"); id != "" {
+		t.Errorf("expected renamed role to be gone under its old name, got %q", id)
+	}
+	if ok, _ := cache.HasRoleNamed("alice", "organizers"); !ok {
+		t.Error("expected the renamed role from the update event")
+	}
+	if ok, _ := cache.HasRoleNamed("alice", "paid"); !ok {
+		t.Error("expected the new role from the member update")
+	}
+
+	cache.memberRemoved(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "alice"}})
+	if ok, _ := cache.HasRoleNamed("alice", "organizers"); ok {
+		t.Error("expected removed member to hold no roles")
+	}
+	cache.roleDeleted("guild", "2")
+	if id, _ := cache.RoleID("paid"); id != "" {
+		t.Errorf("expected deleted role to be gone, got %q", id)
+	}
+	if source.roleCalls != 1 || source.memberCalls != 1 {
+		t.Errorf("expected events to update the cache without Discord calls, got %d role and %d member calls", source.roleCalls, source.memberCalls)
+	}
+}
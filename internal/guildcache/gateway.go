@@ -0,0 +1,106 @@
+package guildcache
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Intents are the gateway intents needed to keep the cache fresh
+const Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMembers
+
+// MemberUpdateHandler is called after a member update event with the roles the member held before it,
+// known is false when the cache did not know them
+type MemberUpdateHandler func(m *discordgo.Member, previous []string, known bool)
+
+// OnMemberUpdate calls the handler on every member update of the guild. The roles are swapped in the cache
+// on the same event, so the handler never sees the roles of another update as the previous ones.
+func (c *Cache) OnMemberUpdate(handler MemberUpdateHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updateHandlers = append(c.updateHandlers, handler)
+}
+
+// AddHandlers keeps the cache up to date from the gateway events of the session
+func (c *Cache) AddHandlers(session *discordgo.Session) {
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleCreate) { c.roleChanged(e.GuildRole) })
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleUpdate) { c.roleChanged(e.GuildRole) })
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildRoleDelete) { c.roleDeleted(e.GuildID, e.RoleID) })
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberAdd) { c.memberChanged(e.Member) })
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberUpdate) { c.memberUpdated(e.Member) })
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildMemberRemove) { c.memberRemoved(e.Member) })
+	// Events may have been missed while disconnected
+	session.AddHandler(func(s *discordgo.Session, e *discordgo.Resumed) { c.Invalidate() })
+}
+
+func (c *Cache) roleChanged(r *discordgo.GuildRole) {
+	if r == nil || r.Role == nil || r.GuildID != c.guildID {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rolesChangedAt = c.now()
+	if c.roles == nil {
+		return
+	}
+	// A renamed role keeps its ID
+	for name, id := range c.roles {
+		if id == r.Role.ID {
+			delete(c.roles, name)
+		}
+	}
+	if _, ok := c.roles[r.Role.Name]; !ok {
+		c.roles[r.Role.Name] = r.Role.ID
+	}
+}
+
+func (c *Cache) roleDeleted(guildID string, roleID string) {
+	if guildID != c.guildID {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rolesChangedAt = c.now()
+	for name, id := range c.roles {
+		if id == roleID {
+			delete(c.roles, name)
+		}
+	}
+}
+
+func (c *Cache) memberUpdated(m *discordgo.Member) {
+	if m == nil || m.User == nil || m.GuildID != c.guildID {
+		return
+	}
+	previous, known := c.memberChanged(m)
+	c.mu.Lock()
+	handlers := c.updateHandlers
+	c.mu.Unlock()
+	for _, handler := range handlers {
+		handler(m, previous, known)
+	}
+}
+
+// memberChanged stores the roles of the member and returns the ones it replaced
+func (c *Cache) memberChanged(m *discordgo.Member) (previous []string, known bool) {
+	if m == nil || m.User == nil || m.GuildID != c.guildID {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.members[m.User.ID]; ok {
+		previous, known = current.roles, !current.stale
+	} else {
+		// Not in the loaded member list
+		known = !c.membersAt.IsZero()
+	}
+	c.members[m.User.ID] = member{roles: m.Roles, fetchedAt: c.now()}
+	return previous, known
+}
+
+func (c *Cache) memberRemoved(m *discordgo.Member) {
+	if m == nil || m.User == nil || m.GuildID != c.guildID {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.members[m.User.ID] = member{fetchedAt: c.now()}
+}
@@ -13,18 +13,20 @@ import (
 	"github.com/gdg-garage/garage-trip-api/internal/config"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
 	"gorm.io/gorm"
 )
 
 type AchievementHandler struct {
 	db          *gorm.DB
 	notifier    notifier.Notifier
+	roles       notifier.RoleManager
 	authHandler *auth.AuthHandler
 	config      *config.Config
 }
 
-func NewAchievementHandler(db *gorm.DB, notifier notifier.Notifier, authHandler *auth.AuthHandler, cfg *config.Config) *AchievementHandler {
-	return &AchievementHandler{db: db, notifier: notifier, authHandler: authHandler, config: cfg}
+func NewAchievementHandler(db *gorm.DB, notifier notifier.Notifier, roles notifier.RoleManager, authHandler *auth.AuthHandler, cfg *config.Config) *AchievementHandler {
+	return &AchievementHandler{db: db, notifier: notifier, roles: roles, authHandler: authHandler, config: cfg}
 }
 
 type CreateAchievementRequest struct {
@@ -98,10 +100,13 @@ func (h *AchievementHandler) HandleCreateAchievement(ctx context.Context, input
 		}
 	}
 
-	// 5. Create Discord Role
-	roleID, err := h.notifier.CreateRole(data.Name)
-	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to create discord role: " + err.Error())
+	// 5. Create Discord Role, without Discord the achievement has none
+	var roleID string
+	if h.roles != nil {
+		roleID, err = h.roles.CreateRole(data.Name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to create discord role: " + err.Error())
+		}
 	}
 
 	// 6. Create Achievement
@@ -177,48 +182,48 @@ func (h *AchievementHandler) HandleGrantAchievement(ctx context.Context, input *
 		return nil, huma.Error500InternalServerError("Database error checking grant: " + err.Error())
 	}
 
-	// 4. Grant Role on Discord
+	// 4. Find the target user
 	var targetUser models.User
 	if err := h.db.First(&targetUser, targetUserID).Error; err != nil {
 		return nil, huma.Error404NotFound("Target user not found")
 	}
 
-	// 4. Check if user already has the role on Discord
-	hasRole, err := h.notifier.HasRole(targetUser.DiscordID, achievement.DiscordRoleID)
-	if err != nil {
-		log.Printf("Failed to check discord role: %v", err)
-	} else if hasRole {
-		return nil, huma.Error409Conflict("User already has the Discord role for this achievement")
-	}
-
-	if err := h.notifier.GrantRole(targetUser.DiscordID, achievement.DiscordRoleID); err != nil {
-		// Log but don't fail complete flow if discord fails? Or fail?
-		// Requirement says "cannot be granted again", implying strong consistency.
-		// However, discord might be down or temporary issue.
-		// Let's log error and try to proceed but maybe return error?
-		// User requirement "grants them the role".
-		log.Printf("Failed to grant discord role: %v", err)
-		return nil, huma.Error500InternalServerError("Failed to grant discord role: " + err.Error())
+	// 5. Check if user already has the role on Discord
+	if h.roles != nil && targetUser.DiscordID != "" && achievement.DiscordRoleID != "" {
+		hasRole, err := h.roles.HasRole(targetUser.DiscordID, achievement.DiscordRoleID)
+		if err != nil {
+			log.Printf("Failed to check discord role: %v", err)
+		} else if hasRole {
+			return nil, huma.Error409Conflict("User already has the Discord role for this achievement")
+		}
 	}
 
-	// 5. Create AchievementGrant in DB
+	// 6. Create AchievementGrant in DB, the role and the notification are delivered by the outbox worker
 	grant := models.AchievementGrant{
 		AchievementID: achievement.ID,
 		UserID:        targetUserID,
 		GrantedByID:   grantorID,
 	}
 
-	if err := h.db.Create(&grant).Error; err != nil {
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&grant).Error; err != nil {
+			return err
+		}
+		// Guests get the role once they link Discord, achievements whose role was deleted in Discord have none
+		if h.roles != nil && targetUser.DiscordID != "" && achievement.DiscordRoleID != "" {
+			if err := outbox.GrantRole(tx, targetUser.DiscordID, achievement.DiscordRoleID); err != nil {
+				return err
+			}
+		}
+		if h.notifier == nil {
+			return nil
+		}
+		return outbox.NotifyAchievement(tx, grant.ID)
+	})
+	if err != nil {
 		return nil, huma.Error500InternalServerError("Failed to record grant: " + err.Error())
 	}
 
-	// 6. Send Discord Notification
-	showGrantor := targetUserID != grantorID
-	if err := h.notifier.NotifyAchievement(targetUser, achievement, grantor, showGrantor); err != nil {
-		log.Printf("Failed to send notification: %v", err)
-		// Don't fail the request here as role and DB are done
-	}
-
 	res := &GrantAchievementResponse{}
 	res.Body.Message = fmt.Sprintf("Achievement '%s' granted to %s", achievement.Name, targetUser.Username)
 
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/bankimport"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportStatusMatched   = "matched"   // Settles the outstanding balance exactly, recorded
+	ImportStatusPartial   = "partial"   // Less than the outstanding balance, not recorded
+	ImportStatusOverpaid  = "overpaid"  // More than the outstanding balance, not recorded
+	ImportStatusUnmatched = "unmatched" // No registration found, nothing recorded
+	ImportStatusDuplicate = "duplicate" // Already imported before
+)
+
+type ImportStatementRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	RawBody        huma.MultipartFormFiles[struct {
+		Statement huma.FormFile `form:"statement" contentType:"text/csv,text/plain,application/xml,text/xml,application/octet-stream" required:"true" doc:"CSV or camt.053 XML bank statement"`
+	}]
+}
+
+type ImportedTransaction struct {
+	bankimport.Transaction
+	Status         string           `json:"status" enum:"matched,partial,overpaid,unmatched,duplicate"`
+	Reason         string           `json:"reason,omitempty" doc:"Why the transaction was not recorded"`
+	RegistrationID uint             `json:"registration_id,omitempty"`
+	PaymentID      uint             `json:"payment_id,omitempty"`
+	Balance        *billing.Balance `json:"balance,omitempty" doc:"Balance of the registration, after the payment when it was recorded"`
+}
+
+type ImportStatementResponse struct {
+	Body struct {
+		Matched      int                   `json:"matched"`
+		Partial      int                   `json:"partial"`
+		Overpaid     int                   `json:"overpaid"`
+		Unmatched    int                   `json:"unmatched"`
+		Duplicate    int                   `json:"duplicate"`
+		Outgoing     int                   `json:"outgoing" doc:"Outgoing transactions which are skipped"`
+		Transactions []ImportedTransaction `json:"transactions" doc:"Incoming transactions and how they were processed"`
+	}
+}
+
+// importTransaction matches an incoming transaction to a registration by its variable symbol and amount.
+// Only transactions paying the outstanding balance exactly are recorded, the others are left for orgs to review.
+func importTransaction(db *gorm.DB, n notifier.Notifier, roles notifier.RoleManager, org models.User, t bankimport.Transaction) (ImportedTransaction, error) {
+	result := ImportedTransaction{Transaction: t, Status: ImportStatusUnmatched}
+	bankTransactionID := t.Reference()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.Payment{}).Where("bank_transaction_id = ?", bankTransactionID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			result.Status = ImportStatusDuplicate
+			return nil
+		}
+
+		if t.VariableSymbol == "" {
+			result.Reason = "Missing variable symbol"
+			return nil
+		}
+		registrationID, err := strconv.ParseUint(t.VariableSymbol, 10, 0)
+		if err != nil {
+			result.Reason = "Invalid variable symbol"
+			return nil
+		}
+		registration, event, err := registrationWithEvent(tx, uint(registrationID))
+		if err != nil {
+			result.Reason = "No registration with variable symbol " + t.VariableSymbol
+			return nil
+		}
+		result.RegistrationID = registration.ID
+
+		currency := billing.Currency(event)
+		if t.Currency != "" && t.Currency != currency {
+			result.Reason = fmt.Sprintf("Event %s is priced in %s, got %s", event.Code, currency, t.Currency)
+			return nil
+		}
+
+		before, err := billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		// Payments made while waitlisted count once the spot is confirmed, they are matched against its price
+		due, outstanding := before.Due, before.Outstanding
+		if registration.Waitlisted && !registration.Cancelled {
+			due = billing.Price(event, registration).Total
+			outstanding = due - before.Paid
+		}
+		if due == 0 {
+			result.Reason = "Registration has nothing to pay"
+			return nil
+		}
+		result.Balance = &before
+
+		// A mistyped variable symbol points to somebody else, the amount has to match too
+		switch {
+		case t.Amount < outstanding:
+			result.Status = ImportStatusPartial
+			result.Reason = fmt.Sprintf("Amount %d is less than the outstanding balance %d", t.Amount, outstanding)
+			return nil
+		case t.Amount > outstanding:
+			result.Status = ImportStatusOverpaid
+			result.Reason = fmt.Sprintf("Amount %d is more than the outstanding balance %d", t.Amount, outstanding)
+			return nil
+		}
+
+		payment := models.Payment{
+			RegistrationID:    registration.ID,
+			Amount:            t.Amount,
+			Currency:          currency,
+			Method:            models.PaymentMethodBankTransfer,
+			Reference:         bankTransactionID,
+			BankTransactionID: bankTransactionID,
+			Note:              t.Counterparty,
+			ReceivedAt:        t.Date,
+			RecordedByID:      org.ID,
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		result.PaymentID = payment.ID
+
+		after, err := billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		result.Balance = &after
+		result.Status = ImportStatusMatched
+
+		// The role and the announcement are delivered by the outbox worker once the payment is committed
+		if err := queuePaidRole(tx, roles, event, registration, billing.PaidInFull(registration, before), billing.PaidInFull(registration, after)); err != nil {
+			return err
+		}
+		if n != nil {
+			return outbox.NotifyPayment(tx, payment.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		// The same statement imported concurrently
+		if database.IsUniqueViolation(err) {
+			return ImportedTransaction{Transaction: t, Status: ImportStatusDuplicate}, nil
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+func (h *PaymentHandler) HandleImportStatement(ctx context.Context, input *ImportStatementRequest) (*ImportStatementResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	data := input.RawBody.Data()
+	if !data.Statement.IsSet || data.Statement.File == nil {
+		return nil, huma.Error400BadRequest("Statement file is required")
+	}
+	content, err := io.ReadAll(data.Statement.File)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Failed to read statement: " + err.Error())
+	}
+
+	transactions, err := bankimport.Parse(content)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Failed to parse statement: " + err.Error())
+	}
+
+	res := &ImportStatementResponse{}
+	res.Body.Transactions = []ImportedTransaction{}
+	for _, t := range transactions {
+		if !t.Incoming() {
+			res.Body.Outgoing++
+			continue
+		}
+
+		imported, err := importTransaction(h.db, h.notifier, h.roles, org, t)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to import transaction " + t.Reference() + ": " + err.Error())
+		}
+		switch imported.Status {
+		case ImportStatusMatched:
+			res.Body.Matched++
+		case ImportStatusPartial:
+			res.Body.Partial++
+		case ImportStatusOverpaid:
+			res.Body.Overpaid++
+		case ImportStatusUnmatched:
+			res.Body.Unmatched++
+		case ImportStatusDuplicate:
+			res.Body.Duplicate++
+		}
+		res.Body.Transactions = append(res.Body.Transactions, imported)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/bankimport"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestImportTransaction(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	org := models.User{DiscordID: "import-org"}
+	payer := models.User{DiscordID: "import-payer"}
+	partial := models.User{DiscordID: "import-partial"}
+	db.Create(&org)
+	db.Create(&payer)
+	db.Create(&partial)
+	event := createTestEvent(t, db, "import-event")
+	db.Model(&event).Update("price_fixed_fee", 100000)
+
+	paid := models.Registration{UserID: payer.ID, Event: event.Code, EventID: event.ID}
+	unpaid := models.Registration{UserID: partial.ID, Event: event.Code, EventID: event.ID}
+	db.Create(&paid)
+	db.Create(&unpaid)
+
+	n := &fakeNotifier{}
+	date := time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	// Manual payments with the same reference do not count as imported
+	db.Create(&models.Payment{RegistrationID: unpaid.ID, Amount: 1, Currency: "CZK", Method: models.PaymentMethodBankTransfer, Reference: "1", RecordedByID: org.ID})
+
+	full := bankimport.Transaction{ID: "1", Date: date, Amount: 100000, Currency: "CZK", VariableSymbol: strconv.Itoa(int(paid.ID))}
+	result, err := importTransaction(db, n, n, org, full)
+	if err != nil {
+		t.Fatalf("importTransaction failed: %v", err)
+	}
+	if result.Status != ImportStatusMatched || result.RegistrationID != paid.ID || result.PaymentID == 0 {
+		t.Errorf("expected matched transaction, got %+v", result)
+	}
+	// The role and the announcement go out through the outbox once the payment is committed
+	n.deliver(t, db)
+	if len(n.grantedRoles) != 1 || n.grantedRoles[0] != event.PaidRole() {
+		t.Errorf("expected paid role to be granted, got %v", n.grantedRoles)
+	}
+	if len(n.payments) != 1 || n.payments[0].ID != result.PaymentID {
+		t.Errorf("expected matched payment to be announced, got %v", n.payments)
+	}
+
+	result, _ = importTransaction(db, n, n, org, full)
+	if result.Status != ImportStatusDuplicate {
+		t.Errorf("expected repeated import to be a duplicate, got %s", result.Status)
+	}
+
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "2", Date: date, Amount: 40000, VariableSymbol: strconv.Itoa(int(unpaid.ID))})
+	if result.Status != ImportStatusPartial || result.PaymentID != 0 || result.Reason == "" {
+		t.Errorf("expected partial payment to be reported and not recorded, got %+v", result)
+	}
+	if len(n.grantedRoles) != 1 {
+		t.Error("expected no role for partial payment")
+	}
+
+	// A variable symbol of a settled registration is most likely a typo
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "6", Date: date, Amount: 100000, VariableSymbol: strconv.Itoa(int(paid.ID))})
+	if result.Status != ImportStatusOverpaid || result.PaymentID != 0 {
+		t.Errorf("expected payment above the outstanding balance not to be recorded, got %+v", result)
+	}
+
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "3", Date: date, Amount: 100000, VariableSymbol: "9999"})
+	if result.Status != ImportStatusUnmatched {
+		t.Errorf("expected unknown variable symbol to be unmatched, got %s", result.Status)
+	}
+
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "4", Date: date, Amount: 100000})
+	if result.Status != ImportStatusUnmatched || result.Reason == "" {
+		t.Errorf("expected missing variable symbol to be unmatched with a reason, got %+v", result)
+	}
+
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "5", Date: date, Amount: 100000, Currency: "EUR", VariableSymbol: strconv.Itoa(int(unpaid.ID))})
+	if result.Status != ImportStatusUnmatched {
+		t.Errorf("expected currency mismatch to be unmatched, got %s", result.Status)
+	}
+
+	// A waitlisted attendee pays the price of the spot, the payment counts once the spot is confirmed
+	waiting := models.User{DiscordID: "import-waiting"}
+	db.Create(&waiting)
+	waitlisted := models.Registration{UserID: waiting.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{Waitlisted: true}}
+	db.Create(&waitlisted)
+	result, _ = importTransaction(db, n, n, org, bankimport.Transaction{ID: "7", Date: date, Amount: 100000, VariableSymbol: strconv.Itoa(int(waitlisted.ID))})
+	if result.Status != ImportStatusMatched || result.PaymentID == 0 {
+		t.Errorf("expected the payment of a waitlisted attendee to be recorded, got %+v", result)
+	}
+	n.deliver(t, db)
+	if len(n.grantedRoles) != 1 {
+		t.Error("expected no paid role before the spot is confirmed")
+	}
+
+	var count int64
+	db.Model(&models.Payment{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected the matched, the waitlisted and the manual payment, got %d", count)
+	}
+}
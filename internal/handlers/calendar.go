@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/ical"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// calendarDomain makes the UIDs of the feed events globally unique
+const calendarDomain = "garage-trip"
+
+type CalendarHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+	cfg         *config.Config
+}
+
+func NewCalendarHandler(db *gorm.DB, authHandler *auth.AuthHandler, cfg *config.Config) *CalendarHandler {
+	return &CalendarHandler{db: db, authHandler: authHandler, cfg: cfg}
+}
+
+type CalendarTokenRequest struct {
+	auth.AuthInput
+}
+
+type CalendarTokenResponse struct {
+	Body struct {
+		Token     string    `json:"token"`
+		URL       string    `json:"url" doc:"Feed with the caller's events, sessions and shifts"`
+		AgendaURL string    `json:"agenda_url" doc:"Feed with the whole agenda of an event, replace {code} with the event code. Restricted to orgs."`
+		CreatedAt time.Time `json:"created_at"`
+	}
+}
+
+type CalendarFeedRequest struct {
+	Token string `path:"token" doc:"Secret calendar token"`
+}
+
+type AgendaFeedRequest struct {
+	Token string `path:"token" doc:"Secret calendar token"`
+	Code  string `path:"code" doc:"Event code"`
+}
+
+type CalendarFeedResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+func calendarUID(kind string, id uint) string {
+	return fmt.Sprintf("%s-%d@%s", kind, id, calendarDomain)
+}
+
+func eventDatesEntry(uid string, title string, from time.Time, to time.Time) ical.Event {
+	return ical.Event{UID: uid, Summary: title, Start: from, End: to, AllDay: true}
+}
+
+func sessionEntry(session models.Session) ical.Event {
+	description := session.Description
+	if session.Speaker != nil {
+		description = strings.TrimSpace("Speaker: " + session.Speaker.Username + "\n\n" + description)
+	}
+	return ical.Event{
+		UID:         calendarUID("session", session.ID),
+		Summary:     session.Title,
+		Description: description,
+		Location:    session.Room,
+		Start:       session.StartsAt,
+		End:         session.EndsAt,
+	}
+}
+
+func shiftEntry(shift models.Shift) ical.Event {
+	var volunteers []string
+	for _, s := range shift.Signups {
+		volunteers = append(volunteers, s.User.Username)
+	}
+	description := shift.Note
+	if len(volunteers) > 0 {
+		description = strings.TrimSpace("Volunteers: " + strings.Join(volunteers, ", ") + "\n\n" + description)
+	}
+	return ical.Event{
+		UID:         calendarUID("shift", shift.ID),
+		Summary:     "Shift: " + shift.Task,
+		Description: description,
+		Start:       shift.StartsAt,
+		End:         shift.EndsAt,
+	}
+}
+
+// userCalendar lists the stays of the user's registrations, their sessions and their shifts
+func userCalendar(db *gorm.DB, userID uint) ([]ical.Event, error) {
+	var entries []ical.Event
+
+	var registrations []models.Registration
+	if err := db.Where("user_id = ? AND cancelled = ?", userID, false).Find(&registrations).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range registrations {
+		event, err := findEvent(db, r.Event)
+		if err != nil {
+			continue
+		}
+		title := event.Title
+		if r.Waitlisted {
+			title += " (waitlisted)"
+		}
+		entries = append(entries, eventDatesEntry(calendarUID("registration", r.ID), title, r.ArrivalDate, r.DepartureDate))
+	}
+
+	var sessions []models.Session
+	err := db.Preload("Speaker").
+		Where("status = ?", models.SessionAccepted).
+		Where("speaker_id = ? OR id IN (?)", userID, db.Model(&models.SessionSignup{}).Select("session_id").Where("user_id = ?", userID)).
+		Order("starts_at ASC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		entries = append(entries, sessionEntry(s))
+	}
+
+	var shifts []models.Shift
+	err = db.Preload("Signups.User").
+		Where("id IN (?)", db.Model(&models.ShiftSignup{}).Select("shift_id").Where("user_id = ?", userID)).
+		Order("starts_at ASC").
+		Find(&shifts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shifts {
+		entries = append(entries, shiftEntry(s))
+	}
+	return entries, nil
+}
+
+// agendaCalendar lists the event dates, its accepted sessions and all shifts
+func agendaCalendar(db *gorm.DB, event models.Event) ([]ical.Event, error) {
+	entries := []ical.Event{eventDatesEntry(calendarUID("event", event.ID), event.Title, event.StartDate, event.EndDate)}
+
+	var sessions []models.Session
+	if err := db.Preload("Speaker").Where("event_id = ? AND status = ?", event.ID, models.SessionAccepted).Order("starts_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		entries = append(entries, sessionEntry(s))
+	}
+
+	var shifts []models.Shift
+	if err := db.Preload("Signups.User").Where("event_id = ?", event.ID).Order("starts_at ASC").Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range shifts {
+		entries = append(entries, shiftEntry(s))
+	}
+	return entries, nil
+}
+
+func calendarFeed(name string, entries []ical.Event) (*CalendarFeedResponse, error) {
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, name, entries, time.Now()); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to write calendar: " + err.Error())
+	}
+	return &CalendarFeedResponse{ContentType: "text/calendar; charset=utf-8", Body: buf.Bytes()}, nil
+}
+
+// calendarUser authenticates a feed request by its token
+func (h *CalendarHandler) calendarUser(token string) (models.User, error) {
+	var calendarToken models.CalendarToken
+	if token == "" || h.db.Where("token = ?", token).First(&calendarToken).Error != nil {
+		return models.User{}, huma.Error404NotFound("Calendar not found")
+	}
+	var user models.User
+	if err := h.db.First(&user, calendarToken.UserID).Error; err != nil {
+		return user, huma.Error404NotFound("Calendar not found")
+	}
+	return user, nil
+}
+
+func (h *CalendarHandler) newTokenResponse(token models.CalendarToken) *CalendarTokenResponse {
+	base := strings.TrimRight(h.cfg.PublicURL, "/") + "/calendar/" + token.Token
+	res := &CalendarTokenResponse{}
+	res.Body.Token = token.Token
+	res.Body.URL = base
+	res.Body.AgendaURL = base + "/events/{code}"
+	res.Body.CreatedAt = token.CreatedAt
+	return res
+}
+
+func (h *CalendarHandler) HandleGetCalendarToken(ctx context.Context, input *CalendarTokenRequest) (*CalendarTokenResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.CalendarToken
+	if err := h.db.Where("user_id = ?", userID).First(&token).Error; err != nil {
+		return nil, huma.Error404NotFound("No calendar token yet, create one first")
+	}
+	return h.newTokenResponse(token), nil
+}
+
+func (h *CalendarHandler) HandleRotateCalendarToken(ctx context.Context, input *CalendarTokenRequest) (*CalendarTokenResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate token")
+	}
+	token := models.CalendarToken{UserID: userID, Token: hex.EncodeToString(tokenBytes)}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Hard delete so the old URL stops working and the user can get a new token
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.CalendarToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create calendar token: " + err.Error())
+	}
+	return h.newTokenResponse(token), nil
+}
+
+func (h *CalendarHandler) HandleRevokeCalendarToken(ctx context.Context, input *CalendarTokenRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.db.Unscoped().Where("user_id = ?", userID).Delete(&models.CalendarToken{}).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to revoke calendar token: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *CalendarHandler) HandleUserFeed(ctx context.Context, input *CalendarFeedRequest) (*CalendarFeedResponse, error) {
+	user, err := h.calendarUser(input.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := userCalendar(h.db, user.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch calendar: " + err.Error())
+	}
+	return calendarFeed("Garage Trip", entries)
+}
+
+func (h *CalendarHandler) HandleAgendaFeed(ctx context.Context, input *AgendaFeedRequest) (*CalendarFeedResponse, error) {
+	user, err := h.calendarUser(input.Token)
+	if err != nil {
+		return nil, err
+	}
+	isOrg, err := h.authHandler.IsOrg(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrg {
+		return nil, huma.Error403Forbidden("Access denied: missing " + h.cfg.OrgRole + " role")
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := agendaCalendar(h.db, event)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch calendar: " + err.Error())
+	}
+	return calendarFeed(event.Title, entries)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "calendar-event")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	user := models.User{DiscordID: "calendar-user", Username: "alice"}
+	db.Create(&user)
+	registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+		ArrivalDate:   today.AddDate(0, 0, 1),
+		DepartureDate: today.AddDate(0, 0, 3),
+	}}
+	db.Create(&registration)
+
+	session := models.Session{EventID: event.ID, Event: event.Code, Title: "Soldering 101", Room: "Workshop", Status: models.SessionAccepted,
+		StartsAt: today.AddDate(0, 0, 1).Add(14 * time.Hour), EndsAt: today.AddDate(0, 0, 1).Add(16 * time.Hour)}
+	db.Create(&session)
+	db.Create(&models.SessionSignup{SessionID: session.ID, RegistrationID: registration.ID, UserID: user.ID})
+	db.Create(&models.Session{EventID: event.ID, Event: event.Code, Title: "Rejected talk", Status: models.SessionRejected,
+		StartsAt: today.AddDate(0, 0, 2), EndsAt: today.AddDate(0, 0, 2).Add(time.Hour)})
+
+	shift := models.Shift{EventID: event.ID, Event: event.Code, Task: "Dishes", Required: 1,
+		StartsAt: today.AddDate(0, 0, 2).Add(18 * time.Hour), EndsAt: today.AddDate(0, 0, 2).Add(19 * time.Hour)}
+	db.Create(&shift)
+	db.Create(&models.ShiftSignup{ShiftID: shift.ID, RegistrationID: registration.ID, UserID: user.ID})
+	db.Create(&models.Shift{EventID: event.ID, Event: event.Code, Task: "Firewood", Required: 1,
+		StartsAt: today.AddDate(0, 0, 2), EndsAt: today.AddDate(0, 0, 2).Add(time.Hour)})
+
+	cfg := &config.Config{JWTSecret: "test-secret", PublicURL: "https://trip.example.com/", OrgRole: "org"}
+	authHandler := auth.NewAuthHandler(cfg, db, nil)
+	handler := NewCalendarHandler(db, authHandler, cfg)
+	token, _ := authHandler.GenerateToken(user.ID)
+	req := &CalendarTokenRequest{}
+	req.Cookie = "auth_token=" + token
+
+	if _, err := handler.HandleGetCalendarToken(context.Background(), req); err == nil {
+		t.Error("expected no calendar token before creating one")
+	}
+	created, err := handler.HandleRotateCalendarToken(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleRotateCalendarToken failed: %v", err)
+	}
+	if created.Body.URL != "https://trip.example.com/calendar/"+created.Body.Token {
+		t.Errorf("unexpected feed URL %s", created.Body.URL)
+	}
+	if got, err := handler.HandleGetCalendarToken(context.Background(), req); err != nil || got.Body.Token != created.Body.Token {
+		t.Errorf("expected the created token, got %v %v", got, err)
+	}
+
+	feed, err := handler.HandleUserFeed(context.Background(), &CalendarFeedRequest{Token: created.Body.Token})
+	if err != nil {
+		t.Fatalf("HandleUserFeed failed: %v", err)
+	}
+	body := string(feed.Body)
+	if !strings.HasPrefix(feed.ContentType, "text/calendar") {
+		t.Errorf("unexpected content type %s", feed.ContentType)
+	}
+	for _, want := range []string{
+		"UID:registration-1@garage-trip",
+		"DTSTART;VALUE=DATE:" + today.AddDate(0, 0, 1).Format("20060102"),
+		"SUMMARY:Soldering 101",
+		"LOCATION:Workshop",
+		"SUMMARY:Shift: Dishes",
+		"DESCRIPTION:Volunteers: alice",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected feed to contain %q, got:\n%s", want, body)
+		}
+	}
+	for _, unwanted := range []string{"Rejected talk", "Firewood"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("expected feed not to contain %q", unwanted)
+		}
+	}
+
+	// Only orgs get the whole agenda, CheckRole is false without Discord
+	if _, err := handler.HandleAgendaFeed(context.Background(), &AgendaFeedRequest{Token: created.Body.Token, Code: event.Code}); err == nil {
+		t.Error("expected agenda feed to be restricted to orgs")
+	}
+	entries, err := agendaCalendar(db, event)
+	if err != nil {
+		t.Fatalf("agendaCalendar failed: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected event dates, one session and two shifts, got %d entries", len(entries))
+	}
+
+	rotated, err := handler.HandleRotateCalendarToken(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleRotateCalendarToken failed: %v", err)
+	}
+	if rotated.Body.Token == created.Body.Token {
+		t.Error("expected a new token")
+	}
+	if _, err := handler.HandleUserFeed(context.Background(), &CalendarFeedRequest{Token: created.Body.Token}); err == nil {
+		t.Error("expected the old token to stop working")
+	}
+
+	if _, err := handler.HandleRevokeCalendarToken(context.Background(), req); err != nil {
+		t.Fatalf("HandleRevokeCalendarToken failed: %v", err)
+	}
+	if _, err := handler.HandleUserFeed(context.Background(), &CalendarFeedRequest{Token: rotated.Body.Token}); err == nil {
+		t.Error("expected the revoked token to stop working")
+	}
+}
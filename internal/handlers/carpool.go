@@ -0,0 +1,515 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+type CarpoolHandler struct {
+	db          *gorm.DB
+	notifier    notifier.Notifier
+	authHandler *auth.AuthHandler
+}
+
+func NewCarpoolHandler(db *gorm.DB, notifier notifier.Notifier, authHandler *auth.AuthHandler) *CarpoolHandler {
+	return &CarpoolHandler{db: db, notifier: notifier, authHandler: authHandler}
+}
+
+type OfferRideRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+	Body struct {
+		Origin    string    `json:"origin" doc:"Where the ride starts"`
+		DepartsAt time.Time `json:"departs_at"`
+		Seats     int       `json:"seats" minimum:"1" doc:"Seats offered to passengers"`
+		Note      string    `json:"note,omitempty"`
+	}
+}
+
+type ListRidesRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type RideIDRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Ride ID"`
+}
+
+type RequestSeatRequest struct {
+	auth.AuthInput
+	ID   uint `path:"id" doc:"Ride ID"`
+	Body struct {
+		Seats int    `json:"seats,omitempty" minimum:"0" doc:"Seats needed, defaults to the attendee and their companions"`
+		Note  string `json:"note,omitempty"`
+	}
+}
+
+type RideRequestIDRequest struct {
+	auth.AuthInput
+	ID        uint `path:"id" doc:"Ride ID"`
+	RequestID uint `path:"request_id" doc:"Ride request ID"`
+}
+
+type RidePassenger struct {
+	RequestID      uint   `json:"request_id"`
+	RegistrationID uint   `json:"registration_id"`
+	Username       string `json:"username"`
+	Seats          int    `json:"seats"`
+	Status         string `json:"status" enum:"pending,accepted"`
+	Note           string `json:"note"`
+}
+
+type RideItem struct {
+	models.Ride
+	DriverUsername string          `json:"driver_username"`
+	FreeSeats      int             `json:"free_seats"`
+	Passengers     []RidePassenger `json:"passengers" doc:"Pending and accepted requests"`
+}
+
+type RideResponse struct {
+	Body RideItem
+}
+
+type ListRidesResponse struct {
+	Body struct {
+		Rides []RideItem `json:"rides"`
+	}
+}
+
+type RideRequestResponse struct {
+	Body models.RideRequest
+}
+
+func newRideItem(ride models.Ride) RideItem {
+	item := RideItem{Ride: ride, DriverUsername: ride.Driver.Username, FreeSeats: ride.FreeSeats(), Passengers: []RidePassenger{}}
+	for _, req := range ride.Requests {
+		if req.Status != models.RideRequestPending && req.Status != models.RideRequestAccepted {
+			continue
+		}
+		item.Passengers = append(item.Passengers, RidePassenger{
+			RequestID:      req.ID,
+			RegistrationID: req.RegistrationID,
+			Username:       req.Passenger.Username,
+			Seats:          req.Seats,
+			Status:         req.Status,
+			Note:           req.Note,
+		})
+	}
+	return item
+}
+
+func findRide(db *gorm.DB, id uint) (models.Ride, error) {
+	var ride models.Ride
+	if err := db.Preload("Driver").Preload("Requests.Passenger").First(&ride, id).Error; err != nil {
+		return ride, huma.Error404NotFound("Ride not found")
+	}
+	return ride, nil
+}
+
+// confirmedRegistration loads the user's registration for the event, only confirmed attendees take part in carpools
+func confirmedRegistration(db *gorm.DB, userID uint, eventID uint) (models.Registration, error) {
+	var registration models.Registration
+	if err := db.Where("user_id = ? AND event_id = ?", userID, eventID).First(&registration).Error; err != nil {
+		return registration, huma.Error403Forbidden("You are not registered for this event")
+	}
+	if !registration.Confirmed() {
+		return registration, huma.Error409Conflict("Only confirmed registrations can join carpools")
+	}
+	return registration, nil
+}
+
+// carpoolRelease collects who to notify about carpools changed by a cancellation
+type carpoolRelease struct {
+	left      []models.RideRequest // Accepted requests of passengers who left
+	cancelled []models.RideRequest // Accepted requests of rides which were cancelled
+}
+
+// enqueue writes the notifications to the outbox in the transaction of the cancellation
+func (c carpoolRelease) enqueue(tx *gorm.DB) error {
+	for _, req := range c.left {
+		if err := outbox.NotifyRideLeft(tx, req.RideID, req.PassengerID); err != nil {
+			return err
+		}
+	}
+	for _, req := range c.cancelled {
+		if err := outbox.NotifyRideCancelled(tx, req.RideID, req.PassengerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelRide withdraws all requests of the ride and deletes it
+func cancelRide(tx *gorm.DB, ride models.Ride, release *carpoolRelease) error {
+	for _, req := range ride.Requests {
+		if req.Status == models.RideRequestAccepted {
+			release.cancelled = append(release.cancelled, req)
+		}
+	}
+	err := tx.Model(&models.RideRequest{}).
+		Where("ride_id = ? AND status IN ?", ride.ID, []string{models.RideRequestPending, models.RideRequestAccepted}).
+		Update("status", models.RideRequestWithdrawn).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(&ride).Error
+}
+
+// releaseCarpool takes a cancelled registration out of all carpools, as a passenger and as a driver
+func releaseCarpool(tx *gorm.DB, registration models.Registration) (carpoolRelease, error) {
+	var release carpoolRelease
+
+	var requests []models.RideRequest
+	if err := tx.Where("registration_id = ? AND status IN ?", registration.ID, []string{models.RideRequestPending, models.RideRequestAccepted}).Find(&requests).Error; err != nil {
+		return release, err
+	}
+	for _, req := range requests {
+		if req.Status == models.RideRequestAccepted {
+			release.left = append(release.left, req)
+		}
+	}
+	if len(requests) > 0 {
+		if err := tx.Model(&models.RideRequest{}).Where("registration_id = ? AND status IN ?", registration.ID, []string{models.RideRequestPending, models.RideRequestAccepted}).Update("status", models.RideRequestWithdrawn).Error; err != nil {
+			return release, err
+		}
+	}
+
+	var rides []models.Ride
+	if err := tx.Preload("Requests").Where("driver_registration_id = ?", registration.ID).Find(&rides).Error; err != nil {
+		return release, err
+	}
+	for _, ride := range rides {
+		if err := cancelRide(tx, ride, &release); err != nil {
+			return release, err
+		}
+	}
+	return release, nil
+}
+
+// canManageRide allows the driver and orgs to manage a ride
+func (h *CarpoolHandler) canManageRide(userID uint, ride models.Ride) error {
+	if ride.DriverID == userID {
+		return nil
+	}
+	isOrg, err := h.authHandler.IsOrg(userID)
+	if err != nil {
+		return err
+	}
+	if !isOrg {
+		return huma.Error403Forbidden("Only the driver can manage the ride")
+	}
+	return nil
+}
+
+func (h *CarpoolHandler) HandleOfferRide(ctx context.Context, input *OfferRideRequest) (*RideResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	registration, err := confirmedRegistration(h.db, userID, event.ID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Body.Origin == "" || input.Body.Seats < 1 {
+		return nil, huma.Error400BadRequest("Origin and at least one free seat are required")
+	}
+
+	ride := models.Ride{
+		EventID:              event.ID,
+		Event:                event.Code,
+		DriverRegistrationID: registration.ID,
+		DriverID:             userID,
+		Origin:               input.Body.Origin,
+		DepartsAt:            input.Body.DepartsAt,
+		Seats:                input.Body.Seats,
+		Note:                 input.Body.Note,
+	}
+	if err := h.db.Create(&ride).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create ride: " + err.Error())
+	}
+
+	ride, err = findRide(h.db, ride.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &RideResponse{Body: newRideItem(ride)}, nil
+}
+
+func (h *CarpoolHandler) HandleListRides(ctx context.Context, input *ListRidesRequest) (*ListRidesResponse, error) {
+	if _, err := h.authHandler.Authorize(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var rides []models.Ride
+	if err := h.db.Preload("Driver").Preload("Requests.Passenger").Where("event_id = ?", event.ID).Order("departs_at ASC").Find(&rides).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch rides: " + err.Error())
+	}
+
+	res := &ListRidesResponse{}
+	res.Body.Rides = make([]RideItem, len(rides))
+	for i, ride := range rides {
+		res.Body.Rides[i] = newRideItem(ride)
+	}
+	return res, nil
+}
+
+func (h *CarpoolHandler) HandleCancelRide(ctx context.Context, input *RideIDRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := findRide(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.canManageRide(userID, ride); err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var release carpoolRelease
+		if err := cancelRide(tx, ride, &release); err != nil {
+			return err
+		}
+		if h.notifier == nil {
+			return nil
+		}
+		return release.enqueue(tx)
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to cancel ride: " + err.Error())
+	}
+	return nil, nil
+}
+
+// requestSeat adds a pending request of the registration to the ride
+func requestSeat(db *gorm.DB, ride models.Ride, registration models.Registration, seats int, note string) (models.RideRequest, error) {
+	var req models.RideRequest
+	if ride.DriverRegistrationID == registration.ID {
+		return req, huma.Error400BadRequest("You are driving this ride")
+	}
+	if seats == 0 {
+		seats = registration.Adults() + registration.Children()
+	}
+	if seats > ride.FreeSeats() {
+		return req, huma.Error409Conflict("Not enough free seats in the ride")
+	}
+
+	var active int64
+	err := db.Model(&models.RideRequest{}).
+		Joins("JOIN rides ON rides.id = ride_requests.ride_id AND rides.deleted_at IS NULL").
+		Where("ride_requests.registration_id = ? AND rides.event_id = ?", registration.ID, ride.EventID).
+		Where("ride_requests.status = ? OR (ride_requests.status = ? AND ride_requests.ride_id = ?)", models.RideRequestAccepted, models.RideRequestPending, ride.ID).
+		Count(&active).Error
+	if err != nil {
+		return req, huma.Error500InternalServerError("Failed to fetch ride requests: " + err.Error())
+	}
+	if active > 0 {
+		return req, huma.Error409Conflict("You already have a ride or a pending request for this one")
+	}
+
+	req = models.RideRequest{
+		RideID:         ride.ID,
+		RegistrationID: registration.ID,
+		PassengerID:    registration.UserID,
+		Seats:          seats,
+		Status:         models.RideRequestPending,
+		Note:           note,
+	}
+	if err := db.Create(&req).Error; err != nil {
+		return req, huma.Error500InternalServerError("Failed to request a seat: " + err.Error())
+	}
+	return req, nil
+}
+
+func (h *CarpoolHandler) HandleRequestSeat(ctx context.Context, input *RequestSeatRequest) (*RideRequestResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := findRide(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	registration, err := confirmedRegistration(h.db, userID, ride.EventID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := requestSeat(h.db, ride, registration, input.Body.Seats, input.Body.Note)
+	if err != nil {
+		return nil, err
+	}
+	return &RideRequestResponse{Body: req}, nil
+}
+
+// acceptRequest takes the passenger into the ride, their other pending requests for the event are withdrawn.
+// It reports whether the ride became full.
+func acceptRequest(tx *gorm.DB, rideID uint, requestID uint) (models.Ride, models.RideRequest, bool, error) {
+	ride, err := findRide(tx, rideID)
+	if err != nil {
+		return ride, models.RideRequest{}, false, err
+	}
+
+	var req models.RideRequest
+	for _, r := range ride.Requests {
+		if r.ID == requestID {
+			req = r
+		}
+	}
+	if req.ID == 0 {
+		return ride, req, false, huma.Error404NotFound("Ride request not found")
+	}
+	if req.Status != models.RideRequestPending {
+		return ride, req, false, huma.Error409Conflict("Ride request is " + req.Status)
+	}
+	if req.Seats > ride.FreeSeats() {
+		return ride, req, false, huma.Error409Conflict("Not enough free seats in the ride")
+	}
+
+	if err := tx.Model(&req).Update("status", models.RideRequestAccepted).Error; err != nil {
+		return ride, req, false, huma.Error500InternalServerError("Failed to accept ride request: " + err.Error())
+	}
+	err = tx.Model(&models.RideRequest{}).
+		Where("registration_id = ? AND status = ? AND id <> ?", req.RegistrationID, models.RideRequestPending, req.ID).
+		Where("ride_id IN (?)", tx.Model(&models.Ride{}).Select("id").Where("event_id = ?", ride.EventID)).
+		Update("status", models.RideRequestWithdrawn).Error
+	if err != nil {
+		return ride, req, false, huma.Error500InternalServerError("Failed to withdraw other ride requests: " + err.Error())
+	}
+
+	ride, err = findRide(tx, rideID)
+	if err != nil {
+		return ride, req, false, err
+	}
+	return ride, req, ride.FreeSeats() == 0, nil
+}
+
+func (h *CarpoolHandler) HandleAcceptRequest(ctx context.Context, input *RideRequestIDRequest) (*RideResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := findRide(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.canManageRide(userID, ride); err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var full bool
+		ride, _, full, err = acceptRequest(tx, input.ID, input.RequestID)
+		if err != nil || !full || h.notifier == nil {
+			return err
+		}
+		if err := outbox.NotifyRideFull(tx, ride.ID); err != nil {
+			return huma.Error500InternalServerError("Failed to accept ride request: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RideResponse{Body: newRideItem(ride)}, nil
+}
+
+func (h *CarpoolHandler) HandleRejectRequest(ctx context.Context, input *RideRequestIDRequest) (*RideResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := findRide(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.canManageRide(userID, ride); err != nil {
+		return nil, err
+	}
+
+	result := h.db.Model(&models.RideRequest{}).
+		Where("id = ? AND ride_id = ? AND status = ?", input.RequestID, ride.ID, models.RideRequestPending).
+		Update("status", models.RideRequestRejected)
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to reject ride request: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, huma.Error404NotFound("Pending ride request not found")
+	}
+
+	ride, err = findRide(h.db, ride.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &RideResponse{Body: newRideItem(ride)}, nil
+}
+
+func (h *CarpoolHandler) HandleWithdrawRequest(ctx context.Context, input *RideRequestIDRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	ride, err := findRide(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var req models.RideRequest
+	for _, r := range ride.Requests {
+		if r.ID == input.RequestID {
+			req = r
+		}
+	}
+	if req.ID == 0 || (req.Status != models.RideRequestPending && req.Status != models.RideRequestAccepted) {
+		return nil, huma.Error404NotFound("Ride request not found")
+	}
+	if req.PassengerID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error403Forbidden("Only the passenger can withdraw the request")
+		}
+	}
+
+	wasAccepted := req.Status == models.RideRequestAccepted
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&req).Update("status", models.RideRequestWithdrawn).Error; err != nil {
+			return err
+		}
+		if !wasAccepted || h.notifier == nil {
+			return nil
+		}
+		return carpoolRelease{left: []models.RideRequest{req}}.enqueue(tx)
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to withdraw ride request: " + err.Error())
+	}
+	return nil, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCarpool(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "carpool-event")
+	register := func(username string, children int) (models.User, models.Registration) {
+		user := models.User{DiscordID: "carpool-" + username, Username: username}
+		db.Create(&user)
+		registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+			ArrivalDate:   time.Now().AddDate(0, 0, 1),
+			DepartureDate: time.Now().AddDate(0, 0, 3),
+			ChildrenCount: children,
+		}}
+		db.Create(&registration)
+		return user, registration
+	}
+	driver, _ := register("driver", 0)
+	parent, _ := register("parent", 1)
+	single, singleReg := register("single", 0)
+	late, _ := register("late", 0)
+
+	n := &fakeNotifier{}
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	handler := NewCarpoolHandler(db, n, authHandler)
+	cookie := func(user models.User) string {
+		token, _ := authHandler.GenerateToken(user.ID)
+		return "auth_token=" + token
+	}
+
+	offer := &OfferRideRequest{Code: event.Code}
+	offer.Cookie = cookie(driver)
+	offer.Body.Origin = "Brno"
+	offer.Body.DepartsAt = time.Now().AddDate(0, 0, 1)
+	offer.Body.Seats = 3
+	offered, err := handler.HandleOfferRide(context.Background(), offer)
+	if err != nil {
+		t.Fatalf("HandleOfferRide failed: %v", err)
+	}
+	ride := offered.Body.Ride
+
+	request := func(user models.User) (*RideRequestResponse, error) {
+		req := &RequestSeatRequest{ID: ride.ID}
+		req.Cookie = cookie(user)
+		return handler.HandleRequestSeat(context.Background(), req)
+	}
+	accept := func(requestID uint) (*RideResponse, error) {
+		req := &RideRequestIDRequest{ID: ride.ID, RequestID: requestID}
+		req.Cookie = cookie(driver)
+		return handler.HandleAcceptRequest(context.Background(), req)
+	}
+
+	parentReq, err := request(parent)
+	if err != nil {
+		t.Fatalf("HandleRequestSeat failed: %v", err)
+	}
+	if parentReq.Body.Seats != 2 {
+		t.Errorf("expected seats for the parent and the child, got %d", parentReq.Body.Seats)
+	}
+	if _, err := request(parent); err == nil {
+		t.Error("expected repeated request to be rejected")
+	}
+	singleReq, _ := request(single)
+	lateReq, _ := request(late)
+
+	// Only the driver accepts
+	notDriver := &RideRequestIDRequest{ID: ride.ID, RequestID: parentReq.Body.ID}
+	notDriver.Cookie = cookie(single)
+	if _, err := handler.HandleAcceptRequest(context.Background(), notDriver); err == nil {
+		t.Error("expected passengers not to accept requests")
+	}
+
+	if _, err := accept(parentReq.Body.ID); err != nil {
+		t.Fatalf("HandleAcceptRequest failed: %v", err)
+	}
+	n.deliver(t, db)
+	if len(n.fullRides) != 0 {
+		t.Error("expected no announcement before the ride is full")
+	}
+	resp, err := accept(singleReq.Body.ID)
+	if err != nil {
+		t.Fatalf("HandleAcceptRequest failed: %v", err)
+	}
+	n.deliver(t, db)
+	if resp.Body.FreeSeats != 0 || len(n.fullRides) != 1 {
+		t.Errorf("expected the full ride to be announced, free seats %d, announcements %d", resp.Body.FreeSeats, len(n.fullRides))
+	}
+	if _, err := accept(lateReq.Body.ID); err == nil {
+		t.Error("expected accepting into a full ride to be rejected")
+	}
+
+	// Cancelling the registration releases the seats and notifies the driver
+	registrationHandler := NewRegistrationHandler(db, n, n, authHandler, &config.Config{})
+	cancel := &RegistrationRequest{}
+	cancel.Cookie = cookie(single)
+	cancel.Body.Event = event.Code
+	cancel.Body.ArrivalDate = singleReg.ArrivalDate
+	cancel.Body.DepartureDate = singleReg.DepartureDate
+	cancel.Body.Cancelled = true
+	if _, err := registrationHandler.HandleRegister(context.Background(), cancel); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+	n.deliver(t, db)
+	if len(n.leftRides) != 1 {
+		t.Errorf("expected the driver to be notified, got %d notifications", len(n.leftRides))
+	}
+	released, _ := findRide(db, ride.ID)
+	if released.FreeSeats() != 1 {
+		t.Errorf("expected one free seat after the cancellation, got %d", released.FreeSeats())
+	}
+	if _, err := accept(lateReq.Body.ID); err != nil {
+		t.Errorf("expected the freed seat to be available: %v", err)
+	}
+
+	// The driver cancelling drops the ride and notifies the passengers
+	var driverReg models.Registration
+	db.Where("user_id = ?", driver.ID).First(&driverReg)
+	if _, err := releaseCarpoolAndNotify(db, driverReg); err != nil {
+		t.Fatalf("releaseCarpool failed: %v", err)
+	}
+	n.deliver(t, db)
+	if len(n.cancelledFor) != 2 {
+		t.Errorf("expected both passengers to be notified, got %d", len(n.cancelledFor))
+	}
+	if _, err := findRide(db, ride.ID); err == nil {
+		t.Error("expected the ride to be cancelled")
+	}
+}
+
+func releaseCarpoolAndNotify(db *gorm.DB, registration models.Registration) (carpoolRelease, error) {
+	var release carpoolRelease
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		release, err = releaseCarpool(tx, registration)
+		if err != nil {
+			return err
+		}
+		return release.enqueue(tx)
+	})
+	return release, err
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+type CheckInHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewCheckInHandler(db *gorm.DB, authHandler *auth.AuthHandler) *CheckInHandler {
+	return &CheckInHandler{db: db, authHandler: authHandler}
+}
+
+type TicketRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Registration ID"`
+}
+
+type TicketQRResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+type CheckInRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Body           struct {
+		Ticket string `json:"ticket" doc:"Scanned ticket"`
+		Event  string `json:"event,omitempty" doc:"Event being checked in, tickets of other events are rejected"`
+	}
+}
+
+type CheckInResponse struct {
+	Body struct {
+		Registration     models.Registration `json:"registration"`
+		AlreadyCheckedIn bool                `json:"already_checked_in" doc:"The ticket was scanned before, the original check-in is kept"`
+	}
+}
+
+// checkIn marks a confirmed registration as checked in, repeated scans keep the first check-in
+func checkIn(db *gorm.DB, registrationID uint, event string, org models.User) (models.Registration, bool, error) {
+	var registration models.Registration
+	if err := db.Preload("User").Preload("CheckedInBy").First(&registration, registrationID).Error; err != nil {
+		return registration, false, huma.Error404NotFound("Registration not found")
+	}
+	if event != "" && registration.Event != event {
+		return registration, false, huma.Error409Conflict("Ticket is for event " + registration.Event)
+	}
+	if registration.CheckedInAt != nil {
+		return registration, true, nil
+	}
+	if registration.Cancelled {
+		return registration, false, huma.Error409Conflict("Registration is cancelled")
+	}
+	if registration.Waitlisted {
+		return registration, false, huma.Error409Conflict("Registration is on the waitlist")
+	}
+
+	now := time.Now()
+	registration.CheckedInAt = &now
+	registration.CheckedInByID = &org.ID
+	err := db.Model(&registration).Updates(map[string]any{"checked_in_at": now, "checked_in_by_id": org.ID}).Error
+	if err != nil {
+		return registration, false, huma.Error500InternalServerError("Failed to check in: " + err.Error())
+	}
+	registration.CheckedInBy = &org
+	return registration, false, nil
+}
+
+func (h *CheckInHandler) HandleCheckIn(ctx context.Context, input *CheckInRequest) (*CheckInResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	registrationID, err := h.authHandler.VerifyTicket(input.Body.Ticket)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid ticket")
+	}
+
+	registration, already, err := checkIn(h.db, registrationID, input.Body.Event, org)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CheckInResponse{}
+	res.Body.Registration = registration
+	res.Body.AlreadyCheckedIn = already
+	return res, nil
+}
+
+func (h *CheckInHandler) HandleTicketQR(ctx context.Context, input *TicketRequest) (*TicketQRResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var registration models.Registration
+	if err := h.db.First(&registration, input.ID).Error; err != nil {
+		return nil, huma.Error404NotFound("Registration not found")
+	}
+	if registration.UserID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error404NotFound("Registration not found")
+		}
+	}
+	if !registration.Confirmed() {
+		return nil, huma.Error409Conflict("Only confirmed registrations have a ticket")
+	}
+
+	png, err := qrcode.Encode(h.authHandler.Ticket(registration.ID), qrcode.Medium, 512)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate QR code: " + err.Error())
+	}
+
+	return &TicketQRResponse{ContentType: "image/png", Body: png}, nil
+}
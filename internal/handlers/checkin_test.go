@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCheckIn(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	org := models.User{DiscordID: "checkin-org", Username: "org"}
+	attendee := models.User{DiscordID: "checkin-attendee"}
+	db.Create(&org)
+	db.Create(&attendee)
+	event := createTestEvent(t, db, "checkin-event")
+
+	registration := models.Registration{UserID: attendee.ID, Event: event.Code, EventID: event.ID}
+	cancelled := models.Registration{UserID: org.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{Cancelled: true}}
+	db.Create(&registration)
+	db.Create(&cancelled)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+
+	// The ticket is available in /me and resolves to the registration
+	token, _ := authHandler.GenerateToken(attendee.ID)
+	me := &auth.MeRequest{}
+	me.Cookie = "auth_token=" + token
+	resp, err := authHandler.HandleMe(context.Background(), me)
+	if err != nil {
+		t.Fatalf("HandleMe failed: %v", err)
+	}
+	if len(resp.Body.Registrations) != 1 || resp.Body.Registrations[0].Ticket == "" {
+		t.Fatalf("expected a ticket in /me, got %+v", resp.Body.Registrations)
+	}
+	id, err := authHandler.VerifyTicket(resp.Body.Registrations[0].Ticket)
+	if err != nil || id != registration.ID {
+		t.Fatalf("expected ticket of registration %d, got %d (%v)", registration.ID, id, err)
+	}
+
+	if _, _, err := checkIn(db, id, "other-event", org); err == nil {
+		t.Error("expected ticket of another event to be rejected")
+	}
+
+	checked, already, err := checkIn(db, id, event.Code, org)
+	if err != nil {
+		t.Fatalf("checkIn failed: %v", err)
+	}
+	if already || checked.CheckedInAt == nil || checked.CheckedInByID == nil || *checked.CheckedInByID != org.ID {
+		t.Errorf("expected registration checked in by org, got %+v", checked)
+	}
+
+	again, already, err := checkIn(db, id, event.Code, org)
+	if err != nil || !already || !again.CheckedInAt.Equal(*checked.CheckedInAt) {
+		t.Errorf("expected repeated scan to keep the first check-in, got %+v (%v)", again, err)
+	}
+	if again.CheckedInBy == nil || again.CheckedInBy.Username != "org" {
+		t.Error("expected the org who checked in to be loaded")
+	}
+
+	if _, _, err := checkIn(db, cancelled.ID, event.Code, org); err == nil {
+		t.Error("expected cancelled registration to be rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandleRegister_Companions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "companion-user", Username: "alice"}
+	db.Create(&user)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "companion-event")
+	db.Model(&event).Updates(map[string]interface{}{
+		"capacity_adults":       2,
+		"price_adult_per_night": 10000,
+		"price_child_per_night": 4000,
+	})
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+	token, _ := authHandler.GenerateToken(user.ID)
+	authCookie := "auth_token=" + token
+
+	age := 7
+	arrival := time.Date(2026, 7, 10, 0, 0, 0, 0, time.UTC)
+	req := RegistrationRequest{}
+	req.Cookie = authCookie
+	req.Body.Event = event.Code
+	req.Body.ArrivalDate = arrival
+	req.Body.DepartureDate = arrival.AddDate(0, 0, 2)
+
+	req.Body.Companions = []models.Companion{{Name: "Bob", Type: models.CompanionChild}}
+	if _, err := handler.HandleRegister(context.Background(), &req); err == nil {
+		t.Error("expected child companion without age to be rejected")
+	}
+	req.Body.Companions = []models.Companion{{Name: "Bob", Type: models.CompanionAdult}, {Name: " bob ", Type: models.CompanionAdult}}
+	if _, err := handler.HandleRegister(context.Background(), &req); err == nil {
+		t.Error("expected duplicate companion names to be rejected")
+	}
+
+	req.Body.Companions = []models.Companion{
+		{Name: "Bob", Type: models.CompanionAdult, DietaryTags: []string{models.DietVegan}},
+		{Name: "Eve", Type: models.CompanionChild, Age: &age},
+	}
+	res, err := handler.HandleRegister(context.Background(), &req)
+	if err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+	if res.Body.Waitlisted {
+		t.Error("expected two adults to fit the event")
+	}
+
+	var registration models.Registration
+	db.Where("user_id = ?", user.ID).First(&registration)
+	if registration.Adults() != 2 || registration.Children() != 1 {
+		t.Errorf("expected 2 adults and 1 child, got %d and %d", registration.Adults(), registration.Children())
+	}
+	if registration.Price != 2*(2*10000+4000) {
+		t.Errorf("expected companions to be priced, got %d", registration.Price)
+	}
+
+	// A third adult does not fit anymore
+	req.Body.Companions = append(req.Body.Companions, models.Companion{Name: "Carol", Type: models.CompanionAdult})
+	if _, err := handler.HandleRegister(context.Background(), &req); err == nil {
+		t.Error("expected companions to count toward the capacity")
+	}
+
+	older := 8
+	req.Body.Companions = []models.Companion{{Name: "Eve", Type: models.CompanionChild, Age: &older}}
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	historyReq := HistoryRequest{Diff: true}
+	historyReq.Cookie = authCookie
+	history, err := handler.HandleHistory(context.Background(), &historyReq)
+	if err != nil {
+		t.Fatalf("HandleHistory failed: %v", err)
+	}
+	changes := history.Body.History[0].RegistrationFields.CompanionChanges
+	if len(changes) != 2 {
+		t.Fatalf("expected two companion changes, got %+v", changes)
+	}
+	if changes[0].Name != "Eve" || changes[0].Change != CompanionUpdated || *changes[0].Companion.Age != 8 {
+		t.Errorf("expected Eve to be updated, got %+v", changes[0])
+	}
+	if changes[1].Name != "Bob" || changes[1].Change != CompanionRemoved || changes[1].Companion != nil {
+		t.Errorf("expected Bob to be removed, got %+v", changes[1])
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandleRegister_Deadlines(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret", OrgRole: "orgs"}
+	event := createTestEvent(t, db, "frozen-event")
+	past := time.Now().Add(-time.Hour)
+	db.Model(&event).Updates(map[string]interface{}{"registration_closes_at": past, "changes_frozen_at": past})
+
+	attendee := models.User{DiscordID: "attendee"}
+	db.Create(&attendee)
+	latecomer := models.User{DiscordID: "latecomer"}
+	db.Create(&latecomer)
+
+	db.Create(&models.Registration{
+		UserID:  attendee.ID,
+		Event:   event.Code,
+		EventID: event.ID,
+		RegistrationFields: models.RegistrationFields{
+			ChildrenCount: 1,
+			Note:          "Original note",
+		},
+	})
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	request := func(user models.User) *RegistrationRequest {
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := &RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		return req
+	}
+
+	expectStatus := func(t *testing.T, err error, status int) {
+		t.Helper()
+		var statusErr huma.StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("expected status error %d, got %v", status, err)
+		}
+		if statusErr.GetStatus() != status {
+			t.Errorf("expected status %d, got %d", status, statusErr.GetStatus())
+		}
+	}
+
+	t.Run("NewRegistrationAfterDeadline", func(t *testing.T) {
+		_, err := handler.HandleRegister(context.Background(), request(latecomer))
+		expectStatus(t, err, http.StatusConflict)
+	})
+
+	t.Run("ChangeAfterFreeze", func(t *testing.T) {
+		req := request(attendee)
+		req.Body.ChildrenCount = 3
+		_, err := handler.HandleRegister(context.Background(), req)
+		expectStatus(t, err, http.StatusLocked)
+	})
+
+	t.Run("CancelAfterFreeze", func(t *testing.T) {
+		req := request(attendee)
+		req.Body.Cancelled = true
+		req.Body.Note = "Sneaky change"
+		if _, err := handler.HandleRegister(context.Background(), req); err != nil {
+			t.Fatalf("expected cancellation to succeed, got %v", err)
+		}
+
+		var registration models.Registration
+		db.Where("user_id = ?", attendee.ID).First(&registration)
+		if !registration.Cancelled {
+			t.Error("expected registration to be cancelled")
+		}
+		if registration.Note != "Original note" || registration.ChildrenCount != 1 {
+			t.Errorf("expected only the cancellation to be applied, got %+v", registration.RegistrationFields)
+		}
+	})
+
+	t.Run("CancelAfterEventClosed", func(t *testing.T) {
+		closed := createTestEvent(t, db, "closed-event")
+		db.Model(&closed).Update("status", models.EventStatusClosed)
+		db.Create(&models.Registration{UserID: latecomer.ID, Event: closed.Code, EventID: closed.ID})
+
+		req := request(latecomer)
+		req.Body.Event = closed.Code
+		req.Body.Note = "Still coming"
+		_, err := handler.HandleRegister(context.Background(), req)
+		expectStatus(t, err, http.StatusBadRequest)
+
+		req.Body.Cancelled = true
+		if _, err := handler.HandleRegister(context.Background(), req); err != nil {
+			t.Fatalf("expected cancellation of a closed event to succeed, got %v", err)
+		}
+		var registration models.Registration
+		db.Where("user_id = ? AND event = ?", latecomer.ID, closed.Code).First(&registration)
+		if !registration.Cancelled || registration.Note != "" {
+			t.Errorf("expected only the cancellation to be applied, got %+v", registration.RegistrationFields)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type DiscordChangeHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewDiscordChangeHandler(db *gorm.DB, authHandler *auth.AuthHandler) *DiscordChangeHandler {
+	return &DiscordChangeHandler{db: db, authHandler: authHandler}
+}
+
+type ListDiscordChangesRequest struct {
+	auth.AuthInput
+	Kind   string `query:"kind" doc:"Optional kind to filter by, e.g. paid_role_added"`
+	UserID uint   `query:"user_id" doc:"Optional user to filter by"`
+	Limit  int    `query:"limit" default:"100" minimum:"1" maximum:"1000"`
+}
+
+type ListDiscordChangesResponse struct {
+	Body struct {
+		Changes []models.DiscordChange `json:"changes" doc:"Newest first"`
+	}
+}
+
+func (h *DiscordChangeHandler) HandleListDiscordChanges(ctx context.Context, input *ListDiscordChangesRequest) (*ListDiscordChangesResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	query := h.db.Order("id DESC")
+	if input.Kind != "" {
+		query = query.Where("kind = ?", input.Kind)
+	}
+	if input.UserID != 0 {
+		query = query.Where("user_id = ?", input.UserID)
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	res := &ListDiscordChangesResponse{}
+	if err := query.Limit(limit).Find(&res.Body.Changes).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch Discord changes: " + err.Error())
+	}
+	return res, nil
+}
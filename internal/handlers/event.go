@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"gorm.io/gorm"
+)
+
+type EventHandler struct {
+	db          *gorm.DB
+	notifier    notifier.Notifier
+	roles       notifier.RoleManager
+	authHandler *auth.AuthHandler
+}
+
+func NewEventHandler(db *gorm.DB, notifier notifier.Notifier, roles notifier.RoleManager, authHandler *auth.AuthHandler) *EventHandler {
+	return &EventHandler{db: db, notifier: notifier, roles: roles, authHandler: authHandler}
+}
+
+type EventBody struct {
+	Code                 string              `json:"code" doc:"Unique event code, also used as the Discord role name" example:"g::t::7.0.0"`
+	Title                string              `json:"title" doc:"Human readable event title"`
+	Location             string              `json:"location,omitempty" doc:"Venue of the event"`
+	StartDate            time.Time           `json:"start_date" doc:"First day of the event"`
+	EndDate              time.Time           `json:"end_date" doc:"Last day of the event"`
+	RegistrationOpensAt  *time.Time          `json:"registration_opens_at,omitempty" doc:"Registrations are rejected before this time"`
+	RegistrationClosesAt *time.Time          `json:"registration_closes_at,omitempty" doc:"New registrations are rejected after this time"`
+	ChangesFrozenAt      *time.Time          `json:"changes_frozen_at,omitempty" doc:"Attendees can only cancel their registration after this time"`
+	Status               string              `json:"status,omitempty" enum:"draft,open,closed" default:"draft" doc:"Only open events accept registrations"`
+	CapacityAdults       int                 `json:"capacity_adults,omitempty" minimum:"0" doc:"Maximum number of adults, 0 means unlimited"`
+	CapacityChildren     int                 `json:"capacity_children,omitempty" minimum:"0" doc:"Maximum number of children, 0 means unlimited"`
+	MealCutoffHours      int                 `json:"meal_cutoff_hours,omitempty" minimum:"0" doc:"Attendees can change their meals of a day until this many hours before it starts"`
+	Pricing              models.EventPricing `json:"pricing,omitempty" doc:"Pricing rules, amounts are in minor currency units"`
+}
+
+type CreateEventRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Body           EventBody
+}
+
+type UpdateEventRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           EventBody
+}
+
+type GetEventRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type DeleteEventRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+}
+
+type EventResponse struct {
+	Body models.Event
+}
+
+type ListEventsRequest struct {
+	auth.AuthInput
+	Status string `query:"status" doc:"Optional status to filter by"`
+}
+
+type ListEventsResponse struct {
+	Body struct {
+		Events []models.Event `json:"events"`
+	}
+}
+
+func validateEventBody(body EventBody) error {
+	if body.Code == "" || body.Title == "" {
+		return huma.Error400BadRequest("Code and title are required")
+	}
+	if body.StartDate.After(body.EndDate) {
+		return huma.Error400BadRequest("Start date cannot be after end date")
+	}
+	if body.RegistrationOpensAt != nil && body.RegistrationClosesAt != nil && body.RegistrationOpensAt.After(*body.RegistrationClosesAt) {
+		return huma.Error400BadRequest("Registration cannot close before it opens")
+	}
+	if body.CapacityAdults < 0 || body.CapacityChildren < 0 {
+		return huma.Error400BadRequest("Capacity cannot be negative")
+	}
+	if body.MealCutoffHours < 0 {
+		return huma.Error400BadRequest("Meal cutoff cannot be negative")
+	}
+	if body.Pricing.FixedFee < 0 || body.Pricing.AdultPerNight < 0 || body.Pricing.ChildPerNight < 0 {
+		return huma.Error400BadRequest("Prices cannot be negative")
+	}
+	if body.Pricing.EarlyBirdDiscountPercent < 0 || body.Pricing.EarlyBirdDiscountPercent > 100 {
+		return huma.Error400BadRequest("Early bird discount must be between 0 and 100 percent")
+	}
+	switch body.Status {
+	case "", models.EventStatusDraft, models.EventStatusOpen, models.EventStatusClosed:
+	default:
+		return huma.Error400BadRequest("Invalid status " + body.Status)
+	}
+	return nil
+}
+
+func applyEventBody(event *models.Event, body EventBody) {
+	event.Code = body.Code
+	event.Title = body.Title
+	event.Location = body.Location
+	event.StartDate = body.StartDate
+	event.EndDate = body.EndDate
+	event.RegistrationOpensAt = body.RegistrationOpensAt
+	event.RegistrationClosesAt = body.RegistrationClosesAt
+	event.ChangesFrozenAt = body.ChangesFrozenAt
+	event.Status = body.Status
+	event.CapacityAdults = body.CapacityAdults
+	event.CapacityChildren = body.CapacityChildren
+	event.MealCutoffHours = body.MealCutoffHours
+	event.Pricing = body.Pricing
+	event.Pricing.Currency = strings.ToUpper(event.Pricing.Currency)
+	if event.Pricing.Currency == "" {
+		event.Pricing.Currency = models.DefaultCurrency
+	}
+	if event.Status == "" {
+		event.Status = models.EventStatusDraft
+	}
+}
+
+// findEvent loads an event by its code, mapping a missing record to 404
+func findEvent(db *gorm.DB, code string) (models.Event, error) {
+	var event models.Event
+	if err := db.Where("code = ?", code).First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return event, huma.Error404NotFound("Event " + code + " not found")
+		}
+		return event, huma.Error500InternalServerError("Failed to fetch event: " + err.Error())
+	}
+	return event, nil
+}
+
+func (h *EventHandler) HandleCreateEvent(ctx context.Context, input *CreateEventRequest) (*EventResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	if err := validateEventBody(input.Body); err != nil {
+		return nil, err
+	}
+
+	var existing models.Event
+	if err := h.db.Where("code = ?", input.Body.Code).First(&existing).Error; err == nil {
+		return nil, huma.Error409Conflict("Event with this code already exists")
+	}
+
+	var event models.Event
+	applyEventBody(&event, input.Body)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+
+		// Link registrations created before the event existed as an entity
+		if err := tx.Model(&models.Registration{}).Where("event = ? AND event_id = 0", event.Code).Update("event_id", event.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RegistrationHistory{}).Where("event = ? AND event_id = 0", event.Code).Update("event_id", event.ID).Error
+	})
+	if err != nil {
+		// Another org created the same event in the meantime
+		if database.IsUniqueViolation(err) {
+			return nil, huma.Error409Conflict("Event with this code already exists")
+		}
+		return nil, huma.Error500InternalServerError("Failed to create event: " + err.Error())
+	}
+
+	return &EventResponse{Body: event}, nil
+}
+
+func (h *EventHandler) HandleUpdateEvent(ctx context.Context, input *UpdateEventRequest) (*EventResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	if err := validateEventBody(input.Body); err != nil {
+		return nil, err
+	}
+
+	if input.Body.Code != input.Code {
+		return nil, huma.Error400BadRequest("Event code cannot be changed")
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Pricing changes move the balances, the paid roles follow them
+		var registrations []models.Registration
+		if err := tx.Preload("User").Where("event_id = ?", event.ID).Find(&registrations).Error; err != nil {
+			return err
+		}
+		before, err := billing.Balances(tx, map[string]models.Event{event.Code: event}, registrations)
+		if err != nil {
+			return err
+		}
+
+		applyEventBody(&event, input.Body)
+		if err := tx.Save(&event).Error; err != nil {
+			return err
+		}
+
+		for _, registration := range registrations {
+			if err := trackPaidRole(tx, h.roles, event, registration, billing.PaidInFull(registration, before[registration.ID])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update event: " + err.Error())
+	}
+
+	return &EventResponse{Body: event}, nil
+}
+
+func (h *EventHandler) HandleGetEvent(ctx context.Context, input *GetEventRequest) (*EventResponse, error) {
+	if _, err := h.authHandler.Authorize(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventResponse{Body: event}, nil
+}
+
+func (h *EventHandler) HandleListEvents(ctx context.Context, input *ListEventsRequest) (*ListEventsResponse, error) {
+	if _, err := h.authHandler.Authorize(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	var events []models.Event
+	query := h.db.Order("start_date DESC")
+	if input.Status != "" {
+		query = query.Where("status = ?", input.Status)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch events: " + err.Error())
+	}
+
+	res := &ListEventsResponse{}
+	res.Body.Events = events
+	return res, nil
+}
+
+func (h *EventHandler) HandleDeleteEvent(ctx context.Context, input *DeleteEventRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := h.db.Model(&models.Registration{}).Where("event_id = ?", event.ID).Count(&count).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to count registrations: " + err.Error())
+	}
+	if count > 0 {
+		return nil, huma.Error409Conflict("Event has registrations, close it instead of deleting")
+	}
+
+	if err := h.db.Delete(&event).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete event: " + err.Error())
+	}
+
+	return nil, nil
+}
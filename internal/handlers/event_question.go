@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type QuestionBody struct {
+	Key      string   `json:"key" doc:"Stable key the answers are stored under" example:"tshirt_size"`
+	Label    string   `json:"label" doc:"Question shown to attendees"`
+	Type     string   `json:"type" enum:"text,number,boolean,single_choice,multi_choice"`
+	Required bool     `json:"required,omitempty"`
+	Options  []string `json:"options,omitempty" doc:"Allowed values for choice questions"`
+}
+
+type SetQuestionsRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           struct {
+		Questions []QuestionBody `json:"questions" doc:"Complete question schema, replaces the existing one"`
+	}
+}
+
+type ListQuestionsRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type QuestionsResponse struct {
+	Body struct {
+		Questions []models.EventQuestion `json:"questions"`
+	}
+}
+
+func validateQuestions(questions []QuestionBody) error {
+	keys := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		if q.Key == "" || q.Label == "" {
+			return huma.Error400BadRequest("Question key and label are required")
+		}
+		if keys[q.Key] {
+			return huma.Error400BadRequest("Duplicate question key " + q.Key)
+		}
+		keys[q.Key] = true
+
+		switch q.Type {
+		case models.QuestionTypeText, models.QuestionTypeNumber, models.QuestionTypeBoolean:
+			if len(q.Options) > 0 {
+				return huma.Error400BadRequest("Question " + q.Key + " cannot have options")
+			}
+		case models.QuestionTypeSingleChoice, models.QuestionTypeMultiChoice:
+			if len(q.Options) == 0 {
+				return huma.Error400BadRequest("Choice question " + q.Key + " needs options")
+			}
+		default:
+			return huma.Error400BadRequest("Invalid type " + q.Type + " of question " + q.Key)
+		}
+	}
+	return nil
+}
+
+// validateAnswer checks a single answer against its question, nil means not answered
+func validateAnswer(q models.EventQuestion, value any) error {
+	switch q.Type {
+	case models.QuestionTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("answer to %s must be a string", q.Key)
+		}
+		if q.Required && strings.TrimSpace(s) == "" {
+			return fmt.Errorf("answer to %s is required", q.Key)
+		}
+	case models.QuestionTypeNumber:
+		v := reflect.ValueOf(value)
+		if !v.CanFloat() && !v.CanInt() && !v.CanUint() {
+			return fmt.Errorf("answer to %s must be a number", q.Key)
+		}
+		if v.CanFloat() && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+			return fmt.Errorf("answer to %s must be a finite number", q.Key)
+		}
+	case models.QuestionTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("answer to %s must be a boolean", q.Key)
+		}
+	case models.QuestionTypeSingleChoice:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(q.Options, s) {
+			return fmt.Errorf("answer to %s must be one of %s", q.Key, strings.Join(q.Options, ", "))
+		}
+	case models.QuestionTypeMultiChoice:
+		var choices []string
+		switch v := value.(type) {
+		case []string:
+			choices = v
+		case []any:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("answer to %s must be a list of strings", q.Key)
+				}
+				choices = append(choices, s)
+			}
+		default:
+			return fmt.Errorf("answer to %s must be a list of strings", q.Key)
+		}
+		for _, c := range choices {
+			if !slices.Contains(q.Options, c) {
+				return fmt.Errorf("answer %s to %s must be one of %s", c, q.Key, strings.Join(q.Options, ", "))
+			}
+		}
+		if q.Required && len(choices) == 0 {
+			return fmt.Errorf("answer to %s is required", q.Key)
+		}
+	}
+	return nil
+}
+
+// validateAnswers checks the answers against the event question schema and
+// returns them without unanswered entries
+func validateAnswers(questions []models.EventQuestion, answers models.Answers) (models.Answers, error) {
+	byKey := make(map[string]models.EventQuestion, len(questions))
+	for _, q := range questions {
+		byKey[q.Key] = q
+	}
+	for key := range answers {
+		if _, ok := byKey[key]; !ok {
+			return nil, fmt.Errorf("unknown question %s", key)
+		}
+	}
+
+	validated := models.Answers{}
+	for _, q := range questions {
+		value, ok := answers[q.Key]
+		if !ok || value == nil {
+			if q.Required {
+				return nil, fmt.Errorf("answer to %s is required", q.Key)
+			}
+			continue
+		}
+		if err := validateAnswer(q, value); err != nil {
+			return nil, err
+		}
+		validated[q.Key] = value
+	}
+	return validated, nil
+}
+
+// answersEqual compares answers treating nil and empty as equal
+func answersEqual(a, b models.Answers) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func eventQuestions(db *gorm.DB, eventID uint) ([]models.EventQuestion, error) {
+	var questions []models.EventQuestion
+	err := db.Where("event_id = ?", eventID).Order("position ASC").Find(&questions).Error
+	return questions, err
+}
+
+func (h *EventHandler) HandleSetQuestions(ctx context.Context, input *SetQuestionsRequest) (*QuestionsResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateQuestions(input.Body.Questions); err != nil {
+		return nil, err
+	}
+
+	questions := make([]models.EventQuestion, len(input.Body.Questions))
+	for i, q := range input.Body.Questions {
+		questions[i] = models.EventQuestion{
+			EventID:  event.ID,
+			Key:      q.Key,
+			Label:    q.Label,
+			Type:     q.Type,
+			Required: q.Required,
+			Options:  q.Options,
+			Position: i,
+		}
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Hard delete so keys can be reused by the new schema
+		if err := tx.Unscoped().Where("event_id = ?", event.ID).Delete(&models.EventQuestion{}).Error; err != nil {
+			return err
+		}
+		if len(questions) == 0 {
+			return nil
+		}
+		return tx.Create(&questions).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to save questions: " + err.Error())
+	}
+
+	res := &QuestionsResponse{}
+	res.Body.Questions = questions
+	return res, nil
+}
+
+func (h *EventHandler) HandleListQuestions(ctx context.Context, input *ListQuestionsRequest) (*QuestionsResponse, error) {
+	if _, err := h.authHandler.Authorize(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := eventQuestions(h.db, event.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch questions: " + err.Error())
+	}
+
+	res := &QuestionsResponse{}
+	res.Body.Questions = questions
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestValidateAnswers(t *testing.T) {
+	questions := []models.EventQuestion{
+		{Key: "tshirt", Type: models.QuestionTypeSingleChoice, Options: []string{"S", "M", "L"}, Required: true},
+		{Key: "train", Type: models.QuestionTypeBoolean},
+		{Key: "tent_size", Type: models.QuestionTypeNumber},
+		{Key: "skills", Type: models.QuestionTypeMultiChoice, Options: []string{"cooking", "driving"}},
+		{Key: "motto", Type: models.QuestionTypeText},
+	}
+
+	tests := []struct {
+		name    string
+		answers models.Answers
+		wantErr bool
+	}{
+		{"Valid", models.Answers{"tshirt": "M", "train": true, "tent_size": 2.0, "skills": []any{"cooking"}, "motto": "hi"}, false},
+		{"OnlyRequired", models.Answers{"tshirt": "L"}, false},
+		{"MissingRequired", models.Answers{"train": false}, true},
+		{"UnknownKey", models.Answers{"tshirt": "S", "pets": "dog"}, true},
+		{"InvalidChoice", models.Answers{"tshirt": "XXL"}, true},
+		{"InvalidMultiChoice", models.Answers{"tshirt": "S", "skills": []any{"juggling"}}, true},
+		{"WrongBooleanType", models.Answers{"tshirt": "S", "train": "yes"}, true},
+		{"WrongNumberType", models.Answers{"tshirt": "S", "tent_size": "two"}, true},
+		{"WrongTextType", models.Answers{"tshirt": "S", "motto": 42.0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateAnswers(questions, tt.answers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHandleRegister_Answers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "answer-user"}
+	db.Create(&user)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "questions-event")
+	db.Create(&models.EventQuestion{EventID: event.ID, Key: "tshirt", Type: models.QuestionTypeSingleChoice, Options: []string{"S", "M"}, Required: true})
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	token, _ := authHandler.GenerateToken(user.ID)
+	authCookie := "auth_token=" + token
+
+	req := RegistrationRequest{}
+	req.Cookie = authCookie
+	req.Body.Event = event.Code
+	if _, err := handler.HandleRegister(context.Background(), &req); err == nil {
+		t.Fatal("expected error for missing required answer, got nil")
+	}
+
+	req.Body.Answers = models.Answers{"tshirt": "S"}
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+	req.Body.Answers = models.Answers{"tshirt": "M"}
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	var registration models.Registration
+	db.First(&registration)
+	if registration.Answers["tshirt"] != "M" {
+		t.Errorf("expected stored answer M, got %v", registration.Answers["tshirt"])
+	}
+
+	historyReq := HistoryRequest{Diff: true}
+	historyReq.Cookie = authCookie
+	resp, err := handler.HandleHistory(context.Background(), &historyReq)
+	if err != nil {
+		t.Fatalf("HandleHistory failed: %v", err)
+	}
+	if len(resp.Body.History) != 2 {
+		t.Fatalf("expected 2 history items, got %d", len(resp.Body.History))
+	}
+	latest := resp.Body.History[0].RegistrationFields
+	if latest.Answers == nil || (*latest.Answers)["tshirt"] != "M" {
+		t.Errorf("expected diff to contain changed answer, got %v", latest.Answers)
+	}
+	if latest.Note != nil {
+		t.Error("expected unchanged note to be omitted from diff")
+	}
+
+	// Cancelling keeps the stored answers instead of unvalidated ones
+	req.Body.Cancelled = true
+	req.Body.Answers = models.Answers{"tshirt": "XXL", "pets": "dog"}
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+	db.First(&registration, registration.ID)
+	if !registration.Cancelled || len(registration.Answers) != 1 || registration.Answers["tshirt"] != "M" {
+		t.Errorf("expected the stored answers to be kept on cancellation, got %v", registration.Answers)
+	}
+}
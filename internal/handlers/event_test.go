@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// createTestEvent creates an event open for registration
+func createTestEvent(t *testing.T, db *gorm.DB, code string) models.Event {
+	t.Helper()
+	event := models.Event{
+		Code:      code,
+		Title:     code,
+		StartDate: time.Now().Add(24 * time.Hour),
+		EndDate:   time.Now().Add(72 * time.Hour),
+		Status:    models.EventStatusOpen,
+	}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatalf("failed to create event %s: %v", code, err)
+	}
+	return event
+}
+
+func TestEvent_RegistrationOpen(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		event models.Event
+		want  bool
+	}{
+		{"Open", models.Event{Status: models.EventStatusOpen}, true},
+		{"Draft", models.Event{Status: models.EventStatusDraft}, false},
+		{"Closed", models.Event{Status: models.EventStatusClosed}, false},
+		{"NotYetOpen", models.Event{Status: models.EventStatusOpen, RegistrationOpensAt: &future}, false},
+		{"AlreadyClosed", models.Event{Status: models.EventStatusOpen, RegistrationClosesAt: &past}, false},
+		{"WithinWindow", models.Event{Status: models.EventStatusOpen, RegistrationOpensAt: &past, RegistrationClosesAt: &future}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.RegistrationOpen(now); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandleRegister_SetsEventID(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "event-user"}
+	db.Create(&user)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "ev-id")
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	token, _ := authHandler.GenerateToken(user.ID)
+	req := RegistrationRequest{}
+	req.Cookie = "auth_token=" + token
+	req.Body.Event = event.Code
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	var registration models.Registration
+	db.First(&registration)
+	if registration.EventID != event.ID {
+		t.Errorf("expected event ID %d, got %d", event.ID, registration.EventID)
+	}
+
+	var history models.RegistrationHistory
+	db.First(&history)
+	if history.EventID != event.ID {
+		t.Errorf("expected history event ID %d, got %d", event.ID, history.EventID)
+	}
+}
+
+func TestHandleCreateEvent_RequiresOrg(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	db.AutoMigrate(&models.User{}, &models.Event{})
+
+	user := models.User{DiscordID: "not-org"}
+	db.Create(&user)
+
+	testCfg := &config.Config{JWTSecret: "test-secret", OrgRole: "orgs"}
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewEventHandler(db, nil, nil, authHandler)
+
+	token, _ := authHandler.GenerateToken(user.ID)
+	req := CreateEventRequest{}
+	req.Cookie = "auth_token=" + token
+	req.Body.Code = "new-event"
+	req.Body.Title = "New Event"
+
+	if _, err := handler.HandleCreateEvent(context.Background(), &req); err == nil {
+		t.Fatal("expected error for non-org user, got nil")
+	}
+
+	var count int64
+	db.Model(&models.Event{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no events to be created, got %d", count)
+	}
+}
+
+func TestHandleDeleteEvent_CodeReusable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	org := models.User{DiscordID: "event-org"}
+	db.Create(&org)
+	authHandler, cookie := orgAuth(t, db, &config.Config{JWTSecret: "test-secret"}, org)
+	handler := NewEventHandler(db, nil, nil, authHandler)
+
+	create := func() error {
+		req := &CreateEventRequest{}
+		req.Cookie = cookie
+		req.Body.Code = "reused"
+		req.Body.Title = "Reused"
+		_, err := handler.HandleCreateEvent(context.Background(), req)
+		return err
+	}
+	if err := create(); err != nil {
+		t.Fatalf("HandleCreateEvent failed: %v", err)
+	}
+	if err := create(); err == nil {
+		t.Error("expected a duplicate code to be rejected")
+	}
+
+	req := &DeleteEventRequest{Code: "reused"}
+	req.Cookie = cookie
+	if _, err := handler.HandleDeleteEvent(context.Background(), req); err != nil {
+		t.Fatalf("HandleDeleteEvent failed: %v", err)
+	}
+	if err := create(); err != nil {
+		t.Errorf("expected the code of a deleted event to be reusable, got %v", err)
+	}
+}
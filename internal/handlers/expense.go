@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/expenses"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+type ExpenseHandler struct {
+	db          *gorm.DB
+	notifier    notifier.Notifier
+	authHandler *auth.AuthHandler
+}
+
+func NewExpenseHandler(db *gorm.DB, notifier notifier.Notifier, authHandler *auth.AuthHandler) *ExpenseHandler {
+	return &ExpenseHandler{db: db, notifier: notifier, authHandler: authHandler}
+}
+
+type ExpenseParticipant struct {
+	Username string `json:"username"`
+	Shares   int    `json:"shares,omitempty" minimum:"0" doc:"Defaults to 1, e.g. 2 for an attendee covering their child"`
+}
+
+type CreateExpenseRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+	Body struct {
+		Description  string               `json:"description" doc:"What was bought, e.g. groceries or fuel"`
+		Amount       int64                `json:"amount" minimum:"1" doc:"Amount in minor currency units"`
+		Currency     string               `json:"currency,omitempty" doc:"Defaults to the event currency"`
+		SpentAt      *time.Time           `json:"spent_at,omitempty" doc:"Defaults to now"`
+		Payer        string               `json:"payer,omitempty" doc:"Username of the payer, defaults to the caller. Only orgs may record expenses paid by others."`
+		Participants []ExpenseParticipant `json:"participants" minItems:"1" doc:"Attendees sharing the expense, the payer included if they take part"`
+	}
+}
+
+type EventExpensesRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type ExpenseIDRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Expense ID"`
+}
+
+type ExpenseShareItem struct {
+	models.ExpenseShare
+	Username string `json:"username"`
+}
+
+type ExpenseItem struct {
+	models.Expense
+	PayerUsername string             `json:"payer_username"`
+	Shares        []ExpenseShareItem `json:"shares"`
+}
+
+type ExpenseResponse struct {
+	Body ExpenseItem
+}
+
+type ListExpensesResponse struct {
+	Body struct {
+		Currency  string                 `json:"currency"`
+		Expenses  []ExpenseItem          `json:"expenses"`
+		Balances  []expenses.UserBalance `json:"balances"`
+		Transfers []models.Transfer      `json:"transfers" doc:"Transfers settling all balances"`
+	}
+}
+
+type SettlementResponse struct {
+	Body struct {
+		Currency  string            `json:"currency"`
+		Transfers []models.Transfer `json:"transfers"`
+	}
+}
+
+func newExpenseItem(expense models.Expense) ExpenseItem {
+	item := ExpenseItem{Expense: expense, PayerUsername: expense.Payer.Username, Shares: make([]ExpenseShareItem, len(expense.Shares))}
+	for i, s := range expense.Shares {
+		item.Shares[i] = ExpenseShareItem{ExpenseShare: s, Username: s.User.Username}
+	}
+	return item
+}
+
+// settlement computes the balances of the event expenses sorted by username and the transfers settling them
+func settlement(db *gorm.DB, event models.Event) ([]models.Expense, []expenses.UserBalance, []models.Transfer, error) {
+	list, err := expenses.Load(db, event.ID)
+	if err != nil {
+		return nil, nil, nil, huma.Error500InternalServerError("Failed to fetch expenses: " + err.Error())
+	}
+
+	byUser := expenses.Balances(list)
+	balances := make([]expenses.UserBalance, 0, len(byUser))
+	for _, b := range byUser {
+		balances = append(balances, b)
+	}
+	slices.SortFunc(balances, func(a expenses.UserBalance, b expenses.UserBalance) int {
+		return strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username))
+	})
+
+	transfers := expenses.Settle(byUser)
+	if transfers == nil {
+		transfers = []models.Transfer{}
+	}
+	return list, balances, transfers, nil
+}
+
+// expenseAttendees resolves usernames to users with a confirmed registration for the event
+func expenseAttendees(db *gorm.DB, event models.Event, usernames []string) (map[string]models.User, error) {
+	lower := make([]string, len(usernames))
+	for i, name := range usernames {
+		lower[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	var registrations []models.Registration
+	err := db.Preload("User").
+		Joins("JOIN users ON users.id = registrations.user_id").
+		Where("registrations.event_id = ? AND registrations.cancelled = ? AND registrations.waitlisted = ?", event.ID, false, false).
+		Where("LOWER(users.username) IN ?", lower).
+		Find(&registrations).Error
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch attendees: " + err.Error())
+	}
+
+	users := make(map[string]models.User, len(registrations))
+	for _, r := range registrations {
+		users[strings.ToLower(r.User.Username)] = r.User
+	}
+	for _, name := range lower {
+		if _, ok := users[name]; !ok {
+			return nil, huma.Error400BadRequest("No confirmed attendee with username " + name)
+		}
+	}
+	return users, nil
+}
+
+// canSeeExpenses allows confirmed attendees of the event and orgs
+func (h *ExpenseHandler) canSeeExpenses(userID uint, event models.Event) error {
+	if _, err := confirmedRegistration(h.db, userID, event.ID); err == nil {
+		return nil
+	}
+	isOrg, err := h.authHandler.IsOrg(userID)
+	if err != nil {
+		return err
+	}
+	if !isOrg {
+		return huma.Error403Forbidden("Only attendees can see the expenses of the event")
+	}
+	return nil
+}
+
+func (h *ExpenseHandler) HandleCreateExpense(ctx context.Context, input *CreateExpenseRequest) (*ExpenseResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.canSeeExpenses(userID, event); err != nil {
+		return nil, err
+	}
+
+	if input.Body.Amount <= 0 || strings.TrimSpace(input.Body.Description) == "" {
+		return nil, huma.Error400BadRequest("Description and a positive amount are required")
+	}
+	currency := strings.ToUpper(input.Body.Currency)
+	if currency == "" {
+		currency = billing.Currency(event)
+	}
+	if currency != billing.Currency(event) {
+		return nil, huma.Error400BadRequest("Event " + event.Code + " is priced in " + billing.Currency(event))
+	}
+
+	var usernames []string
+	shares := make([]int, len(input.Body.Participants))
+	for i, p := range input.Body.Participants {
+		name := strings.ToLower(strings.TrimSpace(p.Username))
+		if slices.Contains(usernames, name) {
+			return nil, huma.Error400BadRequest("Participant " + p.Username + " is listed more than once")
+		}
+		usernames = append(usernames, name)
+		shares[i] = p.Shares
+		if shares[i] == 0 {
+			shares[i] = 1
+		}
+	}
+	if len(usernames) == 0 {
+		return nil, huma.Error400BadRequest("At least one participant is required")
+	}
+	attendees, err := expenseAttendees(h.db, event, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	payerID := userID
+	if input.Body.Payer != "" {
+		payers, err := expenseAttendees(h.db, event, []string{input.Body.Payer})
+		if err != nil {
+			return nil, err
+		}
+		payerID = payers[strings.ToLower(strings.TrimSpace(input.Body.Payer))].ID
+	}
+	if payerID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error403Forbidden("Only orgs can record expenses paid by others")
+		}
+	}
+
+	expense := models.Expense{
+		EventID:     event.ID,
+		Event:       event.Code,
+		PayerID:     payerID,
+		Amount:      input.Body.Amount,
+		Currency:    currency,
+		Description: strings.TrimSpace(input.Body.Description),
+		SpentAt:     time.Now(),
+		CreatedByID: userID,
+	}
+	if input.Body.SpentAt != nil {
+		expense.SpentAt = *input.Body.SpentAt
+	}
+	for i, amount := range expenses.Split(expense.Amount, shares) {
+		expense.Shares = append(expense.Shares, models.ExpenseShare{UserID: attendees[usernames[i]].ID, Shares: shares[i], Amount: amount})
+	}
+
+	if err := h.db.Create(&expense).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create expense: " + err.Error())
+	}
+
+	if err := h.db.Preload("Payer").Preload("Shares.User").First(&expense, expense.ID).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch expense: " + err.Error())
+	}
+	return &ExpenseResponse{Body: newExpenseItem(expense)}, nil
+}
+
+func (h *ExpenseHandler) HandleListExpenses(ctx context.Context, input *EventExpensesRequest) (*ListExpensesResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.canSeeExpenses(userID, event); err != nil {
+		return nil, err
+	}
+
+	list, balances, transfers, err := settlement(h.db, event)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListExpensesResponse{}
+	res.Body.Currency = billing.Currency(event)
+	res.Body.Expenses = make([]ExpenseItem, len(list))
+	for i, e := range list {
+		res.Body.Expenses[i] = newExpenseItem(e)
+	}
+	res.Body.Balances = balances
+	res.Body.Transfers = transfers
+	return res, nil
+}
+
+func (h *ExpenseHandler) HandleDeleteExpense(ctx context.Context, input *ExpenseIDRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var expense models.Expense
+	if err := h.db.First(&expense, input.ID).Error; err != nil {
+		return nil, huma.Error404NotFound("Expense not found")
+	}
+	if expense.PayerID != userID && expense.CreatedByID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error403Forbidden("Only the payer can delete the expense")
+		}
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&expense).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete expense: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *ExpenseHandler) HandlePostSettlement(ctx context.Context, input *EventExpensesRequest) (*SettlementResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, transfers, err := settlement(h.db, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.notifier == nil {
+		return nil, huma.Error503ServiceUnavailable("Notifications are not configured")
+	}
+	// Posted by the outbox worker, which computes the transfers again when delivering
+	if err := outbox.NotifySettlement(h.db, event.ID); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to post the settlement: " + err.Error())
+	}
+
+	res := &SettlementResponse{}
+	res.Body.Currency = billing.Currency(event)
+	res.Body.Transfers = transfers
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExpenses(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "expense-event")
+	register := func(username string, fields models.RegistrationFields) models.User {
+		user := models.User{DiscordID: "expense-" + username, Username: username}
+		db.Create(&user)
+		fields.ArrivalDate = time.Now().AddDate(0, 0, 1)
+		fields.DepartureDate = time.Now().AddDate(0, 0, 3)
+		db.Create(&models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: fields})
+		return user
+	}
+	alice := register("alice", models.RegistrationFields{})
+	bob := register("bob", models.RegistrationFields{})
+	carol := register("carol", models.RegistrationFields{ChildrenCount: 1})
+	waiting := register("waiting", models.RegistrationFields{Waitlisted: true})
+
+	n := &fakeNotifier{}
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	handler := NewExpenseHandler(db, n, authHandler)
+	cookie := func(user models.User) string {
+		token, _ := authHandler.GenerateToken(user.ID)
+		return "auth_token=" + token
+	}
+	create := func(user models.User, amount int64, payer string, participants ...ExpenseParticipant) (*ExpenseResponse, error) {
+		req := &CreateExpenseRequest{Code: event.Code}
+		req.Cookie = cookie(user)
+		req.Body.Description = "groceries"
+		req.Body.Amount = amount
+		req.Body.Payer = payer
+		req.Body.Participants = participants
+		return handler.HandleCreateExpense(context.Background(), req)
+	}
+
+	groceries, err := create(alice, 1000, "", ExpenseParticipant{Username: "alice"}, ExpenseParticipant{Username: "Bob"}, ExpenseParticipant{Username: "carol", Shares: 2})
+	if err != nil {
+		t.Fatalf("HandleCreateExpense failed: %v", err)
+	}
+	if s := groceries.Body.Shares; len(s) != 3 || s[0].Amount != 250 || s[2].Amount != 500 || s[1].Username != "bob" {
+		t.Errorf("unexpected shares %+v", s)
+	}
+	if _, err := create(bob, 300, "", ExpenseParticipant{Username: "carol"}); err != nil {
+		t.Fatalf("HandleCreateExpense failed: %v", err)
+	}
+
+	if _, err := create(waiting, 100, "", ExpenseParticipant{Username: "alice"}); err == nil {
+		t.Error("expected waitlisted attendee to be rejected")
+	}
+	if _, err := create(alice, 100, "", ExpenseParticipant{Username: "waiting"}); err == nil {
+		t.Error("expected waitlisted participant to be rejected")
+	}
+	if _, err := create(alice, 100, "", ExpenseParticipant{Username: "bob"}, ExpenseParticipant{Username: "BOB"}); err == nil {
+		t.Error("expected duplicate participant to be rejected")
+	}
+	if _, err := create(alice, 100, "bob", ExpenseParticipant{Username: "alice"}); err == nil {
+		t.Error("expected expense paid by somebody else to be rejected")
+	}
+
+	list := &EventExpensesRequest{Code: event.Code}
+	list.Cookie = cookie(carol)
+	res, err := handler.HandleListExpenses(context.Background(), list)
+	if err != nil {
+		t.Fatalf("HandleListExpenses failed: %v", err)
+	}
+	if len(res.Body.Expenses) != 2 || res.Body.Currency != models.DefaultCurrency {
+		t.Errorf("unexpected expenses %+v", res.Body.Expenses)
+	}
+	if b := res.Body.Balances; len(b) != 3 || b[0].Username != "alice" || b[0].Net != 750 || b[2].Net != -800 {
+		t.Errorf("unexpected balances %+v", b)
+	}
+	if tr := res.Body.Transfers; len(tr) != 2 || tr[0].FromID != carol.ID || tr[0].ToID != alice.ID || tr[0].Amount != 750 {
+		t.Errorf("unexpected transfers %+v", tr)
+	}
+
+	remove := &ExpenseIDRequest{ID: groceries.Body.ID}
+	remove.Cookie = cookie(bob)
+	if _, err := handler.HandleDeleteExpense(context.Background(), remove); err == nil {
+		t.Error("expected other participants to be denied")
+	}
+	remove.Cookie = cookie(alice)
+	if _, err := handler.HandleDeleteExpense(context.Background(), remove); err != nil {
+		t.Fatalf("HandleDeleteExpense failed: %v", err)
+	}
+	_, balances, transfers, _ := settlement(db, event)
+	if len(balances) != 2 || len(transfers) != 1 || transfers[0].Amount != 300 {
+		t.Errorf("expected only the remaining expense to be settled, got %+v %+v", balances, transfers)
+	}
+
+	// The settlement is posted through the outbox
+	org := models.User{DiscordID: "expense-org", Username: "org"}
+	db.Create(&org)
+	orgHandler, orgCookie := orgAuth(t, db, &config.Config{JWTSecret: "test-secret"}, org)
+	post := &EventExpensesRequest{Code: event.Code}
+	post.Cookie = orgCookie
+	if _, err := NewExpenseHandler(db, n, orgHandler).HandlePostSettlement(context.Background(), post); err != nil {
+		t.Fatalf("HandlePostSettlement failed: %v", err)
+	}
+	n.deliver(t, db)
+	if len(n.settlements) != 1 || len(n.settlements[0]) != 1 || n.settlements[0][0].Amount != 300 {
+		t.Errorf("expected the settlement to be posted, got %+v", n.settlements)
+	}
+}
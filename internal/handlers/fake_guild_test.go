@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// fakeGuild stands in for the Discord REST API and counts the calls made to it
+type fakeGuild struct {
+	roles   []*discordgo.Role
+	members map[string][]string
+	calls   int
+}
+
+func (g *fakeGuild) GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error) {
+	g.calls++
+	return g.roles, nil
+}
+
+func (g *fakeGuild) GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	g.calls++
+	roles, ok := g.members[userID]
+	if !ok {
+		return nil, &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	}
+	return &discordgo.Member{User: &discordgo.User{ID: userID}, Roles: roles}, nil
+}
+
+func (g *fakeGuild) GuildMembers(guildID string, after string, limit int, options ...discordgo.RequestOption) ([]*discordgo.Member, error) {
+	g.calls++
+	var page []*discordgo.Member
+	for id, roles := range g.members {
+		page = append(page, &discordgo.Member{User: &discordgo.User{ID: id}, Roles: roles})
+	}
+	return page, nil
+}
+
+// orgAuth returns an auth handler whose guild knows the user as an org, and a cookie signing the user in
+func orgAuth(t *testing.T, db *gorm.DB, cfg *config.Config, org models.User) (*auth.AuthHandler, string) {
+	t.Helper()
+	cfg.DiscordGuildID = "guild"
+	if cfg.OrgRole == "" {
+		cfg.OrgRole = "orgs"
+	}
+	guild := &fakeGuild{
+		roles:   []*discordgo.Role{{ID: "org-role", Name: cfg.OrgRole}},
+		members: map[string][]string{org.DiscordID: {"org-role"}},
+	}
+	authHandler := auth.NewAuthHandler(cfg, db, guildcache.NewCache(guild, cfg.DiscordGuildID, time.Minute))
+	cookie, err := authHandler.SessionCookie(org.ID)
+	if err != nil {
+		t.Fatalf("SessionCookie failed: %v", err)
+	}
+	return authHandler, strings.Split(cookie, ";")[0]
+}
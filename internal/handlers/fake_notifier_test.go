@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+// fakeNotifier records notifications instead of talking to Discord
+type fakeNotifier struct {
+	registrations []models.Registration
+	promotions    []models.Registration
+	grantedRoles  []string
+	removedRoles  []string
+	fullRides     []models.Ride
+	leftRides     []models.Ride
+	cancelledFor  []models.User
+	settlements   [][]models.Transfer
+	payments      []models.Payment
+}
+
+func (f *fakeNotifier) CreateRole(name string) (string, error) {
+	return "role-" + name, nil
+}
+
+func (f *fakeNotifier) GrantRole(userID string, roleID string) error {
+	return nil
+}
+
+func (f *fakeNotifier) GrantRoleByName(userID string, roleName string) error {
+	f.grantedRoles = append(f.grantedRoles, roleName)
+	return nil
+}
+
+func (f *fakeNotifier) RemoveRoleByName(userID string, roleName string) error {
+	f.removedRoles = append(f.removedRoles, roleName)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error {
+	return nil
+}
+
+func (f *fakeNotifier) NotifyRegistration(user models.User, registration models.Registration) error {
+	f.registrations = append(f.registrations, registration)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyPayment(user models.User, registration models.Registration, payment models.Payment) error {
+	f.payments = append(f.payments, payment)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyWaitlistPromotion(user models.User, registration models.Registration) error {
+	f.promotions = append(f.promotions, registration)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyRideFull(driver models.User, ride models.Ride) error {
+	f.fullRides = append(f.fullRides, ride)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyRideLeft(driver models.User, passenger models.User, ride models.Ride) error {
+	f.leftRides = append(f.leftRides, ride)
+	return nil
+}
+
+func (f *fakeNotifier) NotifyRideCancelled(passenger models.User, ride models.Ride) error {
+	f.cancelledFor = append(f.cancelledFor, passenger)
+	return nil
+}
+
+func (f *fakeNotifier) NotifySettlement(event models.Event, currency string, transfers []models.Transfer) error {
+	f.settlements = append(f.settlements, transfers)
+	return nil
+}
+
+func (f *fakeNotifier) HasRole(userID string, roleID string) (bool, error) {
+	return false, nil
+}
+
+// deliver runs the outbox worker with the fake as the only channel and role manager
+func (f *fakeNotifier) deliver(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	worker := outbox.NewWorker(db, map[string]notifier.Notifier{notifier.DiscordChannel: f}, f)
+	if _, err := worker.ProcessDue(time.Now()); err != nil {
+		t.Fatalf("failed to process outbox: %v", err)
+	}
+}
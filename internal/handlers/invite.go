@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type InviteHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+	cfg         *config.Config
+}
+
+func NewInviteHandler(db *gorm.DB, authHandler *auth.AuthHandler, cfg *config.Config) *InviteHandler {
+	return &InviteHandler{db: db, authHandler: authHandler, cfg: cfg}
+}
+
+type CreateInviteRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+	Body struct {
+		MaxUses   int        `json:"max_uses,omitempty" minimum:"1" doc:"Number of guest accounts the invite can create, defaults to 1"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		Note      string     `json:"note,omitempty" doc:"Who the invite is for"`
+	}
+}
+
+type EventInvitesRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type InviteIDRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Invite ID"`
+}
+
+type InviteTokenRequest struct {
+	Token string `path:"token" doc:"Invite token"`
+}
+
+type AcceptInviteRequest struct {
+	Token string `path:"token" doc:"Invite token"`
+	Body  struct {
+		Username string `json:"username" minLength:"1" doc:"Name shown to other attendees, must not be taken"`
+		Email    string `json:"email" format:"email"`
+	}
+}
+
+type InviteItem struct {
+	models.Invite
+	URL string `json:"url" doc:"Link with the invite details to share with the guests"`
+}
+
+type InviteResponse struct {
+	Body InviteItem
+}
+
+type ListInvitesResponse struct {
+	Body struct {
+		Invites []InviteItem `json:"invites"`
+	}
+}
+
+type InviteDetailsResponse struct {
+	Body struct {
+		Event     string     `json:"event"`
+		Title     string     `json:"title"`
+		StartDate time.Time  `json:"start_date"`
+		EndDate   time.Time  `json:"end_date"`
+		Remaining int        `json:"remaining" doc:"Guest accounts the invite can still create"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}
+}
+
+type AcceptInviteResponse struct {
+	SetCookie string `header:"Set-Cookie"`
+	Body      struct {
+		UserID   uint   `json:"user_id"`
+		Username string `json:"username"`
+		Event    string `json:"event" doc:"Event the guest can register for"`
+	}
+}
+
+var errInviteUsed = errors.New("invite used up")
+
+func (h *InviteHandler) newInviteItem(invite models.Invite) InviteItem {
+	return InviteItem{Invite: invite, URL: strings.TrimRight(h.cfg.PublicURL, "/") + "/invites/" + invite.Token}
+}
+
+// usableInvite finds an invite by its token that can still create guest accounts
+func usableInvite(db *gorm.DB, token string) (models.Invite, error) {
+	var invite models.Invite
+	if token == "" || db.Where("token = ?", token).First(&invite).Error != nil {
+		return invite, huma.Error404NotFound("Invite not found")
+	}
+	if !invite.Usable(time.Now()) {
+		return invite, huma.Error410Gone("Invite has expired or was used up")
+	}
+	return invite, nil
+}
+
+// guestInvitedTo rejects guests registering for events they were not invited to
+func guestInvitedTo(db *gorm.DB, userID uint, event models.Event) error {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return huma.Error404NotFound("User not found")
+	}
+	if !user.Guest {
+		return nil
+	}
+	var invite models.Invite
+	if user.InviteID == nil || db.Unscoped().First(&invite, *user.InviteID).Error != nil || invite.EventID != event.ID {
+		return huma.Error403Forbidden("Guests can only register for the event they were invited to")
+	}
+	return nil
+}
+
+func (h *InviteHandler) HandleCreateInvite(ctx context.Context, input *CreateInviteRequest) (*InviteResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	maxUses := input.Body.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 0 {
+		return nil, huma.Error400BadRequest("Max uses must be positive")
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate token")
+	}
+
+	invite := models.Invite{
+		EventID:     event.ID,
+		Event:       event.Code,
+		Token:       hex.EncodeToString(tokenBytes),
+		MaxUses:     maxUses,
+		ExpiresAt:   input.Body.ExpiresAt,
+		Note:        input.Body.Note,
+		CreatedByID: org.ID,
+	}
+	if err := h.db.Create(&invite).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create invite: " + err.Error())
+	}
+	return &InviteResponse{Body: h.newInviteItem(invite)}, nil
+}
+
+func (h *InviteHandler) HandleListInvites(ctx context.Context, input *EventInvitesRequest) (*ListInvitesResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var invites []models.Invite
+	if err := h.db.Where("event_id = ?", event.ID).Order("created_at DESC").Find(&invites).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch invites: " + err.Error())
+	}
+
+	res := &ListInvitesResponse{}
+	res.Body.Invites = make([]InviteItem, len(invites))
+	for i, invite := range invites {
+		res.Body.Invites[i] = h.newInviteItem(invite)
+	}
+	return res, nil
+}
+
+func (h *InviteHandler) HandleRevokeInvite(ctx context.Context, input *InviteIDRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	// Soft delete keeps the invite around for the guests created with it
+	result := h.db.Delete(&models.Invite{}, input.ID)
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to revoke invite: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, huma.Error404NotFound("Invite not found")
+	}
+	return nil, nil
+}
+
+func (h *InviteHandler) HandleGetInvite(ctx context.Context, input *InviteTokenRequest) (*InviteDetailsResponse, error) {
+	invite, err := usableInvite(h.db, input.Token)
+	if err != nil {
+		return nil, err
+	}
+	event, err := findEvent(h.db, invite.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &InviteDetailsResponse{}
+	res.Body.Event = event.Code
+	res.Body.Title = event.Title
+	res.Body.StartDate = event.StartDate
+	res.Body.EndDate = event.EndDate
+	res.Body.Remaining = invite.MaxUses - invite.Uses
+	res.Body.ExpiresAt = invite.ExpiresAt
+	return res, nil
+}
+
+func (h *InviteHandler) HandleAcceptInvite(ctx context.Context, input *AcceptInviteRequest) (*AcceptInviteResponse, error) {
+	invite, err := usableInvite(h.db, input.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	username := strings.TrimSpace(input.Body.Username)
+	if username == "" {
+		return nil, huma.Error400BadRequest("Username is required")
+	}
+	address, err := mail.ParseAddress(input.Body.Email)
+	if err != nil {
+		return nil, huma.Error400BadRequest("Invalid email address")
+	}
+	email := strings.ToLower(address.Address)
+
+	var taken int64
+	if err := h.db.Model(&models.User{}).Where("LOWER(username) = ?", strings.ToLower(username)).Count(&taken).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to check username: " + err.Error())
+	}
+	if taken > 0 {
+		return nil, huma.Error409Conflict("Username " + username + " is already taken")
+	}
+	if err := h.db.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&taken).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to check email: " + err.Error())
+	}
+	if taken > 0 {
+		return nil, huma.Error409Conflict("An account with this email already exists")
+	}
+
+	user := models.User{Username: username, Email: email, Guest: true, InviteID: &invite.ID}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Count the use only while the invite has some left, concurrent guests may race for the last one
+		result := tx.Model(&models.Invite{}).Where("id = ? AND uses < max_uses", invite.ID).Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInviteUsed
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		if errors.Is(err, errInviteUsed) {
+			return nil, huma.Error410Gone("Invite has expired or was used up")
+		}
+		return nil, huma.Error500InternalServerError("Failed to create guest account: " + err.Error())
+	}
+
+	cookie, err := h.authHandler.SessionCookie(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AcceptInviteResponse{SetCookie: cookie}
+	res.Body.UserID = user.ID
+	res.Body.Username = user.Username
+	res.Body.Event = invite.Event
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGuestInvites(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "invite-event")
+	other := createTestEvent(t, db, "other-event")
+	db.Create(&models.User{DiscordID: "invite-member", Username: "member", Email: "member@example.com"})
+
+	// Creating invites is restricted to orgs
+	invite := models.Invite{EventID: event.ID, Event: event.Code, Token: "guest-token", MaxUses: 2}
+	db.Create(&invite)
+
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	authHandler := auth.NewAuthHandler(cfg, db, nil)
+	handler := NewInviteHandler(db, authHandler, cfg)
+	registrationHandler := NewRegistrationHandler(db, nil, nil, authHandler, cfg)
+
+	details, err := handler.HandleGetInvite(context.Background(), &InviteTokenRequest{Token: invite.Token})
+	if err != nil {
+		t.Fatalf("HandleGetInvite failed: %v", err)
+	}
+	if details.Body.Event != event.Code || details.Body.Remaining != 2 {
+		t.Errorf("unexpected invite details %+v", details.Body)
+	}
+	if _, err := handler.HandleGetInvite(context.Background(), &InviteTokenRequest{Token: "unknown"}); err == nil {
+		t.Error("expected unknown invite to be rejected")
+	}
+
+	accept := func(username string, email string) (*AcceptInviteResponse, error) {
+		req := &AcceptInviteRequest{Token: invite.Token}
+		req.Body.Username = username
+		req.Body.Email = email
+		return handler.HandleAcceptInvite(context.Background(), req)
+	}
+	if _, err := accept("Member", "guest@example.com"); err == nil {
+		t.Error("expected taken username to be rejected")
+	}
+	if _, err := accept("guest", "Member@example.com"); err == nil {
+		t.Error("expected taken email to be rejected")
+	}
+
+	accepted, err := accept("guest", "Guest <guest@example.com>")
+	if err != nil {
+		t.Fatalf("HandleAcceptInvite failed: %v", err)
+	}
+	if !strings.HasPrefix(accepted.SetCookie, "auth_token=") || accepted.Body.Event != event.Code {
+		t.Errorf("unexpected accept response %+v", accepted)
+	}
+	var guest models.User
+	db.First(&guest, accepted.Body.UserID)
+	if !guest.Guest || guest.DiscordID != "" || guest.Email != "guest@example.com" {
+		t.Errorf("unexpected guest account %+v", guest)
+	}
+
+	// Guests share the empty Discord ID
+	if _, err := accept("second", "second@example.com"); err != nil {
+		t.Fatalf("HandleAcceptInvite failed for the second guest: %v", err)
+	}
+	if _, err := accept("third", "third@example.com"); err == nil {
+		t.Error("expected used up invite to be rejected")
+	}
+
+	register := func(code string) error {
+		req := &RegistrationRequest{}
+		req.Cookie = strings.Split(accepted.SetCookie, ";")[0]
+		req.Body.Event = code
+		req.Body.ArrivalDate = event.StartDate
+		req.Body.DepartureDate = event.EndDate
+		_, err := registrationHandler.HandleRegister(context.Background(), req)
+		return err
+	}
+	if err := register(other.Code); err == nil {
+		t.Error("expected guest registration for another event to be rejected")
+	}
+	if err := register(event.Code); err != nil {
+		t.Fatalf("HandleRegister failed for the guest: %v", err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	db.Create(&models.Invite{EventID: event.ID, Event: event.Code, Token: "expired-token", MaxUses: 1, ExpiresAt: &expired})
+	if _, err := handler.HandleGetInvite(context.Background(), &InviteTokenRequest{Token: "expired-token"}); err == nil {
+		t.Error("expected expired invite to be rejected")
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/meals"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type MealHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewMealHandler(db *gorm.DB, authHandler *auth.AuthHandler) *MealHandler {
+	return &MealHandler{db: db, authHandler: authHandler}
+}
+
+type MealReportRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+}
+
+type FoodRestriction struct {
+	RegistrationID   uint     `json:"registration_id"`
+	Username         string   `json:"username"`
+	DietaryTags      []string `json:"dietary_tags"`
+	FoodRestrictions string   `json:"food_restrictions"`
+	People           int      `json:"people"`
+}
+
+type MealReportResponse struct {
+	Body struct {
+		Meals        []meals.Headcount `json:"meals" doc:"Headcount of each meal by day"`
+		Restrictions []FoodRestriction `json:"restrictions" doc:"Attendees with free text food restrictions"`
+	}
+}
+
+type MealChoicesRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Registration ID"`
+}
+
+type SetMealChoicesRequest struct {
+	auth.AuthInput
+	ID   uint `path:"id" doc:"Registration ID"`
+	Body struct {
+		SkippedMeals []models.MealSlot `json:"skipped_meals" doc:"Meals of the stay the attendees will not eat, all others are counted"`
+	}
+}
+
+type MealChoice struct {
+	models.MealSlot
+	Eating bool `json:"eating"`
+	Locked bool `json:"locked" doc:"The cutoff for changing the meal has passed"`
+}
+
+type MealChoicesResponse struct {
+	Body struct {
+		Meals []MealChoice `json:"meals" doc:"Meals served during the stay"`
+	}
+}
+
+type MealReportCSVResponse struct {
+	ContentType        string `header:"Content-Type"`
+	ContentDisposition string `header:"Content-Disposition"`
+	Body               []byte
+}
+
+// mealReport counts the meals of confirmed registrations of the event
+func mealReport(db *gorm.DB, event models.Event) ([]meals.Headcount, []FoodRestriction, error) {
+	var registrations []models.Registration
+	if err := db.Preload("User").Where("event_id = ? AND cancelled = ? AND waitlisted = ?", event.ID, false, false).Order("id ASC").Find(&registrations).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var diners []meals.Diner
+	restrictions := []FoodRestriction{}
+	for _, r := range registrations {
+		diners = append(diners, meals.RegistrationDiners(r)...)
+		if strings.TrimSpace(r.FoodRestrictions) != "" {
+			restrictions = append(restrictions, FoodRestriction{
+				RegistrationID:   r.ID,
+				Username:         r.User.Username,
+				DietaryTags:      r.DietaryTags,
+				FoodRestrictions: r.FoodRestrictions,
+				People:           r.Adults() + r.Children(),
+			})
+		}
+	}
+	return meals.Report(diners), restrictions, nil
+}
+
+func (h *MealHandler) HandleMealReport(ctx context.Context, input *MealReportRequest) (*MealReportResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	report, restrictions, err := mealReport(h.db, event)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch registrations: " + err.Error())
+	}
+
+	res := &MealReportResponse{}
+	res.Body.Meals = report
+	res.Body.Restrictions = restrictions
+	return res, nil
+}
+
+func (h *MealHandler) HandleMealReportCSV(ctx context.Context, input *MealReportRequest) (*MealReportCSVResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	report, _, err := mealReport(h.db, event)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch registrations: " + err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := meals.WriteCSV(&buf, report); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to write CSV: " + err.Error())
+	}
+
+	return &MealReportCSVResponse{
+		ContentType:        "text/csv; charset=utf-8",
+		ContentDisposition: `attachment; filename="meals.csv"`,
+		Body:               buf.Bytes(),
+	}, nil
+}
+
+func newMealChoicesResponse(event models.Event, registration models.Registration, now time.Time) *MealChoicesResponse {
+	res := &MealChoicesResponse{}
+	res.Body.Meals = []MealChoice{}
+	for _, slot := range meals.Served(registration.ArrivalDate, registration.DepartureDate) {
+		res.Body.Meals = append(res.Body.Meals, MealChoice{
+			MealSlot: slot,
+			Eating:   !meals.Contains(registration.SkippedMeals, slot),
+			Locked:   event.MealChangesClosed(slot.Day, now),
+		})
+	}
+	return res
+}
+
+// validateMealChoices checks the skipped meals are served during the stay and reports whether
+// any changed meal is past the event cutoff
+func validateMealChoices(event models.Event, registration models.Registration, skipped []models.MealSlot, now time.Time) ([]models.MealSlot, bool, error) {
+	served := meals.Served(registration.ArrivalDate, registration.DepartureDate)
+	for _, slot := range skipped {
+		if !meals.Contains(served, slot) {
+			return nil, false, huma.Error400BadRequest(fmt.Sprintf("No %s is served on %s during the stay", slot.Meal, slot.Day.Format("2006-01-02")))
+		}
+	}
+	valid := meals.Within(skipped, registration.ArrivalDate, registration.DepartureDate)
+
+	for _, slot := range meals.Changed(registration.SkippedMeals, valid) {
+		if event.MealChangesClosed(slot.Day, now) {
+			return valid, true, nil
+		}
+	}
+	return valid, false, nil
+}
+
+// stayMealsLocked reports whether moving the stay of the registration to the new dates
+// adds or removes a meal past the event cutoff
+func stayMealsLocked(event models.Event, registration models.Registration, arrival time.Time, departure time.Time, now time.Time) bool {
+	before := mealsEaten(registration.ArrivalDate, registration.DepartureDate, registration.SkippedMeals)
+	after := mealsEaten(arrival, departure, registration.SkippedMeals)
+	for _, slot := range meals.Changed(before, after) {
+		if event.MealChangesClosed(slot.Day, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// mealsEaten lists the meals served during the stay which are not skipped
+func mealsEaten(arrival time.Time, departure time.Time, skipped []models.MealSlot) []models.MealSlot {
+	var eaten []models.MealSlot
+	for _, slot := range meals.Served(arrival, departure) {
+		if !meals.Contains(skipped, slot) {
+			eaten = append(eaten, slot)
+		}
+	}
+	return eaten
+}
+
+func mealsLockedError(event models.Event) error {
+	return huma.NewError(http.StatusLocked, fmt.Sprintf("Meals can only be changed until %d hours before the day starts", event.MealCutoffHours))
+}
+
+// ownRegistration loads the registration of the caller, orgs may access any
+func (h *MealHandler) ownRegistration(ctx context.Context, cookie string, id uint) (models.Registration, models.Event, uint, error) {
+	userID, err := h.authHandler.Authorize(ctx, cookie)
+	if err != nil {
+		return models.Registration{}, models.Event{}, 0, err
+	}
+
+	registration, event, err := registrationWithEvent(h.db, id)
+	if err != nil {
+		return registration, event, userID, err
+	}
+	if registration.UserID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return registration, event, userID, err
+		}
+		if !isOrg {
+			return registration, event, userID, huma.Error404NotFound("Registration not found")
+		}
+	}
+	return registration, event, userID, nil
+}
+
+func (h *MealHandler) HandleGetMealChoices(ctx context.Context, input *MealChoicesRequest) (*MealChoicesResponse, error) {
+	registration, event, _, err := h.ownRegistration(ctx, input.Cookie, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newMealChoicesResponse(event, registration, time.Now()), nil
+}
+
+func (h *MealHandler) HandleSetMealChoices(ctx context.Context, input *SetMealChoicesRequest) (*MealChoicesResponse, error) {
+	registration, event, userID, err := h.ownRegistration(ctx, input.Cookie, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !registration.Confirmed() {
+		return nil, huma.Error409Conflict("Only confirmed registrations can choose meals")
+	}
+
+	now := time.Now()
+	skipped, locked, err := validateMealChoices(event, registration, input.Body.SkippedMeals, now)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, mealsLockedError(event)
+		}
+	}
+
+	registration.SkippedMeals = skipped
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&registration).Select("SkippedMeals").Updates(&registration).Error; err != nil {
+			return err
+		}
+		_, err := saveHistory(tx, registration)
+		return err
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to save meals: " + err.Error())
+	}
+
+	return newMealChoicesResponse(event, registration, now), nil
+}
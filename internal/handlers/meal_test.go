@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/meals"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMealReport(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "meal-event")
+	arrival := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	register := func(discordID string, fields models.RegistrationFields) {
+		user := models.User{DiscordID: discordID, Username: discordID}
+		db.Create(&user)
+		fields.ArrivalDate = arrival
+		fields.DepartureDate = arrival.AddDate(0, 0, 1)
+		db.Create(&models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: fields})
+	}
+	register("meal-vegan", models.RegistrationFields{DietaryTags: []string{models.DietVegan}, ChildrenCount: 1})
+	register("meal-celery", models.RegistrationFields{FoodRestrictions: "no celery"})
+	register("meal-cancelled", models.RegistrationFields{Cancelled: true})
+
+	report, restrictions, err := mealReport(db, event)
+	if err != nil {
+		t.Fatalf("mealReport failed: %v", err)
+	}
+	if len(report) != 2 || report[0].Total != 3 || report[0].Tags[models.DietVegan] != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(restrictions) != 1 || restrictions[0].FoodRestrictions != "no celery" {
+		t.Errorf("expected the free text restriction, got %+v", restrictions)
+	}
+
+	if _, err := validateDietaryTags([]string{"carnivore"}); err == nil {
+		t.Error("expected unknown dietary tag to be rejected")
+	}
+	if tags, _ := validateDietaryTags([]string{models.DietVegan, models.DietGlutenFree, models.DietVegan}); len(tags) != 2 || tags[0] != models.DietGlutenFree {
+		t.Errorf("expected sorted tags without duplicates, got %v", tags)
+	}
+}
+
+func TestMealChoices(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "meal-choices")
+	event.MealCutoffHours = 48
+	db.Save(&event)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	user := models.User{DiscordID: "meal-owner", Username: "owner"}
+	db.Create(&user)
+	other := models.User{DiscordID: "meal-other", Username: "other"}
+	db.Create(&other)
+	registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+		ArrivalDate:   today.AddDate(0, 0, 2),
+		DepartureDate: today.AddDate(0, 0, 4),
+	}}
+	db.Create(&registration)
+
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	handler := NewMealHandler(db, authHandler)
+	set := func(user models.User, skipped ...models.MealSlot) (*MealChoicesResponse, error) {
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := &SetMealChoicesRequest{ID: registration.ID}
+		req.Cookie = "auth_token=" + token
+		req.Body.SkippedMeals = skipped
+		return handler.HandleSetMealChoices(context.Background(), req)
+	}
+
+	lunch := models.MealSlot{Day: today.AddDate(0, 0, 3).Add(12 * time.Hour), Meal: meals.Lunch}
+	res, err := set(user, lunch, lunch)
+	if err != nil {
+		t.Fatalf("HandleSetMealChoices failed: %v", err)
+	}
+	if len(res.Body.Meals) != 5 || res.Body.Meals[0].Eating != true || !res.Body.Meals[0].Locked || res.Body.Meals[2].Eating {
+		t.Errorf("unexpected meal choices %+v", res.Body.Meals)
+	}
+
+	var saved models.Registration
+	db.First(&saved, registration.ID)
+	if len(saved.SkippedMeals) != 1 || !saved.SkippedMeals[0].Day.Equal(today.AddDate(0, 0, 3)) {
+		t.Errorf("expected the lunch to be skipped once, got %v", saved.SkippedMeals)
+	}
+	var history int64
+	db.Model(&models.RegistrationHistory{}).Where("registration_id = ?", registration.ID).Count(&history)
+	if history != 1 {
+		t.Errorf("expected the change in the history, got %d entries", history)
+	}
+
+	report, _, _ := mealReport(db, event)
+	if len(report) != 4 || report[2].Meal != meals.Dinner {
+		t.Errorf("expected the skipped lunch to be excluded, got %+v", report)
+	}
+
+	if _, err := set(user, lunch, models.MealSlot{Day: today.AddDate(0, 0, 2), Meal: meals.Dinner}); err == nil {
+		t.Error("expected change past the cutoff to be rejected")
+	}
+	if _, err := set(user, models.MealSlot{Day: today.AddDate(0, 0, 2), Meal: meals.Breakfast}); err == nil {
+		t.Error("expected meal outside the stay to be rejected")
+	}
+	if _, err := set(other); err == nil {
+		t.Error("expected other users to be denied")
+	}
+}
+
+func TestHandleRegister_MealCutoff(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "meal-dates")
+	event.MealCutoffHours = 48
+	db.Save(&event)
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+	user := models.User{DiscordID: "meal-dates-user"}
+	db.Create(&user)
+	token, _ := authHandler.GenerateToken(user.ID)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	register := func(arrival int, departure int, cancelled bool) error {
+		req := &RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = today.AddDate(0, 0, arrival)
+		req.Body.DepartureDate = today.AddDate(0, 0, departure)
+		req.Body.Cancelled = cancelled
+		_, err := handler.HandleRegister(context.Background(), req)
+		return err
+	}
+
+	if err := register(2, 5, false); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	// Arriving a day later drops the dinner of the day after tomorrow, which is locked
+	var statusErr huma.StatusError
+	if err := register(3, 5, false); !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusLocked {
+		t.Errorf("expected dropping a locked meal to be rejected, got %v", err)
+	}
+	if err := register(2, 6, false); err != nil {
+		t.Errorf("expected staying longer to only add open meals: %v", err)
+	}
+	if err := register(2, 6, true); err != nil {
+		t.Errorf("expected cancelling to ignore the meal cutoff: %v", err)
+	}
+}
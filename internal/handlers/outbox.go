@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"gorm.io/gorm"
+)
+
+type OutboxHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewOutboxHandler(db *gorm.DB, authHandler *auth.AuthHandler) *OutboxHandler {
+	return &OutboxHandler{db: db, authHandler: authHandler}
+}
+
+type ListOutboxRequest struct {
+	auth.AuthInput
+	Status string `query:"status" enum:"pending,delivered,failed" doc:"Optional status to filter by"`
+	Kind   string `query:"kind" doc:"Optional kind to filter by, e.g. notify_registration"`
+	Limit  int    `query:"limit" default:"100" minimum:"1" maximum:"1000"`
+}
+
+type ListOutboxResponse struct {
+	Body struct {
+		Items []models.OutboxItem `json:"items" doc:"Newest first"`
+	}
+}
+
+type OutboxItemRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Outbox item ID"`
+}
+
+type OutboxItemResponse struct {
+	Body models.OutboxItem
+}
+
+func (h *OutboxHandler) HandleListOutbox(ctx context.Context, input *ListOutboxRequest) (*ListOutboxResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	query := h.db.Order("id DESC")
+	if input.Status != "" {
+		query = query.Where("status = ?", input.Status)
+	}
+	if input.Kind != "" {
+		query = query.Where("kind = ?", input.Kind)
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	res := &ListOutboxResponse{}
+	if err := query.Limit(limit).Find(&res.Body.Items).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch outbox: " + err.Error())
+	}
+	return res, nil
+}
+
+func (h *OutboxHandler) HandleReplayOutboxItem(ctx context.Context, input *OutboxItemRequest) (*OutboxItemResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	var item models.OutboxItem
+	if err := h.db.First(&item, input.ID).Error; err != nil {
+		return nil, huma.Error404NotFound("Outbox item not found")
+	}
+	if item.Status == models.OutboxPending {
+		return nil, huma.Error409Conflict("Outbox item is still pending")
+	}
+	if err := outbox.Replay(h.db, &item); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to replay outbox item: " + err.Error())
+	}
+	return &OutboxItemResponse{Body: item}, nil
+}
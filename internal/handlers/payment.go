@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+type PaymentHandler struct {
+	db          *gorm.DB
+	notifier    notifier.Notifier
+	roles       notifier.RoleManager
+	authHandler *auth.AuthHandler
+}
+
+func NewPaymentHandler(db *gorm.DB, notifier notifier.Notifier, roles notifier.RoleManager, authHandler *auth.AuthHandler) *PaymentHandler {
+	return &PaymentHandler{db: db, notifier: notifier, roles: roles, authHandler: authHandler}
+}
+
+type RecordPaymentRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Body           struct {
+		RegistrationID uint       `json:"registration_id" doc:"Registration the payment belongs to"`
+		Amount         int64      `json:"amount" doc:"Amount in minor currency units" minimum:"1"`
+		Currency       string     `json:"currency,omitempty" doc:"Defaults to the event currency"`
+		Method         string     `json:"method" enum:"bank_transfer,cash,card,other"`
+		Reference      string     `json:"reference,omitempty" doc:"External reference, e.g. bank transaction ID"`
+		Note           string     `json:"note,omitempty"`
+		ReceivedAt     *time.Time `json:"received_at,omitempty" doc:"Defaults to now"`
+	}
+}
+
+type VoidPaymentRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id"`
+	Body           struct {
+		Reason string `json:"reason" doc:"Why the payment is voided"`
+	}
+}
+
+type PaymentResponse struct {
+	Body struct {
+		Payment models.Payment  `json:"payment"`
+		Balance billing.Balance `json:"balance" doc:"Balance of the registration after the change"`
+	}
+}
+
+type PaymentInfoRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Registration ID"`
+}
+
+type PaymentInfoResponse struct {
+	Body struct {
+		IBAN           string          `json:"iban"`
+		Amount         int64           `json:"amount" doc:"Outstanding amount in minor currency units"`
+		Currency       string          `json:"currency"`
+		VariableSymbol string          `json:"variable_symbol"`
+		PaymentString  string          `json:"payment_string" doc:"SPAYD string for Czech QR payments"`
+		Balance        billing.Balance `json:"balance"`
+	}
+}
+
+type PaymentQRResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+type ListPaymentsRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Event          string `query:"event" doc:"Optional event ID to filter by"`
+	RegistrationID uint   `query:"registration_id" doc:"Optional registration ID to filter by"`
+}
+
+type ListPaymentsResponse struct {
+	Body struct {
+		Payments []models.Payment `json:"payments"`
+	}
+}
+
+// registrationWithEvent loads a registration together with its user and event
+func registrationWithEvent(db *gorm.DB, registrationID uint) (models.Registration, models.Event, error) {
+	var registration models.Registration
+	if err := db.Preload("User").First(&registration, registrationID).Error; err != nil {
+		return registration, models.Event{}, huma.Error404NotFound("Registration not found")
+	}
+	event, err := findEvent(db, registration.Event)
+	return registration, event, err
+}
+
+// queuePaidRole grants the paid role once the registration is paid in full and removes it when it no longer is,
+// the change is written to the outbox in the transaction moving the balance
+func queuePaidRole(tx *gorm.DB, roles notifier.RoleManager, event models.Event, registration models.Registration, paidBefore bool, paidAfter bool) error {
+	if roles == nil || paidBefore == paidAfter {
+		return nil
+	}
+	if registration.User.ID == 0 {
+		if err := tx.First(&registration.User, registration.UserID).Error; err != nil {
+			return err
+		}
+	}
+	// Guests get the role once they link Discord
+	if registration.User.DiscordID == "" {
+		return nil
+	}
+	if paidAfter {
+		return outbox.GrantRoleByName(tx, registration.User.DiscordID, event.PaidRole())
+	}
+	return outbox.RemoveRoleByName(tx, registration.User.DiscordID, event.PaidRole())
+}
+
+// paidInFull computes the balance of the registration and reports whether it is paid in full
+func paidInFull(db *gorm.DB, event models.Event, registration models.Registration) (bool, error) {
+	balance, err := billing.RegistrationBalance(db, event, registration)
+	if err != nil {
+		return false, err
+	}
+	return billing.PaidInFull(registration, balance), nil
+}
+
+// trackPaidRole queues the paid role change of a registration whose balance changed within the transaction
+func trackPaidRole(tx *gorm.DB, roles notifier.RoleManager, event models.Event, registration models.Registration, paidBefore bool) error {
+	if roles == nil {
+		return nil
+	}
+	paidAfter, err := paidInFull(tx, event, registration)
+	if err != nil {
+		return err
+	}
+	return queuePaidRole(tx, roles, event, registration, paidBefore, paidAfter)
+}
+
+func (h *PaymentHandler) HandleRecordPayment(ctx context.Context, input *RecordPaymentRequest) (*PaymentResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Body.Amount <= 0 {
+		return nil, huma.Error400BadRequest("Amount must be positive")
+	}
+
+	registration, event, err := registrationWithEvent(h.db, input.Body.RegistrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := strings.ToUpper(input.Body.Currency)
+	if currency == "" {
+		currency = billing.Currency(event)
+	}
+	if currency != billing.Currency(event) {
+		return nil, huma.Error400BadRequest("Event " + event.Code + " is priced in " + billing.Currency(event))
+	}
+
+	payment := models.Payment{
+		RegistrationID: registration.ID,
+		Amount:         input.Body.Amount,
+		Currency:       currency,
+		Method:         input.Body.Method,
+		Reference:      input.Body.Reference,
+		Note:           input.Body.Note,
+		ReceivedAt:     time.Now(),
+		RecordedByID:   org.ID,
+	}
+	if input.Body.ReceivedAt != nil {
+		payment.ReceivedAt = *input.Body.ReceivedAt
+	}
+
+	var after billing.Balance
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		after, err = billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		return queuePaidRole(tx, h.roles, event, registration, billing.PaidInFull(registration, before), billing.PaidInFull(registration, after))
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to record payment: " + err.Error())
+	}
+
+	payment.RecordedBy = org
+	res := &PaymentResponse{}
+	res.Body.Payment = payment
+	res.Body.Balance = after
+	return res, nil
+}
+
+func (h *PaymentHandler) HandleVoidPayment(ctx context.Context, input *VoidPaymentRequest) (*PaymentResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment models.Payment
+	if err := h.db.Preload("RecordedBy").First(&payment, input.ID).Error; err != nil {
+		return nil, huma.Error404NotFound("Payment not found")
+	}
+	if payment.VoidedAt != nil {
+		return nil, huma.Error409Conflict("Payment is already voided")
+	}
+
+	registration, event, err := registrationWithEvent(h.db, payment.RegistrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	payment.VoidedAt = &now
+	payment.VoidedByID = &org.ID
+	payment.VoidReason = input.Body.Reason
+	var after billing.Balance
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		before, err := billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		after, err = billing.RegistrationBalance(tx, event, registration)
+		if err != nil {
+			return err
+		}
+		return queuePaidRole(tx, h.roles, event, registration, billing.PaidInFull(registration, before), billing.PaidInFull(registration, after))
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to void payment: " + err.Error())
+	}
+
+	res := &PaymentResponse{}
+	res.Body.Payment = payment
+	res.Body.Balance = after
+	return res, nil
+}
+
+func (h *PaymentHandler) HandleListPayments(ctx context.Context, input *ListPaymentsRequest) (*ListPaymentsResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	query := h.db.Preload("RecordedBy").Order("received_at DESC")
+	if input.RegistrationID != 0 {
+		query = query.Where("registration_id = ?", input.RegistrationID)
+	}
+	if input.Event != "" {
+		query = query.Where("registration_id IN (?)", h.db.Model(&models.Registration{}).Select("id").Where("event = ?", input.Event))
+	}
+
+	var payments []models.Payment
+	if err := query.Find(&payments).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch payments: " + err.Error())
+	}
+
+	res := &ListPaymentsResponse{}
+	res.Body.Payments = payments
+	return res, nil
+}
+
+// paymentFor authorizes the owner of the registration or an org and builds the payment settling its balance
+func (h *PaymentHandler) paymentFor(ctx context.Context, cookie string, registrationID uint) (billing.SPAYD, billing.Balance, error) {
+	userID, err := h.authHandler.Authorize(ctx, cookie)
+	if err != nil {
+		return billing.SPAYD{}, billing.Balance{}, err
+	}
+
+	if h.authHandler.BankAccount().IBAN == "" {
+		return billing.SPAYD{}, billing.Balance{}, huma.Error503ServiceUnavailable("Bank account for payments is not configured")
+	}
+
+	registration, event, err := registrationWithEvent(h.db, registrationID)
+	if err != nil {
+		return billing.SPAYD{}, billing.Balance{}, err
+	}
+
+	if registration.UserID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return billing.SPAYD{}, billing.Balance{}, err
+		}
+		if !isOrg {
+			return billing.SPAYD{}, billing.Balance{}, huma.Error404NotFound("Registration not found")
+		}
+	}
+
+	balance, err := billing.RegistrationBalance(h.db, event, registration)
+	if err != nil {
+		return billing.SPAYD{}, billing.Balance{}, huma.Error500InternalServerError("Failed to compute balance: " + err.Error())
+	}
+	if balance.Outstanding <= 0 {
+		return billing.SPAYD{}, balance, huma.Error409Conflict("Registration has nothing left to pay")
+	}
+
+	return h.authHandler.BankAccount().PaymentFor(registration, balance), balance, nil
+}
+
+func (h *PaymentHandler) HandlePaymentInfo(ctx context.Context, input *PaymentInfoRequest) (*PaymentInfoResponse, error) {
+	payment, balance, err := h.paymentFor(ctx, input.Cookie, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PaymentInfoResponse{}
+	res.Body.IBAN = payment.IBAN
+	res.Body.Amount = payment.Amount
+	res.Body.Currency = payment.Currency
+	res.Body.VariableSymbol = payment.VariableSymbol
+	res.Body.PaymentString = payment.String()
+	res.Body.Balance = balance
+	return res, nil
+}
+
+func (h *PaymentHandler) HandlePaymentQR(ctx context.Context, input *PaymentInfoRequest) (*PaymentQRResponse, error) {
+	payment, _, err := h.paymentFor(ctx, input.Cookie, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := qrcode.Encode(payment.String(), qrcode.Medium, 512)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to generate QR code: " + err.Error())
+	}
+
+	return &PaymentQRResponse{ContentType: "image/png", Body: png}, nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestQueuePaidRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := models.Event{Code: "ev"}
+	registration := models.Registration{User: models.User{Model: gorm.Model{ID: 1}, DiscordID: "payer"}}
+	guest := models.Registration{User: models.User{Model: gorm.Model{ID: 2}}}
+
+	n := &fakeNotifier{}
+	queuePaidRole(db, n, event, registration, false, true)
+	queuePaidRole(db, n, event, registration, true, true)
+	queuePaidRole(db, nil, event, registration, true, false)
+	queuePaidRole(db, n, event, guest, false, true)
+	n.deliver(t, db)
+	if len(n.grantedRoles) != 1 || n.grantedRoles[0] != "ev::paid" || len(n.removedRoles) != 0 {
+		t.Errorf("expected only the paid role to be granted, granted %v removed %v", n.grantedRoles, n.removedRoles)
+	}
+
+	queuePaidRole(db, n, event, registration, true, false)
+	n.deliver(t, db)
+	if len(n.removedRoles) != 1 || n.removedRoles[0] != "ev::paid" {
+		t.Errorf("expected paid role to be removed, got %v", n.removedRoles)
+	}
+}
+
+func TestPaidInFull_SkipsWaitlistedAndCancelled(t *testing.T) {
+	settled := billing.Balance{Paid: 100, Payments: 1, Settled: true}
+
+	if !billing.PaidInFull(models.Registration{}, settled) {
+		t.Error("expected confirmed registration with settled balance to be paid")
+	}
+	waitlisted := models.Registration{RegistrationFields: models.RegistrationFields{Waitlisted: true}}
+	if billing.PaidInFull(waitlisted, settled) {
+		t.Error("expected waitlisted registration not to be paid")
+	}
+	cancelled := models.Registration{RegistrationFields: models.RegistrationFields{Cancelled: true}}
+	if billing.PaidInFull(cancelled, settled) {
+		t.Error("expected cancelled registration not to be paid")
+	}
+}
+
+func TestPaidRole_FollowsBalanceChanges(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "priced")
+	db.Model(&event).Updates(map[string]interface{}{"capacity_adults": 1, "price_adult_per_night": 1000})
+	db.First(&event, event.ID)
+
+	org := models.User{DiscordID: "priced-org"}
+	first := models.User{DiscordID: "priced-first"}
+	second := models.User{DiscordID: "priced-second"}
+	db.Create(&org)
+	db.Create(&first)
+	db.Create(&second)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	authHandler, orgCookie := orgAuth(t, db, testCfg, org)
+	n := &fakeNotifier{}
+	handler := NewRegistrationHandler(db, n, n, authHandler, testCfg)
+
+	arrival := event.StartDate
+	register := func(t *testing.T, user models.User, nights int, cancelled bool) models.Registration {
+		t.Helper()
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = arrival
+		req.Body.DepartureDate = arrival.Add(time.Duration(nights) * 24 * time.Hour)
+		req.Body.Cancelled = cancelled
+		if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+			t.Fatalf("HandleRegister failed: %v", err)
+		}
+		n.deliver(t, db)
+		var registration models.Registration
+		db.Where("user_id = ?", user.ID).First(&registration)
+		return registration
+	}
+	pay := func(registration models.Registration, amount int64) {
+		db.Create(&models.Payment{RegistrationID: registration.ID, Amount: amount, Currency: models.DefaultCurrency, ReceivedAt: time.Now(), RecordedByID: org.ID})
+	}
+
+	confirmed := register(t, first, 2, false)
+	pay(confirmed, 1000)
+
+	register(t, first, 1, false)
+	if len(n.grantedRoles) != 1 {
+		t.Fatalf("expected shorter stay covered by the payment to grant the paid role, got %v", n.grantedRoles)
+	}
+	register(t, first, 2, false)
+	if len(n.removedRoles) != 1 {
+		t.Fatalf("expected longer stay to remove the paid role, got %v", n.removedRoles)
+	}
+
+	waitlisted := register(t, second, 1, false)
+	if !waitlisted.Waitlisted {
+		t.Fatal("expected second registration to be waitlisted")
+	}
+	pay(waitlisted, 1000)
+	register(t, second, 1, false)
+	if len(n.grantedRoles) != 1 {
+		t.Fatalf("expected waitlisted registration not to get the paid role, got %v", n.grantedRoles)
+	}
+
+	register(t, first, 2, true)
+	if len(n.grantedRoles) != 2 || len(n.removedRoles) != 1 {
+		t.Fatalf("expected promoted registration to get the paid role, granted %v removed %v", n.grantedRoles, n.removedRoles)
+	}
+
+	events := NewEventHandler(db, n, n, authHandler)
+	update := &UpdateEventRequest{Code: event.Code}
+	update.Cookie = orgCookie
+	update.Body = EventBody{Code: event.Code, Title: event.Title, StartDate: event.StartDate, EndDate: event.EndDate, Status: event.Status, CapacityAdults: 1}
+	update.Body.Pricing.AdultPerNight = 2000
+	if _, err := events.HandleUpdateEvent(context.Background(), update); err != nil {
+		t.Fatalf("HandleUpdateEvent failed: %v", err)
+	}
+	n.deliver(t, db)
+	if len(n.removedRoles) != 2 {
+		t.Errorf("expected price increase to remove the paid role, got %v", n.removedRoles)
+	}
+}
+
+func TestHandleMe_LedgerPaid(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "balance-user"}
+	db.Create(&user)
+	event := createTestEvent(t, db, "paid-event")
+	db.Model(&event).Update("price_fixed_fee", 1000)
+
+	registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID}
+	db.Create(&registration)
+	db.Create(&models.Payment{RegistrationID: registration.ID, Amount: 1000, Currency: "CZK"})
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+
+	token, _ := authHandler.GenerateToken(user.ID)
+	req := &auth.MeRequest{Event: event.Code}
+	req.Cookie = "auth_token=" + token
+	resp, err := authHandler.HandleMe(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleMe failed: %v", err)
+	}
+	if !resp.Body.Paid {
+		t.Error("expected registration settled in the ledger to be paid")
+	}
+	if len(resp.Body.Registrations) != 1 || resp.Body.Registrations[0].Balance.Paid != 1000 {
+		t.Errorf("expected balance with 1000 paid, got %+v", resp.Body.Registrations)
+	}
+}
+
+func TestHandlePaymentInfo(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	owner := models.User{DiscordID: "qr-owner", Username: "owner"}
+	other := models.User{DiscordID: "qr-other"}
+	db.Create(&owner)
+	db.Create(&other)
+	event := createTestEvent(t, db, "qr-event")
+	db.Model(&event).Update("price_fixed_fee", 150000)
+
+	registration := models.Registration{UserID: owner.ID, Event: event.Code, EventID: event.ID}
+	db.Create(&registration)
+	db.Create(&models.Payment{RegistrationID: registration.ID, Amount: 50000, Currency: "CZK"})
+
+	testCfg := &config.Config{JWTSecret: "test-secret", PaymentIBAN: "CZ6508000000192000145399"}
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewPaymentHandler(db, nil, nil, authHandler)
+
+	token, _ := authHandler.GenerateToken(owner.ID)
+	req := &PaymentInfoRequest{ID: registration.ID}
+	req.Cookie = "auth_token=" + token
+	resp, err := handler.HandlePaymentInfo(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandlePaymentInfo failed: %v", err)
+	}
+	if resp.Body.Amount != 100000 || resp.Body.VariableSymbol != registration.VariableSymbol() {
+		t.Errorf("unexpected payment info %+v", resp.Body)
+	}
+	if !strings.Contains(resp.Body.PaymentString, "AM:1000.00") || !strings.Contains(resp.Body.PaymentString, "X-VS:"+registration.VariableSymbol()) {
+		t.Errorf("unexpected payment string %s", resp.Body.PaymentString)
+	}
+
+	qr, err := handler.HandlePaymentQR(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandlePaymentQR failed: %v", err)
+	}
+	if qr.ContentType != "image/png" || !bytes.HasPrefix(qr.Body, []byte("\x89PNG")) {
+		t.Error("expected a PNG QR code")
+	}
+
+	// Somebody else's registration is not visible
+	otherToken, _ := authHandler.GenerateToken(other.ID)
+	req.Cookie = "auth_token=" + otherToken
+	if _, err := handler.HandlePaymentInfo(context.Background(), req); err == nil {
+		t.Error("expected error for registration of another user")
+	}
+
+	// Nothing to pay once settled
+	db.Create(&models.Payment{RegistrationID: registration.ID, Amount: 100000, Currency: "CZK"})
+	req.Cookie = "auth_token=" + token
+	if _, err := handler.HandlePaymentInfo(context.Background(), req); err == nil {
+		t.Error("expected error for settled registration")
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandleRegister_PriceHistory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "price-user"}
+	db.Create(&user)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "priced-event")
+	db.Model(&event).Update("price_adult_per_night", 50000)
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	token, _ := authHandler.GenerateToken(user.ID)
+	authCookie := "auth_token=" + token
+
+	// Meals of past days are locked, so the stay must be in the future to change it
+	arrival := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 30)
+	req := RegistrationRequest{}
+	req.Cookie = authCookie
+	req.Body.Event = event.Code
+	req.Body.ArrivalDate = arrival
+	req.Body.DepartureDate = arrival.AddDate(0, 0, 2)
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	req.Body.DepartureDate = arrival.AddDate(0, 0, 3)
+	if _, err := handler.HandleRegister(context.Background(), &req); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+
+	historyReq := HistoryRequest{Diff: true}
+	historyReq.Cookie = authCookie
+	resp, err := handler.HandleHistory(context.Background(), &historyReq)
+	if err != nil {
+		t.Fatalf("HandleHistory failed: %v", err)
+	}
+
+	latest := resp.Body.History[0].RegistrationFields
+	if latest.Price == nil || *latest.Price != 150000 {
+		t.Errorf("expected price change to 150000 in diff, got %v", latest.Price)
+	}
+	oldest := resp.Body.History[1].RegistrationFields
+	if oldest.Price == nil || *oldest.Price != 100000 {
+		t.Errorf("expected initial price 100000, got %v", oldest.Price)
+	}
+
+	meReq := &auth.MeRequest{Event: event.Code}
+	meReq.Cookie = authCookie
+	me, err := authHandler.HandleMe(context.Background(), meReq)
+	if err != nil {
+		t.Fatalf("HandleMe failed: %v", err)
+	}
+	if me.Body.Registrations[0].Quote.Total != 150000 || me.Body.Registrations[0].Quote.Nights != 3 {
+		t.Errorf("unexpected quote in /me: %+v", me.Body.Registrations[0].Quote)
+	}
+}
@@ -2,46 +2,64 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/meals"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"github.com/gdg-garage/garage-trip-api/internal/outbox"
 	"gorm.io/gorm"
 )
 
 type RegistrationHandler struct {
 	db          *gorm.DB
 	notifier    notifier.Notifier
+	roles       notifier.RoleManager
 	authHandler *auth.AuthHandler
 	cfg         *config.Config
 }
 
-func NewRegistrationHandler(db *gorm.DB, notifier notifier.Notifier, authHandler *auth.AuthHandler, cfg *config.Config) *RegistrationHandler {
-	return &RegistrationHandler{db: db, notifier: notifier, authHandler: authHandler, cfg: cfg}
+func NewRegistrationHandler(db *gorm.DB, notifier notifier.Notifier, roles notifier.RoleManager, authHandler *auth.AuthHandler, cfg *config.Config) *RegistrationHandler {
+	return &RegistrationHandler{db: db, notifier: notifier, roles: roles, authHandler: authHandler, cfg: cfg}
 }
 
 type RegistrationRequest struct {
 	auth.AuthInput
 	Body struct {
-		ArrivalDate      time.Time `json:"arrival_date" doc:"Date of arrival"`
-		DepartureDate    time.Time `json:"departure_date" doc:"Date of departure"`
-		FoodRestrictions string    `json:"food_restrictions" doc:"Food restrictions or allergies"`
-		ChildrenCount    int       `json:"children_count" doc:"Number of children joining"`
-		Cancelled        bool      `json:"cancelled" doc:"Whether the registration is cancelled"`
-		Note             string    `json:"note" doc:"Additional notes"`
-		Event            string    `json:"event" doc:"Event ID"`
+		ArrivalDate      time.Time          `json:"arrival_date" doc:"Date of arrival"`
+		DepartureDate    time.Time          `json:"departure_date" doc:"Date of departure"`
+		FoodRestrictions string             `json:"food_restrictions" doc:"Food restrictions or allergies"`
+		DietaryTags      []string           `json:"dietary_tags,omitempty" enum:"vegetarian,vegan,gluten_free,lactose_free,nut_allergy" doc:"Dietary needs of the attendee and their children, use food_restrictions for anything else"`
+		ChildrenCount    int                `json:"children_count" doc:"Number of children joining, prefer listing them as companions"`
+		Companions       []models.Companion `json:"companions,omitempty" doc:"Partners, friends and children without their own account joining on this registration"`
+		Cancelled        bool               `json:"cancelled" doc:"Whether the registration is cancelled"`
+		Note             string             `json:"note" doc:"Additional notes"`
+		Event            string             `json:"event" doc:"Event ID"`
+		Answers          models.Answers     `json:"answers,omitempty" doc:"Answers to the event questions keyed by question key"`
+		Roommates        []string           `json:"roommates,omitempty" doc:"Usernames of preferred roommates"`
+		QuietRoom        bool               `json:"quiet_room,omitempty" doc:"Prefers a quiet room"`
+		NoStairs         bool               `json:"no_stairs,omitempty" doc:"Needs a room reachable without stairs"`
 	}
 }
 
 type RegistrationResponse struct {
 	Body struct {
-		Message string `json:"message"`
+		Message    string `json:"message"`
+		Waitlisted bool   `json:"waitlisted" doc:"Whether the registration is waiting for a free spot"`
 	}
 }
 
+var errCapacityExceeded = errors.New("capacity exceeded")
+
 func (h *RegistrationHandler) HandleRegister(ctx context.Context, input *RegistrationRequest) (*RegistrationResponse, error) {
 	// Get UserID
 	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
@@ -59,32 +77,141 @@ func (h *RegistrationHandler) HandleRegister(ctx context.Context, input *Registr
 	}
 
 	// Validate event
-	eventEnabled := false
-	for _, e := range h.cfg.EnabledEvents {
-		if e == input.Body.Event {
-			eventEnabled = true
-			break
+	var event models.Event
+	if err := h.db.Where("code = ?", input.Body.Event).First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, huma.Error400BadRequest("Event " + input.Body.Event + " does not exist")
 		}
+		return nil, huma.Error500InternalServerError("Failed to fetch event: " + err.Error())
+	}
+	if err := guestInvitedTo(h.db, userID, event); err != nil {
+		return nil, err
+	}
+
+	var existing *models.Registration
+	var current models.Registration
+	result := h.db.Where("user_id = ? AND event = ?", userID, event.Code).Limit(1).Find(&current)
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch registration: " + result.Error.Error())
 	}
-	if !eventEnabled {
-		return nil, huma.Error400BadRequest("Event " + input.Body.Event + " is not enabled for registration")
+	if result.RowsAffected > 0 {
+		existing = &current
+	}
+
+	cancelOnly, err := h.checkDeadlines(userID, event, existing, input.Body.Cancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moving the stay of a confirmed registration changes its meals, those past the cutoff are locked
+	if existing != nil && existing.Confirmed() && !input.Body.Cancelled && !cancelOnly {
+		if stayMealsLocked(event, *existing, input.Body.ArrivalDate, input.Body.DepartureDate, time.Now()) {
+			isOrg, err := h.authHandler.IsOrg(userID)
+			if err != nil {
+				return nil, err
+			}
+			if !isOrg {
+				return nil, mealsLockedError(event)
+			}
+		}
+	}
+
+	// Validate answers to the event questions, cancellations keep the stored ones
+	var answers models.Answers
+	if existing != nil {
+		answers = existing.Answers
+	}
+	if !input.Body.Cancelled {
+		questions, err := eventQuestions(h.db, event.ID)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch questions: " + err.Error())
+		}
+		answers, err = validateAnswers(questions, input.Body.Answers)
+		if err != nil {
+			return nil, huma.Error400BadRequest("Invalid answers: " + err.Error())
+		}
+	}
+
+	dietaryTags, err := validateDietaryTags(input.Body.DietaryTags)
+	if err != nil {
+		return nil, err
+	}
+	companions, err := validateCompanions(input.Body.Companions)
+	if err != nil {
+		return nil, err
 	}
 
 	var registration models.Registration
 	err = h.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_id = ? AND event = ?", userID, input.Body.Event).FirstOrInit(&registration).Error; err != nil {
+		if err := tx.Where("user_id = ? AND event = ?", userID, event.Code).FirstOrInit(&registration).Error; err != nil {
 			return err
 		}
+		// Dates, companions and the waitlist change the balance, the paid role follows it
+		paidBefore := false
+		if registration.ID != 0 {
+			var err error
+			paidBefore, err = paidInFull(tx, event, registration)
+			if err != nil {
+				return err
+			}
+		}
+		wasConfirmed := registration.ID != 0 && registration.Confirmed()
+		wasWaitlisted := registration.ID != 0 && registration.Waitlisted && !registration.Cancelled
+		// Meal choices are changed separately, keep those still within the stay
+		skippedMeals := meals.Within(registration.SkippedMeals, input.Body.ArrivalDate, input.Body.DepartureDate)
+
 		registration.UserID = userID
-		registration.Event = input.Body.Event
+		registration.Event = event.Code
+		registration.EventID = event.ID
 
 		registration.RegistrationFields = models.RegistrationFields{
 			ArrivalDate:      input.Body.ArrivalDate,
 			DepartureDate:    input.Body.DepartureDate,
 			FoodRestrictions: input.Body.FoodRestrictions,
+			DietaryTags:      dietaryTags,
 			ChildrenCount:    input.Body.ChildrenCount,
 			Cancelled:        input.Body.Cancelled,
+			Waitlisted:       wasWaitlisted,
 			Note:             input.Body.Note,
+			Answers:          answers,
+			Roommates:        input.Body.Roommates,
+			QuietRoom:        input.Body.QuietRoom,
+			NoStairs:         input.Body.NoStairs,
+			SkippedMeals:     skippedMeals,
+			Companions:       companions,
+		}
+		if cancelOnly {
+			registration.RegistrationFields = current.RegistrationFields
+			registration.Cancelled = true
+		}
+		registration.Price = billing.Price(event, registration).Total
+
+		// Capacity check
+		if registration.Cancelled {
+			registration.Waitlisted = false
+			registration.WaitlistedAt = nil
+		} else {
+			fits, err := fitsEvent(tx, event, registration)
+			if err != nil {
+				return err
+			}
+			if wasConfirmed {
+				if !fits {
+					return errCapacityExceeded
+				}
+			} else {
+				ahead, err := waitlistAhead(tx, event, registration)
+				if err != nil {
+					return err
+				}
+				registration.Waitlisted = !fits || ahead
+				if !registration.Waitlisted {
+					registration.WaitlistedAt = nil
+				} else if registration.WaitlistedAt == nil {
+					now := time.Now()
+					registration.WaitlistedAt = &now
+				}
+			}
 		}
 
 		if err := tx.Save(&registration).Error; err != nil {
@@ -92,38 +219,205 @@ func (h *RegistrationHandler) HandleRegister(ctx context.Context, input *Registr
 		}
 
 		// Save history snapshot
-		history := models.RegistrationHistory{
-			RegistrationID:     registration.ID,
-			UserID:             registration.UserID,
-			Event:              registration.Event,
-			RegistrationFields: registration.RegistrationFields,
-			Model:              gorm.Model{CreatedAt: time.Now()}, // Ensure CreatedAt is set
+		history, err := saveHistory(tx, registration)
+		if err != nil {
+			return err
+		}
+		if err := trackPaidRole(tx, h.roles, event, registration, paidBefore); err != nil {
+			return err
 		}
 
-		if err := tx.Create(&history).Error; err != nil {
+		// More people or nights must still fit the assigned rooms
+		if err := checkRegistrationRooms(tx, registration); err != nil {
 			return err
 		}
 
-		return nil
+		// Cancelled attendees leave their carpools, shifts and sessions
+		var released carpoolRelease
+		if registration.Cancelled {
+			released, err = releaseCarpool(tx, registration)
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("registration_id = ?", registration.ID).Delete(&models.ShiftSignup{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("registration_id = ?", registration.ID).Delete(&models.SessionSignup{}).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := releaseShiftsOutsideStay(tx, registration); err != nil {
+				return err
+			}
+			if err := releaseSessionsOutsideStay(tx, registration); err != nil {
+				return err
+			}
+		}
+
+		// Free spots go to the waitlist
+		var promoted []models.Registration
+		if wasConfirmed {
+			var err error
+			promoted, err = promoteWaitlisted(tx, event)
+			if err != nil {
+				return err
+			}
+			// Payments made while waitlisted count once the spot is confirmed
+			for _, p := range promoted {
+				if err := trackPaidRole(tx, h.roles, event, p, false); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Notifications are delivered by the outbox worker once the change is committed
+		if h.notifier == nil {
+			return nil
+		}
+		if err := released.enqueue(tx); err != nil {
+			return err
+		}
+		return enqueueRegistrationNotifications(tx, history, promoted)
 	})
 
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Failed to process registration: " + err.Error())
-	}
-
-	// Fetch user for notification
-	var user models.User
-	if err := h.db.First(&user, userID).Error; err == nil {
-		if h.notifier != nil {
-			_ = h.notifier.NotifyRegistration(user, registration)
+		if errors.Is(err, errCapacityExceeded) {
+			return nil, huma.Error409Conflict("Event " + event.Code + " does not have enough capacity for this change")
 		}
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, statusErr
+		}
+		return nil, huma.Error500InternalServerError("Failed to process registration: " + err.Error())
 	}
 
 	res := &RegistrationResponse{}
+	res.Body.Waitlisted = registration.Waitlisted
 	res.Body.Message = "Registration processed successfully"
+	if registration.Waitlisted {
+		res.Body.Message = "Event is full, registration was added to the waitlist"
+	}
 	return res, nil
 }
 
+// validateDietaryTags rejects unknown tags and returns the tags sorted without duplicates
+func validateDietaryTags(tags []string) ([]string, error) {
+	var valid []string
+	for _, tag := range tags {
+		if !slices.Contains(models.DietaryTags, tag) {
+			return nil, huma.Error400BadRequest("Unknown dietary tag " + tag)
+		}
+		if !slices.Contains(valid, tag) {
+			valid = append(valid, tag)
+		}
+	}
+	slices.Sort(valid)
+	return valid, nil
+}
+
+// validateCompanions checks the companions and returns them with trimmed names and validated dietary tags
+func validateCompanions(companions []models.Companion) ([]models.Companion, error) {
+	valid := make([]models.Companion, 0, len(companions))
+	var names []string
+	for _, c := range companions {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, huma.Error400BadRequest("Companion name is required")
+		}
+		name := strings.ToLower(c.Name)
+		if slices.Contains(names, name) {
+			return nil, huma.Error400BadRequest("Companion " + c.Name + " is listed more than once")
+		}
+		names = append(names, name)
+		if c.Age != nil && *c.Age < 0 {
+			return nil, huma.Error400BadRequest("Age of companion " + c.Name + " cannot be negative")
+		}
+
+		switch c.Type {
+		case models.CompanionAdult:
+			if c.Age != nil && *c.Age < models.AdultAge {
+				return nil, huma.Error400BadRequest(fmt.Sprintf("Companion %s is younger than %d, add them as a child", c.Name, models.AdultAge))
+			}
+		case models.CompanionChild:
+			if c.Age == nil {
+				return nil, huma.Error400BadRequest("Age of child companion " + c.Name + " is required")
+			}
+			if *c.Age >= models.AdultAge {
+				return nil, huma.Error400BadRequest(fmt.Sprintf("Companion %s is %d or older, add them as an adult", c.Name, models.AdultAge))
+			}
+		default:
+			return nil, huma.Error400BadRequest("Unknown type of companion " + c.Name)
+		}
+
+		tags, err := validateDietaryTags(c.DietaryTags)
+		if err != nil {
+			return nil, err
+		}
+		c.DietaryTags = tags
+		valid = append(valid, c)
+	}
+	return valid, nil
+}
+
+// checkDeadlines enforces the registration deadline and the change freeze of the event,
+// orgs may override both. It reports whether only the cancellation from the request may
+// be applied because changes are frozen.
+func (h *RegistrationHandler) checkDeadlines(callerID uint, event models.Event, existing *models.Registration, cancel bool) (bool, error) {
+	now := time.Now()
+	active := existing != nil && !existing.Cancelled
+
+	if event.Status != models.EventStatusOpen {
+		// Attendees can still cancel once the event stops taking registrations
+		if active && cancel {
+			return true, nil
+		}
+		return false, huma.Error400BadRequest("Event " + event.Code + " is not open for registration")
+	}
+	cancelOnly := false
+	var deadlineErr error
+
+	switch {
+	case !active && !event.RegistrationOpen(now):
+		if event.RegistrationClosed(now) {
+			deadlineErr = huma.Error409Conflict(fmt.Sprintf("Registration for %s closed at %s", event.Code, event.RegistrationClosesAt.Format(time.RFC3339)))
+		} else {
+			deadlineErr = huma.Error400BadRequest(fmt.Sprintf("Registration for %s opens at %s", event.Code, event.RegistrationOpensAt.Format(time.RFC3339)))
+		}
+	case active && event.ChangesFrozen(now):
+		if cancel {
+			cancelOnly = true
+		} else {
+			deadlineErr = huma.NewError(http.StatusLocked, fmt.Sprintf("Changes to %s registrations are frozen since %s, you can only cancel your registration", event.Code, event.ChangesFrozenAt.Format(time.RFC3339)))
+		}
+	}
+
+	if deadlineErr == nil && !cancelOnly {
+		return false, nil
+	}
+
+	isOrg, err := h.authHandler.IsOrg(callerID)
+	if err != nil {
+		return false, err
+	}
+	if isOrg {
+		return false, nil
+	}
+	return cancelOnly, deadlineErr
+}
+
+// enqueueRegistrationNotifications announces the registration change and lets promoted attendees know they got a spot
+func enqueueRegistrationNotifications(tx *gorm.DB, history models.RegistrationHistory, promoted []models.Registration) error {
+	if err := outbox.NotifyRegistration(tx, history.ID); err != nil {
+		return err
+	}
+	for _, p := range promoted {
+		if err := outbox.NotifyWaitlistPromotion(tx, p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type HistoryRequest struct {
 	auth.AuthInput
 	Event string `query:"event" doc:"Optional event ID to filter by"`
@@ -131,12 +425,59 @@ type HistoryRequest struct {
 }
 
 type RegistrationFieldsResponse struct {
-	ArrivalDate      *time.Time `json:"arrival_date,omitempty"`
-	DepartureDate    *time.Time `json:"departure_date,omitempty"`
-	FoodRestrictions *string    `json:"food_restrictions,omitempty"`
-	ChildrenCount    *int       `json:"children_count,omitempty"`
-	Cancelled        *bool      `json:"cancelled,omitempty"`
-	Note             *string    `json:"note,omitempty"`
+	ArrivalDate      *time.Time          `json:"arrival_date,omitempty"`
+	DepartureDate    *time.Time          `json:"departure_date,omitempty"`
+	FoodRestrictions *string             `json:"food_restrictions,omitempty"`
+	DietaryTags      *[]string           `json:"dietary_tags,omitempty"`
+	ChildrenCount    *int                `json:"children_count,omitempty"`
+	Cancelled        *bool               `json:"cancelled,omitempty"`
+	Waitlisted       *bool               `json:"waitlisted,omitempty"`
+	Note             *string             `json:"note,omitempty"`
+	Answers          *models.Answers     `json:"answers,omitempty"`
+	Price            *int64              `json:"price,omitempty"`
+	Roommates        *[]string           `json:"roommates,omitempty"`
+	QuietRoom        *bool               `json:"quiet_room,omitempty"`
+	NoStairs         *bool               `json:"no_stairs,omitempty"`
+	SkippedMeals     *[]models.MealSlot  `json:"skipped_meals,omitempty"`
+	Companions       *[]models.Companion `json:"companions,omitempty"`
+	CompanionChanges []CompanionChange   `json:"companion_changes,omitempty" doc:"Companions added, removed or updated compared to the previous entry"`
+}
+
+const (
+	CompanionAdded   = "added"
+	CompanionRemoved = "removed"
+	CompanionUpdated = "updated"
+)
+
+type CompanionChange struct {
+	Name      string            `json:"name"`
+	Change    string            `json:"change" enum:"added,removed,updated"`
+	Companion *models.Companion `json:"companion,omitempty" doc:"Companion after the change, missing for removed ones"`
+}
+
+func companionEqual(a models.Companion, b models.Companion) bool {
+	sameAge := (a.Age == nil && b.Age == nil) || (a.Age != nil && b.Age != nil && *a.Age == *b.Age)
+	return a.Name == b.Name && a.Type == b.Type && sameAge && slices.Equal(a.DietaryTags, b.DietaryTags)
+}
+
+// companionChanges lists the per-companion differences, companions are matched by name
+func companionChanges(current []models.Companion, previous []models.Companion) []CompanionChange {
+	var changes []CompanionChange
+	for i, c := range current {
+		j := slices.IndexFunc(previous, func(p models.Companion) bool { return strings.EqualFold(p.Name, c.Name) })
+		switch {
+		case j < 0:
+			changes = append(changes, CompanionChange{Name: c.Name, Change: CompanionAdded, Companion: &current[i]})
+		case !companionEqual(c, previous[j]):
+			changes = append(changes, CompanionChange{Name: c.Name, Change: CompanionUpdated, Companion: &current[i]})
+		}
+	}
+	for _, p := range previous {
+		if !slices.ContainsFunc(current, func(c models.Companion) bool { return strings.EqualFold(c.Name, p.Name) }) {
+			changes = append(changes, CompanionChange{Name: p.Name, Change: CompanionRemoved})
+		}
+	}
+	return changes
 }
 
 type RegistrationHistoryResponseItem struct {
@@ -193,9 +534,18 @@ func (h *RegistrationHandler) HandleHistory(ctx context.Context, input *HistoryR
 				ArrivalDate:      &history[i].ArrivalDate,
 				DepartureDate:    &history[i].DepartureDate,
 				FoodRestrictions: &history[i].FoodRestrictions,
+				DietaryTags:      &history[i].DietaryTags,
 				ChildrenCount:    &history[i].ChildrenCount,
 				Cancelled:        &history[i].Cancelled,
+				Waitlisted:       &history[i].Waitlisted,
 				Note:             &history[i].Note,
+				Answers:          &history[i].Answers,
+				Price:            &history[i].Price,
+				Roommates:        &history[i].Roommates,
+				QuietRoom:        &history[i].QuietRoom,
+				NoStairs:         &history[i].NoStairs,
+				SkippedMeals:     &history[i].SkippedMeals,
+				Companions:       &history[i].Companions,
 			}
 		} else {
 			// Compare with previous item (which is next in the list since we ordered DESC)
@@ -211,15 +561,43 @@ func (h *RegistrationHandler) HandleHistory(ctx context.Context, input *HistoryR
 			if item.FoodRestrictions != prev.FoodRestrictions {
 				fields.FoodRestrictions = &history[i].FoodRestrictions
 			}
+			if !slices.Equal(item.DietaryTags, prev.DietaryTags) {
+				fields.DietaryTags = &history[i].DietaryTags
+			}
 			if item.ChildrenCount != prev.ChildrenCount {
 				fields.ChildrenCount = &history[i].ChildrenCount
 			}
 			if item.Cancelled != prev.Cancelled {
 				fields.Cancelled = &history[i].Cancelled
 			}
+			if item.Waitlisted != prev.Waitlisted {
+				fields.Waitlisted = &history[i].Waitlisted
+			}
 			if item.Note != prev.Note {
 				fields.Note = &history[i].Note
 			}
+			if !answersEqual(item.Answers, prev.Answers) {
+				fields.Answers = &history[i].Answers
+			}
+			if item.Price != prev.Price {
+				fields.Price = &history[i].Price
+			}
+			if !slices.Equal(item.Roommates, prev.Roommates) {
+				fields.Roommates = &history[i].Roommates
+			}
+			if item.QuietRoom != prev.QuietRoom {
+				fields.QuietRoom = &history[i].QuietRoom
+			}
+			if item.NoStairs != prev.NoStairs {
+				fields.NoStairs = &history[i].NoStairs
+			}
+			if len(meals.Changed(item.SkippedMeals, prev.SkippedMeals)) > 0 {
+				fields.SkippedMeals = &history[i].SkippedMeals
+			}
+			if changes := companionChanges(history[i].Companions, prev.Companions); len(changes) > 0 {
+				fields.Companions = &history[i].Companions
+				fields.CompanionChanges = changes
+			}
 			respItem.RegistrationFields = fields
 		}
 		responseItems = append(responseItems, respItem)
@@ -237,7 +615,10 @@ type ListRegistrationsRequest struct {
 
 type RegistrationListItem struct {
 	models.Registration
-	Paid bool `json:"paid"`
+	Guest   bool            `json:"guest" doc:"Registered through an invite without a Discord account"`
+	Paid    bool            `json:"paid"`
+	Balance billing.Balance `json:"balance"`
+	Quote   billing.Quote   `json:"quote" doc:"Computed price with its breakdown"`
 }
 
 type ListRegistrationsResponse struct {
@@ -270,7 +651,7 @@ func (h *RegistrationHandler) HandleListRegistrations(ctx context.Context, input
 
 	// 4. Fetch all registrations
 	var registrations []models.Registration
-	query := h.db.Preload("User")
+	query := h.db.Preload("User").Preload("CheckedInBy")
 
 	if input.Event != "" {
 		query = query.Where("event = ?", input.Event)
@@ -280,11 +661,33 @@ func (h *RegistrationHandler) HandleListRegistrations(ctx context.Context, input
 		return nil, huma.Error500InternalServerError("Failed to fetch registrations: " + err.Error())
 	}
 
+	var events []models.Event
+	if err := h.db.Find(&events).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch events: " + err.Error())
+	}
+	eventsByCode := make(map[string]models.Event, len(events))
+	for _, e := range events {
+		eventsByCode[e.Code] = e
+	}
+
+	balances, err := billing.Balances(h.db, eventsByCode, registrations)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to compute balances: " + err.Error())
+	}
+
+	// Registrations without payments fall back to the paid role, load all members at once instead of one per row
+	if len(registrations) > 0 {
+		h.authHandler.PrefetchMembers()
+	}
+
 	resItems := make([]RegistrationListItem, len(registrations))
 	for i, reg := range registrations {
 		resItems[i] = RegistrationListItem{
 			Registration: reg,
-			Paid:         h.authHandler.IsPaid(reg.User.DiscordID, reg.Event),
+			Guest:        reg.User.Guest,
+			Paid:         h.authHandler.PaidStatus(reg, eventsByCode[reg.Event], balances[reg.ID]),
+			Balance:      balances[reg.ID],
+			Quote:        billing.Price(eventsByCode[reg.Event], reg),
 		}
 	}
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -19,15 +20,16 @@ func TestHandleHistory_Diff(t *testing.T) {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	// Create user
 	user := models.User{DiscordID: "diff-user"}
 	db.Create(&user)
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"ev1"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "ev1")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	token, _ := authHandler.GenerateToken(user.ID)
 	authCookie := "auth_token=" + token
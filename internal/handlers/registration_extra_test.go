@@ -6,6 +6,7 @@ import (
 
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -18,15 +19,17 @@ func TestHandleRegister_SeparateEvents(t *testing.T) {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	// Create a dummy user
 	user := models.User{DiscordID: "123456789"}
 	db.Create(&user)
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"Event-A", "Event-B"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "Event-A")
+	createTestEvent(t, db, "Event-B")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	token, _ := authHandler.GenerateToken(user.ID)
 	authCookie := "auth_token=" + token
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandleListRegistrations_CachedRoles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "big-trip")
+	guild := &fakeGuild{
+		roles:   []*discordgo.Role{{ID: "org-role", Name: "orgs"}, {ID: "paid-role", Name: event.PaidRole()}},
+		members: map[string][]string{"org": {"org-role"}},
+	}
+	org := models.User{DiscordID: "org", Username: "org"}
+	db.Create(&org)
+	for i := 0; i < 100; i++ {
+		user := models.User{DiscordID: fmt.Sprintf("member-%d", i), Username: fmt.Sprintf("member-%d", i)}
+		db.Create(&user)
+		db.Create(&models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID})
+		if i%2 == 0 {
+			guild.members[user.DiscordID] = []string{"paid-role"}
+		}
+	}
+
+	cfg := &config.Config{JWTSecret: "test-secret", DiscordGuildID: "guild", OrgRole: "orgs"}
+	authHandler := auth.NewAuthHandler(cfg, db, guildcache.NewCache(guild, cfg.DiscordGuildID, time.Minute))
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, cfg)
+
+	cookie, err := authHandler.SessionCookie(org.ID)
+	if err != nil {
+		t.Fatalf("SessionCookie failed: %v", err)
+	}
+	req := &ListRegistrationsRequest{Event: event.Code}
+	req.Cookie = strings.Split(cookie, ";")[0]
+
+	res, err := handler.HandleListRegistrations(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleListRegistrations failed: %v", err)
+	}
+	if len(res.Body.Registrations) != 100 {
+		t.Fatalf("expected 100 registrations, got %d", len(res.Body.Registrations))
+	}
+	paid := 0
+	for _, item := range res.Body.Registrations {
+		if item.Paid {
+			paid++
+		}
+	}
+	if paid != 50 {
+		t.Errorf("expected 50 registrations paid by role, got %d", paid)
+	}
+	// The roles, the org and the member list
+	if guild.calls > 3 {
+		t.Errorf("expected at most 3 Discord calls, got %d", guild.calls)
+	}
+
+	if _, err := handler.HandleListRegistrations(context.Background(), req); err != nil {
+		t.Fatalf("HandleListRegistrations failed: %v", err)
+	}
+	if guild.calls > 3 {
+		t.Errorf("expected the second listing to be served from the cache, got %d calls", guild.calls)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -19,7 +20,7 @@ func TestHandleRegister_MultiUser(t *testing.T) {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	// Create two users
 	user1 := models.User{DiscordID: "user1", Username: "user1"}
@@ -33,9 +34,11 @@ func TestHandleRegister_MultiUser(t *testing.T) {
 
 	t.Logf("User1 ID: %d, User2 ID: %d", user1.ID, user2.ID)
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"event-1", "event-2"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "event-1")
+	createTestEvent(t, db, "event-2")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	// User 1 registers
 	arrival := time.Now().Add(24 * time.Hour)
@@ -117,15 +120,16 @@ func TestHandleRegister_ZeroUserID(t *testing.T) {
 	// Use a unique name for each test or just don't share cache
 	db, _ = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	// Create a user with ID 1
 	user1 := models.User{DiscordID: "user1", Username: "user1"}
 	db.Create(&user1) // Should get ID 1
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"event-1"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "event-1")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	// Register for user 1
 	req1 := RegistrationRequest{}
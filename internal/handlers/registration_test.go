@@ -7,6 +7,7 @@ import (
 
 	"github.com/gdg-garage/garage-trip-api/internal/auth"
 	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -19,15 +20,17 @@ func TestHandleRegister(t *testing.T) {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	// Create a dummy user
 	user := models.User{DiscordID: "123456789"}
 	db.Create(&user)
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"test-event-1", "g::t::7.0.0"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "test-event-1")
+	createTestEvent(t, db, "g::t::7.0.0")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	arrival := time.Now().Add(24 * time.Hour)
 	departure := time.Now().Add(48 * time.Hour)
@@ -145,14 +148,15 @@ func TestHandleRegister_EnabledEvents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to connect database: %v", err)
 	}
-	db.AutoMigrate(&models.Registration{}, &models.User{}, &models.RegistrationHistory{})
+	database.Migrate(db)
 
 	user := models.User{DiscordID: "test-user"}
 	db.Create(&user)
 
-	testCfg := &config.Config{JWTSecret: "test-secret", EnabledEvents: []string{"enabled-event"}}
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	createTestEvent(t, db, "enabled-event")
 	authHandler := auth.NewAuthHandler(testCfg, db, nil)
-	handler := NewRegistrationHandler(db, nil, authHandler, testCfg)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
 
 	token, _ := authHandler.GenerateToken(user.ID)
 	authCookie := "auth_token=" + token
@@ -172,4 +176,14 @@ func TestHandleRegister_EnabledEvents(t *testing.T) {
 	if _, err := handler.HandleRegister(context.Background(), &reqDisabled); err == nil {
 		t.Error("Expected error for disabled event, got nil")
 	}
+
+	// Test case 3: Closed event
+	closed := createTestEvent(t, db, "closed-event")
+	db.Model(&closed).Update("status", models.EventStatusClosed)
+	reqClosed := RegistrationRequest{}
+	reqClosed.Cookie = authCookie
+	reqClosed.Body.Event = "closed-event"
+	if _, err := handler.HandleRegister(context.Background(), &reqClosed); err == nil {
+		t.Error("Expected error for closed event, got nil")
+	}
 }
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/lodging"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type RoomHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewRoomHandler(db *gorm.DB, authHandler *auth.AuthHandler) *RoomHandler {
+	return &RoomHandler{db: db, authHandler: authHandler}
+}
+
+type RoomBody struct {
+	Name   string `json:"name" doc:"Unique room name within the event"`
+	Beds   int    `json:"beds" minimum:"1" doc:"Number of people the room sleeps, children included"`
+	Quiet  bool   `json:"quiet,omitempty" doc:"Suitable for attendees asking for a quiet room"`
+	Stairs bool   `json:"stairs,omitempty" doc:"Reaching the room requires climbing stairs"`
+	Note   string `json:"note,omitempty"`
+}
+
+type CreateRoomRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           RoomBody
+}
+
+type UpdateRoomRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Room ID"`
+	Body           RoomBody
+}
+
+type DeleteRoomRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Room ID"`
+}
+
+type ListRoomsRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+}
+
+type AssignRoomRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Room ID"`
+	Body           struct {
+		RegistrationID uint       `json:"registration_id"`
+		FromDate       *time.Time `json:"from_date,omitempty" doc:"Day of the first night, defaults to the arrival date"`
+		ToDate         *time.Time `json:"to_date,omitempty" doc:"Day of leaving the room, defaults to the departure date"`
+	}
+}
+
+type UnassignRoomRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Room ID"`
+	AssignmentID   uint `path:"assignment_id" doc:"Room assignment ID"`
+}
+
+type RoomAssignmentItem struct {
+	models.RoomAssignment
+	Username string `json:"username"`
+	People   int    `json:"people" doc:"Adults and children sleeping in the room"`
+}
+
+type NightOccupancy struct {
+	Night  time.Time `json:"night" doc:"Day the night starts"`
+	People int       `json:"people"`
+	Free   int       `json:"free" doc:"Free beds, negative when overbooked"`
+}
+
+type RoomOverview struct {
+	models.Room
+	Assignments []RoomAssignmentItem `json:"assignments"`
+	Occupancy   []NightOccupancy     `json:"occupancy" doc:"Occupancy of each night of the event"`
+}
+
+type RoomResponse struct {
+	Body models.Room
+}
+
+type RoomAssignmentResponse struct {
+	Body models.RoomAssignment
+}
+
+type ListRoomsResponse struct {
+	Body struct {
+		Rooms []RoomOverview `json:"rooms"`
+	}
+}
+
+// roomStays loads what the room's assignments occupy, leaving out the assignment with excludeID
+func roomStays(tx *gorm.DB, roomID uint, excludeID uint) ([]lodging.Stay, error) {
+	var assignments []models.RoomAssignment
+	if err := tx.Preload("Registration").Where("room_id = ? AND id <> ?", roomID, excludeID).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	stays := make([]lodging.Stay, len(assignments))
+	for i, a := range assignments {
+		stays[i] = lodging.AssignmentStay(a)
+	}
+	return stays, nil
+}
+
+// checkRoomCapacity rejects the stays when they overbook the room on any night
+func checkRoomCapacity(room models.Room, stays []lodging.Stay) error {
+	if night, people, overbooked := lodging.Overbooked(room.Beds, stays); overbooked {
+		return huma.Error409Conflict(fmt.Sprintf("Room %s has %d beds but %d people would sleep there on %s", room.Name, room.Beds, people, night.Format("2006-01-02")))
+	}
+	return nil
+}
+
+// checkRegistrationRooms rejects a saved change of the registration's stay or headcount
+// which overbooks a room it is assigned to
+func checkRegistrationRooms(tx *gorm.DB, registration models.Registration) error {
+	if !registration.Confirmed() {
+		return nil
+	}
+
+	var assignments []models.RoomAssignment
+	if err := tx.Preload("Room").Where("registration_id = ?", registration.ID).Find(&assignments).Error; err != nil {
+		return err
+	}
+	checked := make(map[uint]bool)
+	for _, a := range assignments {
+		if checked[a.RoomID] {
+			continue
+		}
+		checked[a.RoomID] = true
+
+		stays, err := roomStays(tx, a.RoomID, 0)
+		if err != nil {
+			return err
+		}
+		if err := checkRoomCapacity(a.Room, stays); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventNights lists the nights of the event, falling back to the nights occupied in the room
+func eventNights(event models.Event, occupancy map[time.Time]int) []time.Time {
+	nights := lodging.Nights(event.StartDate, event.EndDate)
+	known := make(map[time.Time]bool, len(nights))
+	for _, n := range nights {
+		known[n] = true
+	}
+	for n := range occupancy {
+		if !known[n] {
+			nights = append(nights, n)
+		}
+	}
+	slices.SortFunc(nights, time.Time.Compare)
+	return nights
+}
+
+func findRoom(db *gorm.DB, id uint) (models.Room, error) {
+	var room models.Room
+	if err := db.First(&room, id).Error; err != nil {
+		return room, huma.Error404NotFound("Room not found")
+	}
+	return room, nil
+}
+
+func (h *RoomHandler) HandleCreateRoom(ctx context.Context, input *CreateRoomRequest) (*RoomResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if input.Body.Name == "" || input.Body.Beds < 1 {
+		return nil, huma.Error400BadRequest("Room name and at least one bed are required")
+	}
+
+	var existing int64
+	if err := h.db.Model(&models.Room{}).Where("event_id = ? AND name = ?", event.ID, input.Body.Name).Count(&existing).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch rooms: " + err.Error())
+	}
+	if existing > 0 {
+		return nil, huma.Error409Conflict("Room " + input.Body.Name + " already exists")
+	}
+
+	room := models.Room{
+		EventID: event.ID,
+		Name:    input.Body.Name,
+		Beds:    input.Body.Beds,
+		Quiet:   input.Body.Quiet,
+		Stairs:  input.Body.Stairs,
+		Note:    input.Body.Note,
+	}
+	if err := h.db.Create(&room).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create room: " + err.Error())
+	}
+	return &RoomResponse{Body: room}, nil
+}
+
+func (h *RoomHandler) HandleUpdateRoom(ctx context.Context, input *UpdateRoomRequest) (*RoomResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	room, err := findRoom(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Body.Name == "" || input.Body.Beds < 1 {
+		return nil, huma.Error400BadRequest("Room name and at least one bed are required")
+	}
+
+	var existing int64
+	if err := h.db.Model(&models.Room{}).Where("event_id = ? AND name = ? AND id <> ?", room.EventID, input.Body.Name, room.ID).Count(&existing).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch rooms: " + err.Error())
+	}
+	if existing > 0 {
+		return nil, huma.Error409Conflict("Room " + input.Body.Name + " already exists")
+	}
+
+	room.Name = input.Body.Name
+	room.Beds = input.Body.Beds
+	room.Quiet = input.Body.Quiet
+	room.Stairs = input.Body.Stairs
+	room.Note = input.Body.Note
+
+	stays, err := roomStays(h.db, room.ID, 0)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch room assignments: " + err.Error())
+	}
+	if err := checkRoomCapacity(room, stays); err != nil {
+		return nil, err
+	}
+
+	if err := h.db.Save(&room).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update room: " + err.Error())
+	}
+	return &RoomResponse{Body: room}, nil
+}
+
+func (h *RoomHandler) HandleDeleteRoom(ctx context.Context, input *DeleteRoomRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	room, err := findRoom(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments int64
+	if err := h.db.Model(&models.RoomAssignment{}).Where("room_id = ?", room.ID).Count(&assignments).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch room assignments: " + err.Error())
+	}
+	if assignments > 0 {
+		return nil, huma.Error409Conflict("Room has assignments, remove them first")
+	}
+
+	// Hard delete so the name can be reused
+	if err := h.db.Unscoped().Delete(&room).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete room: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *RoomHandler) HandleListRooms(ctx context.Context, input *ListRoomsRequest) (*ListRoomsResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var rooms []models.Room
+	if err := h.db.Where("event_id = ?", event.ID).Order("name ASC").Find(&rooms).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch rooms: " + err.Error())
+	}
+
+	var assignments []models.RoomAssignment
+	roomIDs := make([]uint, len(rooms))
+	for i, r := range rooms {
+		roomIDs[i] = r.ID
+	}
+	if len(roomIDs) > 0 {
+		if err := h.db.Preload("Registration.User").Where("room_id IN ?", roomIDs).Order("from_date ASC").Find(&assignments).Error; err != nil {
+			return nil, huma.Error500InternalServerError("Failed to fetch room assignments: " + err.Error())
+		}
+	}
+
+	res := &ListRoomsResponse{}
+	res.Body.Rooms = make([]RoomOverview, len(rooms))
+	for i, room := range rooms {
+		overview := RoomOverview{Room: room, Assignments: []RoomAssignmentItem{}}
+		var stays []lodging.Stay
+		for _, a := range assignments {
+			if a.RoomID != room.ID {
+				continue
+			}
+			stay := lodging.AssignmentStay(a)
+			stays = append(stays, stay)
+			overview.Assignments = append(overview.Assignments, RoomAssignmentItem{
+				RoomAssignment: a,
+				Username:       a.Registration.User.Username,
+				People:         stay.People,
+			})
+		}
+
+		occupancy := lodging.Occupancy(stays)
+		for _, night := range eventNights(event, occupancy) {
+			overview.Occupancy = append(overview.Occupancy, NightOccupancy{
+				Night:  night,
+				People: occupancy[night],
+				Free:   room.Beds - occupancy[night],
+			})
+		}
+		res.Body.Rooms[i] = overview
+	}
+	return res, nil
+}
+
+// assignRoom validates the assignment against the registration's stay, its other
+// assignments and the room capacity and stores it
+func assignRoom(tx *gorm.DB, room models.Room, assignment models.RoomAssignment) (models.RoomAssignment, error) {
+	var registration models.Registration
+	if err := tx.First(&registration, assignment.RegistrationID).Error; err != nil {
+		return assignment, huma.Error404NotFound("Registration not found")
+	}
+	if registration.EventID != room.EventID {
+		return assignment, huma.Error400BadRequest("Registration belongs to another event")
+	}
+	if !registration.Confirmed() {
+		return assignment, huma.Error409Conflict("Only confirmed registrations can be assigned a room")
+	}
+
+	if assignment.FromDate.IsZero() {
+		assignment.FromDate = registration.ArrivalDate
+	}
+	if assignment.ToDate.IsZero() {
+		assignment.ToDate = registration.DepartureDate
+	}
+	assignment.FromDate = lodging.Day(assignment.FromDate)
+	assignment.ToDate = lodging.Day(assignment.ToDate)
+	if !assignment.FromDate.Before(assignment.ToDate) {
+		return assignment, huma.Error400BadRequest("Assignment must cover at least one night")
+	}
+	if assignment.FromDate.Before(lodging.Day(registration.ArrivalDate)) || assignment.ToDate.After(lodging.Day(registration.DepartureDate)) {
+		return assignment, huma.Error400BadRequest("Assignment must be within the stay of the registration")
+	}
+
+	var overlapping int64
+	err := tx.Model(&models.RoomAssignment{}).
+		Where("registration_id = ? AND from_date < ? AND to_date > ?", registration.ID, assignment.ToDate, assignment.FromDate).
+		Count(&overlapping).Error
+	if err != nil {
+		return assignment, huma.Error500InternalServerError("Failed to fetch room assignments: " + err.Error())
+	}
+	if overlapping > 0 {
+		return assignment, huma.Error409Conflict("Registration already has a room for some of these nights")
+	}
+
+	stays, err := roomStays(tx, room.ID, 0)
+	if err != nil {
+		return assignment, huma.Error500InternalServerError("Failed to fetch room assignments: " + err.Error())
+	}
+	assignment.Registration = registration
+	if err := checkRoomCapacity(room, append(stays, lodging.AssignmentStay(assignment))); err != nil {
+		return assignment, err
+	}
+
+	assignment.RoomID = room.ID
+	if err := tx.Omit("Registration", "Room").Create(&assignment).Error; err != nil {
+		return assignment, huma.Error500InternalServerError("Failed to assign room: " + err.Error())
+	}
+	assignment.Room = room
+	return assignment, nil
+}
+
+func (h *RoomHandler) HandleAssignRoom(ctx context.Context, input *AssignRoomRequest) (*RoomAssignmentResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := findRoom(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := models.RoomAssignment{RegistrationID: input.Body.RegistrationID, AssignedByID: org.ID}
+	if input.Body.FromDate != nil {
+		assignment.FromDate = *input.Body.FromDate
+	}
+	if input.Body.ToDate != nil {
+		assignment.ToDate = *input.Body.ToDate
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		assignment, err = assignRoom(tx, room, assignment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RoomAssignmentResponse{Body: assignment}, nil
+}
+
+func (h *RoomHandler) HandleUnassignRoom(ctx context.Context, input *UnassignRoomRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	var assignment models.RoomAssignment
+	if err := h.db.Where("id = ? AND room_id = ?", input.AssignmentID, input.ID).First(&assignment).Error; err != nil {
+		return nil, huma.Error404NotFound("Room assignment not found")
+	}
+	if err := h.db.Delete(&assignment).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to remove room assignment: " + err.Error())
+	}
+	return nil, nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/lodging"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type ProposedAssignment struct {
+	RegistrationID uint      `json:"registration_id"`
+	RoomID         uint      `json:"room_id"`
+	FromDate       time.Time `json:"from_date,omitempty"`
+	ToDate         time.Time `json:"to_date,omitempty"`
+	Username       string    `json:"username,omitempty" readOnly:"true"`
+	RoomName       string    `json:"room_name,omitempty" readOnly:"true"`
+	People         int       `json:"people,omitempty" readOnly:"true"`
+}
+
+type RoomPlanRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+}
+
+type CommitRoomPlanRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           struct {
+		Assignments []ProposedAssignment `json:"assignments" doc:"Reviewed proposal, stored all at once or not at all"`
+	}
+}
+
+type RoomPlanResponse struct {
+	Body struct {
+		Assignments []ProposedAssignment `json:"assignments"`
+		Unsatisfied []lodging.Issue      `json:"unsatisfied" doc:"Preferences the plan does not meet"`
+	}
+}
+
+// roomPlanState holds the rooms of an event with their current assignments and the room preferences of its attendees
+type roomPlanState struct {
+	rooms    []lodging.RoomState
+	guests   []lodging.Guest
+	assigned map[uint][]uint // Registration ID to the rooms it is assigned
+}
+
+func (s roomPlanState) roomModels() []models.Room {
+	rooms := make([]models.Room, len(s.rooms))
+	for i, r := range s.rooms {
+		rooms[i] = r.Room
+	}
+	return rooms
+}
+
+// loadRoomPlanState resolves roommate usernames against the event registrations
+func loadRoomPlanState(db *gorm.DB, event models.Event) (roomPlanState, error) {
+	state := roomPlanState{assigned: make(map[uint][]uint)}
+
+	var rooms []models.Room
+	if err := db.Where("event_id = ?", event.ID).Order("name ASC").Find(&rooms).Error; err != nil {
+		return state, err
+	}
+	for _, room := range rooms {
+		stays, err := roomStays(db, room.ID, 0)
+		if err != nil {
+			return state, err
+		}
+		state.rooms = append(state.rooms, lodging.RoomState{Room: room, Stays: stays})
+	}
+
+	var assignments []models.RoomAssignment
+	if err := db.Joins("JOIN rooms ON rooms.id = room_assignments.room_id").Where("rooms.event_id = ?", event.ID).Find(&assignments).Error; err != nil {
+		return state, err
+	}
+	for _, a := range assignments {
+		if !slices.Contains(state.assigned[a.RegistrationID], a.RoomID) {
+			state.assigned[a.RegistrationID] = append(state.assigned[a.RegistrationID], a.RoomID)
+		}
+	}
+
+	var registrations []models.Registration
+	if err := db.Preload("User").Where("event_id = ? AND cancelled = ? AND waitlisted = ?", event.ID, false, false).Order("id ASC").Find(&registrations).Error; err != nil {
+		return state, err
+	}
+	byUsername := make(map[string]uint, len(registrations))
+	for _, r := range registrations {
+		byUsername[strings.ToLower(r.User.Username)] = r.ID
+	}
+
+	for _, r := range registrations {
+		guest := lodging.Guest{
+			RegistrationID: r.ID,
+			Username:       r.User.Username,
+			Stay:           lodging.AssignmentStay(models.RoomAssignment{FromDate: r.ArrivalDate, ToDate: r.DepartureDate, Registration: r}),
+			Quiet:          r.QuietRoom,
+			NoStairs:       r.NoStairs,
+		}
+		for _, name := range r.Roommates {
+			id, ok := byUsername[strings.ToLower(strings.TrimSpace(name))]
+			switch {
+			case !ok:
+				guest.UnknownRoommates = append(guest.UnknownRoommates, name)
+			case id != r.ID && !slices.Contains(guest.Roommates, id):
+				guest.Roommates = append(guest.Roommates, id)
+			}
+		}
+		state.guests = append(state.guests, guest)
+	}
+	return state, nil
+}
+
+// proposeRooms places attendees without any room assignment
+func proposeRooms(state roomPlanState) ([]ProposedAssignment, []lodging.Issue) {
+	var unassigned []lodging.Guest
+	guests := make(map[uint]lodging.Guest, len(state.guests))
+	for _, g := range state.guests {
+		guests[g.RegistrationID] = g
+		if len(state.assigned[g.RegistrationID]) == 0 && len(lodging.Nights(g.Stay.From, g.Stay.To)) > 0 {
+			unassigned = append(unassigned, g)
+		}
+	}
+
+	placements, _ := lodging.Allocate(state.rooms, unassigned, state.assigned)
+
+	roomOf := make(map[uint][]uint, len(state.assigned)+len(placements))
+	for id, rooms := range state.assigned {
+		roomOf[id] = rooms
+	}
+	roomNames := make(map[uint]string, len(state.rooms))
+	for _, r := range state.rooms {
+		roomNames[r.Room.ID] = r.Room.Name
+	}
+
+	proposal := make([]ProposedAssignment, len(placements))
+	for i, p := range placements {
+		roomOf[p.RegistrationID] = append(roomOf[p.RegistrationID], p.RoomID)
+		g := guests[p.RegistrationID]
+		proposal[i] = ProposedAssignment{
+			RegistrationID: p.RegistrationID,
+			RoomID:         p.RoomID,
+			FromDate:       g.Stay.From,
+			ToDate:         g.Stay.To,
+			Username:       g.Username,
+			RoomName:       roomNames[p.RoomID],
+			People:         g.Stay.People,
+		}
+	}
+	return proposal, lodging.Unsatisfied(state.roomModels(), state.guests, roomOf)
+}
+
+func (h *RoomHandler) HandleProposeRoomPlan(ctx context.Context, input *RoomPlanRequest) (*RoomPlanResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := loadRoomPlanState(h.db, event)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to load rooms: " + err.Error())
+	}
+
+	res := &RoomPlanResponse{}
+	res.Body.Assignments, res.Body.Unsatisfied = proposeRooms(state)
+	return res, nil
+}
+
+// commitRoomPlan stores the assignments in a single transaction, any invalid one rejects them all
+func commitRoomPlan(db *gorm.DB, event models.Event, org models.User, proposal []ProposedAssignment) ([]models.RoomAssignment, error) {
+	var created []models.RoomAssignment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range proposal {
+			room, err := findRoom(tx, p.RoomID)
+			if err != nil {
+				return err
+			}
+			if room.EventID != event.ID {
+				return huma.Error400BadRequest("Room " + room.Name + " belongs to another event")
+			}
+			assignment, err := assignRoom(tx, room, models.RoomAssignment{
+				RegistrationID: p.RegistrationID,
+				FromDate:       p.FromDate,
+				ToDate:         p.ToDate,
+				AssignedByID:   org.ID,
+			})
+			if err != nil {
+				return err
+			}
+			created = append(created, assignment)
+		}
+		return nil
+	})
+	return created, err
+}
+
+func (h *RoomHandler) HandleCommitRoomPlan(ctx context.Context, input *CommitRoomPlanRequest) (*RoomPlanResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := commitRoomPlan(h.db, event, org, input.Body.Assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := loadRoomPlanState(h.db, event)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to load rooms: " + err.Error())
+	}
+
+	res := &RoomPlanResponse{}
+	res.Body.Assignments = make([]ProposedAssignment, len(created))
+	for i, a := range created {
+		res.Body.Assignments[i] = ProposedAssignment{
+			RegistrationID: a.RegistrationID,
+			RoomID:         a.RoomID,
+			FromDate:       a.FromDate,
+			ToDate:         a.ToDate,
+			RoomName:       a.Room.Name,
+			People:         lodging.AssignmentStay(a).People,
+		}
+	}
+	res.Body.Unsatisfied = lodging.Unsatisfied(state.roomModels(), state.guests, state.assigned)
+	return res, nil
+}
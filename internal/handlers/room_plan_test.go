@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/lodging"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRoomPlan(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "plan-event")
+	twin := models.Room{EventID: event.ID, Name: "Twin", Beds: 2, Stairs: true}
+	single := models.Room{EventID: event.ID, Name: "Single", Beds: 1}
+	db.Create(&twin)
+	db.Create(&single)
+
+	arrival := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	register := func(username string, fields models.RegistrationFields) models.Registration {
+		user := models.User{DiscordID: "plan-" + username, Username: username}
+		db.Create(&user)
+		fields.ArrivalDate = arrival
+		fields.DepartureDate = arrival.AddDate(0, 0, 2)
+		registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: fields}
+		db.Create(&registration)
+		return registration
+	}
+	alice := register("alice", models.RegistrationFields{Roommates: []string{"Bob", "nobody"}})
+	bob := register("bob", models.RegistrationFields{})
+	carol := register("carol", models.RegistrationFields{NoStairs: true})
+
+	state, err := loadRoomPlanState(db, event)
+	if err != nil {
+		t.Fatalf("loadRoomPlanState failed: %v", err)
+	}
+	proposal, unsatisfied := proposeRooms(state)
+	if len(proposal) != 3 {
+		t.Fatalf("expected everybody to get a room, got %+v", proposal)
+	}
+	rooms := make(map[uint]uint)
+	for _, p := range proposal {
+		rooms[p.RegistrationID] = p.RoomID
+	}
+	if rooms[alice.ID] != twin.ID || rooms[bob.ID] != twin.ID || rooms[carol.ID] != single.ID {
+		t.Errorf("unexpected proposal %+v", proposal)
+	}
+	if len(unsatisfied) != 1 || unsatisfied[0].Preference != lodging.PreferenceRoommate || unsatisfied[0].Username != "alice" {
+		t.Errorf("expected the unknown roommate to be reported, got %+v", unsatisfied)
+	}
+
+	// Nothing is stored before the commit
+	var count int64
+	db.Model(&models.RoomAssignment{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected proposal not to be stored, got %d assignments", count)
+	}
+
+	// An invalid assignment rejects the whole batch
+	invalid := append([]ProposedAssignment{}, proposal...)
+	invalid = append(invalid, ProposedAssignment{RegistrationID: carol.ID, RoomID: twin.ID})
+	if _, err := commitRoomPlan(db, event, models.User{}, invalid); err == nil {
+		t.Error("expected overbooking batch to be rejected")
+	}
+	db.Model(&models.RoomAssignment{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected rejected batch to store nothing, got %d assignments", count)
+	}
+
+	created, err := commitRoomPlan(db, event, models.User{}, proposal)
+	if err != nil {
+		t.Fatalf("commitRoomPlan failed: %v", err)
+	}
+	if len(created) != 3 {
+		t.Errorf("expected 3 assignments, got %d", len(created))
+	}
+
+	// Everybody has a room now, a new proposal is empty
+	state, _ = loadRoomPlanState(db, event)
+	if proposal, _ := proposeRooms(state); len(proposal) != 0 {
+		t.Errorf("expected no new proposals, got %+v", proposal)
+	}
+}
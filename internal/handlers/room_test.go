@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAssignRoom(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "room-event")
+	room := models.Room{EventID: event.ID, Name: "Attic", Beds: 3}
+	db.Create(&room)
+
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 0, 0, 0, 0, time.UTC) }
+	register := func(discordID string, arrival, departure, children int) models.Registration {
+		user := models.User{DiscordID: discordID}
+		db.Create(&user)
+		registration := models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+			ArrivalDate:   day(arrival),
+			DepartureDate: day(departure),
+			ChildrenCount: children,
+		}}
+		db.Create(&registration)
+		return registration
+	}
+
+	family := register("room-family", 1, 4, 1)
+	early := register("room-early", 1, 3, 0)
+	late := register("room-late", 3, 5, 0)
+	extra := register("room-extra", 2, 3, 0)
+
+	assignment, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: family.ID})
+	if err != nil {
+		t.Fatalf("assignRoom failed: %v", err)
+	}
+	if !assignment.FromDate.Equal(day(1)) || !assignment.ToDate.Equal(day(4)) {
+		t.Errorf("expected assignment to default to the stay, got %v - %v", assignment.FromDate, assignment.ToDate)
+	}
+
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: early.ID}); err != nil {
+		t.Fatalf("expected the third bed to be free: %v", err)
+	}
+	// The bed of early is free again from the 3rd
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: late.ID}); err != nil {
+		t.Fatalf("expected the bed to be reused after departure: %v", err)
+	}
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: extra.ID}); err == nil {
+		t.Error("expected overbooking to be rejected")
+	}
+
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: family.ID, FromDate: day(3), ToDate: day(4)}); err == nil {
+		t.Error("expected overlapping assignment of the same registration to be rejected")
+	}
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: extra.ID, FromDate: day(1), ToDate: day(3)}); err == nil {
+		t.Error("expected assignment outside of the stay to be rejected")
+	}
+
+	// Cancelling frees the beds
+	db.Model(&early).Update("cancelled", true)
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: extra.ID}); err != nil {
+		t.Errorf("expected beds of cancelled registration to be free: %v", err)
+	}
+
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	token, _ := authHandler.GenerateToken(family.UserID)
+	me := &auth.MeRequest{}
+	me.Cookie = "auth_token=" + token
+	resp, err := authHandler.HandleMe(context.Background(), me)
+	if err != nil {
+		t.Fatalf("HandleMe failed: %v", err)
+	}
+	rooms := resp.Body.Registrations[0].Rooms
+	if len(rooms) != 1 || rooms[0].Room.Name != "Attic" {
+		t.Errorf("expected the room in /me, got %+v", rooms)
+	}
+}
+
+func TestHandleRegister_RoomCapacity(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "room-headcount")
+	room := models.Room{EventID: event.ID, Name: "Double", Beds: 2}
+	db.Create(&room)
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	user := models.User{DiscordID: "room-headcount-user"}
+	db.Create(&user)
+	token, _ := authHandler.GenerateToken(user.ID)
+	register := func(children int) error {
+		req := &RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = event.StartDate
+		req.Body.DepartureDate = event.EndDate
+		req.Body.ChildrenCount = children
+		_, err := handler.HandleRegister(context.Background(), req)
+		return err
+	}
+
+	if err := register(1); err != nil {
+		t.Fatalf("HandleRegister failed: %v", err)
+	}
+	var registration models.Registration
+	db.Where("user_id = ?", user.ID).First(&registration)
+	if _, err := assignRoom(db, room, models.RoomAssignment{RegistrationID: registration.ID}); err != nil {
+		t.Fatalf("assignRoom failed: %v", err)
+	}
+
+	var statusErr huma.StatusError
+	if err := register(2); !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusConflict {
+		t.Fatalf("expected more people than beds in the assigned room to be a conflict, got %v", err)
+	}
+	var current models.Registration
+	db.First(&current, registration.ID)
+	if current.Children() != 1 {
+		t.Errorf("expected rejected change to be rolled back, got %d children", current.Children())
+	}
+
+	if err := register(0); err != nil {
+		t.Errorf("expected fewer people to fit the room: %v", err)
+	}
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func RegisterRoutes(r *chi.Mux, cfg *config.Config, authHandler *auth.AuthHandler, registrationHandler *RegistrationHandler, achievementHandler *AchievementHandler, apiKeyHandler *APIKeyHandler) {
+func RegisterRoutes(r *chi.Mux, cfg *config.Config, authHandler *auth.AuthHandler, registrationHandler *RegistrationHandler, achievementHandler *AchievementHandler, apiKeyHandler *APIKeyHandler, eventHandler *EventHandler, paymentHandler *PaymentHandler, checkInHandler *CheckInHandler, roomHandler *RoomHandler, carpoolHandler *CarpoolHandler, mealHandler *MealHandler, expenseHandler *ExpenseHandler, shiftHandler *ShiftHandler, sessionHandler *SessionHandler, calendarHandler *CalendarHandler, inviteHandler *InviteHandler, emailLoginHandler *auth.EmailLoginHandler, outboxHandler *OutboxHandler, discordChangeHandler *DiscordChangeHandler) {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -44,6 +44,67 @@ func RegisterRoutes(r *chi.Mux, cfg *config.Config, authHandler *auth.AuthHandle
 	huma.Get(api, "/auth/discord/login", authHandler.HandleLogin)
 	huma.Get(api, "/auth/discord/callback", authHandler.HandleCallback)
 	huma.Get(api, "/auth/logout", authHandler.HandleLogout)
+	huma.Post(api, "/auth/email/login", emailLoginHandler.HandleEmailLogin, func(o *huma.Operation) {
+		o.Summary = "Request email login link"
+		o.Description = "Sends a single-use login link to the account with the email, an alternative to signing in with Discord."
+	})
+	huma.Get(api, "/auth/email/callback", emailLoginHandler.HandleEmailConfirm, func(o *huma.Operation) {
+		o.Summary = "Email login confirmation"
+		o.Description = "Opened from the login link, shows a page submitting the token so that mail scanners opening the link do not use it up."
+		o.Responses = map[string]*huma.Response{
+			"200": {
+				Description: "Confirmation page",
+				Content: map[string]*huma.MediaType{
+					"text/html": {},
+				},
+			},
+		}
+	})
+	huma.Post(api, "/auth/email/callback", emailLoginHandler.HandleEmailCallback, func(o *huma.Operation) {
+		o.Summary = "Email login callback"
+		o.Description = "Exchanges the token from the login link for the auth cookie and redirects to the frontend."
+	})
+
+	// Invites are authenticated by the secret token in the URL
+	huma.Get(api, "/invites/{token}", inviteHandler.HandleGetInvite, func(o *huma.Operation) {
+		o.Summary = "Get invite"
+		o.Description = "Returns the event a guest is invited to."
+	})
+	huma.Post(api, "/invites/{token}/accept", inviteHandler.HandleAcceptInvite, func(o *huma.Operation) {
+		o.Summary = "Accept invite"
+		o.Description = "Creates a guest account without Discord and signs it in. The guest can then register for the event and link a Discord account later by signing in with it."
+	})
+
+	huma.Get(api, "/events/{code}/agenda", sessionHandler.HandleAgenda, func(o *huma.Operation) {
+		o.Summary = "Event agenda"
+		o.Description = "Returns the accepted talks and workshops of the event with their free seats."
+	})
+
+	// Calendar feeds are authenticated by the secret token in the URL, calendar clients cannot send cookies
+	huma.Get(api, "/calendar/{token}", calendarHandler.HandleUserFeed, func(o *huma.Operation) {
+		o.Summary = "Personal calendar feed"
+		o.Description = "Returns the stays, sessions and shifts of the token owner as iCalendar."
+		o.Responses = map[string]*huma.Response{
+			"200": {
+				Description: "iCalendar feed",
+				Content: map[string]*huma.MediaType{
+					"text/calendar": {},
+				},
+			},
+		}
+	})
+	huma.Get(api, "/calendar/{token}/events/{code}", calendarHandler.HandleAgendaFeed, func(o *huma.Operation) {
+		o.Summary = "Event agenda calendar feed"
+		o.Description = "Returns the dates, sessions and shifts of the event as iCalendar. Restricted to orgs."
+		o.Responses = map[string]*huma.Response{
+			"200": {
+				Description: "iCalendar feed",
+				Content: map[string]*huma.MediaType{
+					"text/calendar": {},
+				},
+			},
+		}
+	})
 
 	// Protected routes
 	r.Group(func(r chi.Router) {
@@ -58,6 +119,50 @@ func RegisterRoutes(r *chi.Mux, cfg *config.Config, authHandler *auth.AuthHandle
 		huma.Get(api, "/me", authHandler.HandleMe, func(o *huma.Operation) {
 			o.Security = authSecurity
 		})
+		huma.Post(api, "/events/{code}/invites", inviteHandler.HandleCreateInvite, func(o *huma.Operation) {
+			o.Summary = "Create invite"
+			o.Description = "Creates an invite link letting guests without Discord sign up for the event. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/invites", inviteHandler.HandleListInvites, func(o *huma.Operation) {
+			o.Summary = "List invites"
+			o.Description = "Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/invites/{id}", inviteHandler.HandleRevokeInvite, func(o *huma.Operation) {
+			o.Summary = "Revoke invite"
+			o.Description = "Stops the invite from creating more guest accounts, existing guests keep their accounts. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/outbox", outboxHandler.HandleListOutbox, func(o *huma.Operation) {
+			o.Summary = "List outbox"
+			o.Description = "Lists the notifications and Discord role changes waiting for delivery, delivered or given up on. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/outbox/{id}/replay", outboxHandler.HandleReplayOutboxItem, func(o *huma.Operation) {
+			o.Summary = "Replay outbox item"
+			o.Description = "Schedules a failed or delivered item for delivery again. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/discord/changes", discordChangeHandler.HandleListDiscordChanges, func(o *huma.Operation) {
+			o.Summary = "List Discord changes"
+			o.Description = "Lists the payments, achievement grants and profile updates caused by changes made directly in Discord. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/me/calendar", calendarHandler.HandleGetCalendarToken, func(o *huma.Operation) {
+			o.Summary = "Get calendar token"
+			o.Description = "Returns the secret calendar feed URLs of the caller."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/me/calendar", calendarHandler.HandleRotateCalendarToken, func(o *huma.Operation) {
+			o.Summary = "Create calendar token"
+			o.Description = "Creates a new secret calendar token, the previous feed URLs stop working."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/me/calendar", calendarHandler.HandleRevokeCalendarToken, func(o *huma.Operation) {
+			o.Summary = "Revoke calendar token"
+			o.Security = authSecurity
+		})
 		huma.Post(api, "/register", registrationHandler.HandleRegister, func(o *huma.Operation) {
 			o.Security = authSecurity
 		})
@@ -70,9 +175,310 @@ func RegisterRoutes(r *chi.Mux, cfg *config.Config, authHandler *auth.AuthHandle
 			o.Security = authSecurity
 		})
 
+		// Event Management Routes
+		huma.Post(api, "/events", eventHandler.HandleCreateEvent, func(o *huma.Operation) {
+			o.Summary = "Create event"
+			o.Description = "Creates a new event. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events", eventHandler.HandleListEvents, func(o *huma.Operation) {
+			o.Summary = "List events"
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}", eventHandler.HandleGetEvent, func(o *huma.Operation) {
+			o.Summary = "Get event"
+			o.Security = authSecurity
+		})
+		huma.Put(api, "/events/{code}", eventHandler.HandleUpdateEvent, func(o *huma.Operation) {
+			o.Summary = "Update event"
+			o.Description = "Updates an event, e.g. to open or close registrations. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Put(api, "/events/{code}/questions", eventHandler.HandleSetQuestions, func(o *huma.Operation) {
+			o.Summary = "Set event questions"
+			o.Description = "Replaces the custom registration questions of an event. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/questions", eventHandler.HandleListQuestions, func(o *huma.Operation) {
+			o.Summary = "List event questions"
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/events/{code}", eventHandler.HandleDeleteEvent, func(o *huma.Operation) {
+			o.Summary = "Delete event"
+			o.Description = "Deletes an event without registrations. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/rooms", roomHandler.HandleCreateRoom, func(o *huma.Operation) {
+			o.Summary = "Create a room"
+			o.Description = "Adds a room with a fixed number of beds to the event. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/rooms", roomHandler.HandleListRooms, func(o *huma.Operation) {
+			o.Summary = "List rooms"
+			o.Description = "Returns the rooms of the event with their assignments and occupancy per night. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/rooms/plan", roomHandler.HandleProposeRoomPlan, func(o *huma.Operation) {
+			o.Summary = "Propose a room plan"
+			o.Description = "Proposes rooms for attendees without a room, respecting bed capacities, stays and room preferences. Nothing is stored, review the proposal and commit it. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/rooms/plan/commit", roomHandler.HandleCommitRoomPlan, func(o *huma.Operation) {
+			o.Summary = "Commit a room plan"
+			o.Description = "Stores the reviewed room assignments as a batch, an invalid assignment rejects the whole batch. Returns the preferences left unsatisfied. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/meals", mealHandler.HandleMealReport, func(o *huma.Operation) {
+			o.Summary = "Meal headcount report"
+			o.Description = "Returns the number of people eating each meal by day, broken down by dietary tags. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/meals/csv", mealHandler.HandleMealReportCSV, func(o *huma.Operation) {
+			o.Summary = "Export meal headcounts"
+			o.Description = "Returns the meal headcount report as CSV for the cooks. Restricted to orgs."
+			o.Security = authSecurity
+			o.Responses = map[string]*huma.Response{
+				"200": {
+					Description: "Meal headcounts",
+					Content: map[string]*huma.MediaType{
+						"text/csv": {},
+					},
+				},
+			}
+		})
+		huma.Post(api, "/events/{code}/rides", carpoolHandler.HandleOfferRide, func(o *huma.Operation) {
+			o.Summary = "Offer a ride"
+			o.Description = "Offers free seats in the caller's car, requires a confirmed registration."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/rides", carpoolHandler.HandleListRides, func(o *huma.Operation) {
+			o.Summary = "List rides"
+			o.Description = "Returns the rides offered for the event with free seats and passengers."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/expenses", expenseHandler.HandleCreateExpense, func(o *huma.Operation) {
+			o.Summary = "Record a shared expense"
+			o.Description = "Records what the caller paid for other attendees, split by shares. Orgs may record expenses paid by others."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/expenses", expenseHandler.HandleListExpenses, func(o *huma.Operation) {
+			o.Summary = "List shared expenses"
+			o.Description = "Returns the expenses of the event, the balances of the attendees and the transfers settling them up. Restricted to confirmed attendees and orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/expenses/settlement", expenseHandler.HandlePostSettlement, func(o *huma.Operation) {
+			o.Summary = "Post the settlement"
+			o.Description = "Queues the transfers settling the shared expenses for posting to Discord and the notification channels. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/shifts", shiftHandler.HandleCreateShift, func(o *huma.Operation) {
+			o.Summary = "Create a shift"
+			o.Description = "Creates a volunteer shift, e.g. cooking or firewood duty. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/shifts", shiftHandler.HandleListShifts, func(o *huma.Operation) {
+			o.Summary = "List shifts"
+			o.Description = "Returns the duty roster of the event with volunteers and free spots."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/shifts/report", shiftHandler.HandleShiftReport, func(o *huma.Operation) {
+			o.Summary = "Shift fairness report"
+			o.Description = "Returns the number of shifts and hours of each confirmed attendee. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/sessions", sessionHandler.HandleCreateSession, func(o *huma.Operation) {
+			o.Summary = "Create a session"
+			o.Description = "Adds a talk or workshop to the agenda. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/events/{code}/proposals", sessionHandler.HandleProposeSession, func(o *huma.Operation) {
+			o.Summary = "Propose a session"
+			o.Description = "Submits a talk or workshop with the caller as the speaker, it is on the agenda once accepted by the orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/events/{code}/proposals", sessionHandler.HandleListProposals, func(o *huma.Operation) {
+			o.Summary = "List session proposals"
+			o.Description = "Returns proposed and rejected sessions. Orgs see all proposals, attendees only their own."
+			o.Security = authSecurity
+		})
+		huma.Put(api, "/rooms/{id}", roomHandler.HandleUpdateRoom, func(o *huma.Operation) {
+			o.Summary = "Update a room"
+			o.Description = "Updates the room, rejecting fewer beds than already assigned. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/rooms/{id}", roomHandler.HandleDeleteRoom, func(o *huma.Operation) {
+			o.Summary = "Delete a room"
+			o.Description = "Deletes a room without assignments. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/rooms/{id}/assignments", roomHandler.HandleAssignRoom, func(o *huma.Operation) {
+			o.Summary = "Assign a room"
+			o.Description = "Assigns a registration including its children to the room for its stay or a part of it. Rejects assignments overbooking the room on any night. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/rooms/{id}/assignments/{assignment_id}", roomHandler.HandleUnassignRoom, func(o *huma.Operation) {
+			o.Summary = "Remove a room assignment"
+			o.Description = "Restricted to orgs."
+			o.Security = authSecurity
+		})
+
+		// Payment Ledger Routes
+		huma.Post(api, "/payments", paymentHandler.HandleRecordPayment, func(o *huma.Operation) {
+			o.Summary = "Record payment"
+			o.Description = "Records a received payment for a registration and grants the paid role once the balance is settled. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/payments", paymentHandler.HandleListPayments, func(o *huma.Operation) {
+			o.Summary = "List payments"
+			o.Description = "Returns recorded payments including voided ones. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/payments/{id}/void", paymentHandler.HandleVoidPayment, func(o *huma.Operation) {
+			o.Summary = "Void payment"
+			o.Description = "Marks a payment as void, it no longer counts towards the balance. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/payments/import", paymentHandler.HandleImportStatement, func(o *huma.Operation) {
+			o.Summary = "Import a bank statement"
+			o.Description = "Imports a CSV or camt.053 bank statement, matches incoming transactions to registrations by variable symbol and amount and records them as payments. Reports partial, overpaid and unmatched transactions without recording them. Restricted to orgs."
+			o.Security = authSecurity
+		})
+
+		huma.Get(api, "/registrations/{id}/payment", paymentHandler.HandlePaymentInfo, func(o *huma.Operation) {
+			o.Summary = "Get payment details"
+			o.Description = "Returns bank transfer details with the variable symbol and a SPAYD string for the outstanding balance of the registration."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/registrations/{id}/payment/qr", paymentHandler.HandlePaymentQR, func(o *huma.Operation) {
+			o.Summary = "Get payment QR code"
+			o.Description = "Returns a PNG QR code with the SPAYD payment for the outstanding balance of the registration."
+			o.Security = authSecurity
+			o.Responses = map[string]*huma.Response{
+				"200": {
+					Description: "QR code image",
+					Content: map[string]*huma.MediaType{
+						"image/png": {},
+					},
+				},
+			}
+		})
+
+		huma.Delete(api, "/rides/{id}", carpoolHandler.HandleCancelRide, func(o *huma.Operation) {
+			o.Summary = "Cancel a ride"
+			o.Description = "Cancels the ride and lets its passengers know. Restricted to the driver and orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/rides/{id}/requests", carpoolHandler.HandleRequestSeat, func(o *huma.Operation) {
+			o.Summary = "Request a seat"
+			o.Description = "Asks the driver for seats in the ride, requires a confirmed registration."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/rides/{id}/requests/{request_id}/accept", carpoolHandler.HandleAcceptRequest, func(o *huma.Operation) {
+			o.Summary = "Accept a ride request"
+			o.Description = "Takes the passenger into the ride, the ride is announced once full. Restricted to the driver and orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/rides/{id}/requests/{request_id}/reject", carpoolHandler.HandleRejectRequest, func(o *huma.Operation) {
+			o.Summary = "Reject a ride request"
+			o.Description = "Restricted to the driver and orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/rides/{id}/requests/{request_id}", carpoolHandler.HandleWithdrawRequest, func(o *huma.Operation) {
+			o.Summary = "Withdraw a ride request"
+			o.Description = "Releases the seats, the driver is notified when the passenger was already accepted."
+			o.Security = authSecurity
+		})
+
+		huma.Delete(api, "/expenses/{id}", expenseHandler.HandleDeleteExpense, func(o *huma.Operation) {
+			o.Summary = "Delete a shared expense"
+			o.Description = "Restricted to the payer, the author of the expense and orgs."
+			o.Security = authSecurity
+		})
+
+		huma.Put(api, "/shifts/{id}", shiftHandler.HandleUpdateShift, func(o *huma.Operation) {
+			o.Summary = "Update a shift"
+			o.Description = "Updates the shift, shifts with volunteers cannot be moved or need fewer people than signed up. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/shifts/{id}", shiftHandler.HandleDeleteShift, func(o *huma.Operation) {
+			o.Summary = "Delete a shift"
+			o.Description = "Deletes the shift together with its signups. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/shifts/{id}/signups", shiftHandler.HandleSignUp, func(o *huma.Operation) {
+			o.Summary = "Sign up for a shift"
+			o.Description = "Signs up the caller, the shift must be within their stay and not overlap their other shifts. Orgs may sign up other attendees."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/shifts/{id}/signups/{signup_id}", shiftHandler.HandleLeaveShift, func(o *huma.Operation) {
+			o.Summary = "Leave a shift"
+			o.Description = "Restricted to the volunteer and orgs."
+			o.Security = authSecurity
+		})
+
+		huma.Put(api, "/sessions/{id}", sessionHandler.HandleUpdateSession, func(o *huma.Operation) {
+			o.Summary = "Update a session"
+			o.Description = "Updates the session, it cannot have fewer seats than signups or move outside of the stay of an attendee signed up. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/sessions/{id}", sessionHandler.HandleDeleteSession, func(o *huma.Operation) {
+			o.Summary = "Delete a session"
+			o.Description = "Deletes the session together with its signups. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/sessions/{id}/accept", sessionHandler.HandleAcceptSession, func(o *huma.Operation) {
+			o.Summary = "Accept a session proposal"
+			o.Description = "Schedules the proposed session and puts it on the agenda. Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/sessions/{id}/reject", sessionHandler.HandleRejectSession, func(o *huma.Operation) {
+			o.Summary = "Reject a session proposal"
+			o.Description = "Restricted to orgs."
+			o.Security = authSecurity
+		})
+		huma.Post(api, "/sessions/{id}/signups", sessionHandler.HandleSignUp, func(o *huma.Operation) {
+			o.Summary = "Sign up for a session"
+			o.Description = "Takes a seat at the session, it must have free seats and be within the caller's stay."
+			o.Security = authSecurity
+		})
+		huma.Delete(api, "/sessions/{id}/signups", sessionHandler.HandleWithdraw, func(o *huma.Operation) {
+			o.Summary = "Withdraw from a session"
+			o.Description = "Frees the caller's seat at the session."
+			o.Security = authSecurity
+		})
+
+		huma.Get(api, "/registrations/{id}/meals", mealHandler.HandleGetMealChoices, func(o *huma.Operation) {
+			o.Summary = "Get meal choices"
+			o.Description = "Returns the meals served during the stay and whether the attendees eat them."
+			o.Security = authSecurity
+		})
+		huma.Put(api, "/registrations/{id}/meals", mealHandler.HandleSetMealChoices, func(o *huma.Operation) {
+			o.Summary = "Set meal choices"
+			o.Description = "Opts out of individual meals of the stay. Meals of a day cannot be changed after the event meal cutoff, orgs may override it."
+			o.Security = authSecurity
+		})
+		huma.Get(api, "/registrations/{id}/ticket", checkInHandler.HandleTicketQR, func(o *huma.Operation) {
+			o.Summary = "Get ticket QR code"
+			o.Description = "Returns a PNG QR code with the signed check-in ticket of a confirmed registration."
+			o.Security = authSecurity
+			o.Responses = map[string]*huma.Response{
+				"200": {
+					Description: "QR code image",
+					Content: map[string]*huma.MediaType{
+						"image/png": {},
+					},
+				},
+			}
+		})
+		huma.Post(api, "/checkin", checkInHandler.HandleCheckIn, func(o *huma.Operation) {
+			o.Summary = "Check in an attendee"
+			o.Description = "Verifies a scanned ticket and marks the registration as checked in. Restricted to orgs."
+			o.Security = authSecurity
+		})
+
 		huma.Post(api, "/achievements/create", achievementHandler.HandleCreateAchievement, func(o *huma.Operation) {
 			o.Summary = "Create a new achievement"
-			o.Description = "Creates a new achievement and a corresponding Discord role. Restricted to orgs."
+			o.Description = "Creates a new achievement and a corresponding Discord role when Discord is configured. Restricted to orgs."
 			o.Security = authSecurity
 		})
 		huma.Post(api, "/achievements/grant", achievementHandler.HandleGrantAchievement, func(o *huma.Operation) {
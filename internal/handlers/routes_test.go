@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Huma panics on conflicting schema names or routes, so registering all of them catches it before deploy
+func TestRegisterRoutes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	a := auth.NewAuthHandler(cfg, db, nil)
+	r := chi.NewMux()
+	RegisterRoutes(r, cfg, a,
+		NewRegistrationHandler(db, nil, nil, a, cfg),
+		NewAchievementHandler(db, nil, nil, a, cfg),
+		NewAPIKeyHandler(db, a),
+		NewEventHandler(db, nil, nil, a),
+		NewPaymentHandler(db, nil, nil, a),
+		NewCheckInHandler(db, a),
+		NewRoomHandler(db, a),
+		NewCarpoolHandler(db, nil, a),
+		NewMealHandler(db, a),
+		NewExpenseHandler(db, nil, a),
+		NewShiftHandler(db, a),
+		NewSessionHandler(db, a),
+		NewCalendarHandler(db, a, cfg),
+		NewInviteHandler(db, a, cfg),
+		auth.NewEmailLoginHandler(db, cfg, nil, a),
+		NewOutboxHandler(db, a),
+		NewDiscordChangeHandler(db, a),
+	)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the OpenAPI document, got %d", rec.Code)
+	}
+	for _, schema := range []string{`"Balance"`, `"UserBalance"`} {
+		if !strings.Contains(rec.Body.String(), schema) {
+			t.Errorf("expected schema %s in the OpenAPI document", schema)
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/roster"
+	"gorm.io/gorm"
+)
+
+type SessionHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewSessionHandler(db *gorm.DB, authHandler *auth.AuthHandler) *SessionHandler {
+	return &SessionHandler{db: db, authHandler: authHandler}
+}
+
+type SessionBody struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Speaker     string    `json:"speaker,omitempty" doc:"Username of the speaker"`
+	Room        string    `json:"room,omitempty" doc:"Where the session takes place"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Capacity    int       `json:"capacity,omitempty" minimum:"0" doc:"Seats for attendees, 0 when unlimited"`
+}
+
+type CreateSessionRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           SessionBody
+}
+
+type UpdateSessionRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Session ID"`
+	Body           SessionBody
+}
+
+type SessionIDRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Session ID"`
+}
+
+type AgendaRequest struct {
+	Code string `path:"code" doc:"Event code"`
+}
+
+type EventProposalsRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type ProposeSessionRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+	Body struct {
+		Title       string     `json:"title"`
+		Description string     `json:"description" doc:"What the talk or workshop is about"`
+		StartsAt    *time.Time `json:"starts_at,omitempty" doc:"Preferred start, the orgs schedule the session on acceptance"`
+		EndsAt      *time.Time `json:"ends_at,omitempty" doc:"Preferred end"`
+		Capacity    int        `json:"capacity,omitempty" minimum:"0" doc:"Seats for attendees, 0 when unlimited"`
+	}
+}
+
+type AcceptSessionRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Session ID"`
+	Body           struct {
+		Room     string     `json:"room,omitempty" doc:"Where the session takes place"`
+		StartsAt *time.Time `json:"starts_at,omitempty" doc:"Defaults to the proposed start"`
+		EndsAt   *time.Time `json:"ends_at,omitempty" doc:"Defaults to the proposed end"`
+		Capacity *int       `json:"capacity,omitempty" minimum:"0" doc:"Defaults to the proposed capacity"`
+		Note     string     `json:"note,omitempty" doc:"Message for the speaker"`
+	}
+}
+
+type RejectSessionRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Session ID"`
+	Body           struct {
+		Reason string `json:"reason" doc:"Why the proposal is rejected"`
+	}
+}
+
+type SessionItem struct {
+	models.Session
+	SpeakerUsername string `json:"speaker_username,omitempty"`
+	Taken           int    `json:"taken" doc:"Seats taken by attendees"`
+	Free            *int   `json:"free,omitempty" doc:"Free seats, missing when the capacity is unlimited"`
+}
+
+type SessionResponse struct {
+	Body SessionItem
+}
+
+type ListSessionsResponse struct {
+	Body struct {
+		Sessions []SessionItem `json:"sessions"`
+	}
+}
+
+// AgendaItem is an accepted session as shown publicly, without the review details
+type AgendaItem struct {
+	ID              uint      `json:"id"`
+	Event           string    `json:"event"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	SpeakerUsername string    `json:"speaker_username,omitempty"`
+	Room            string    `json:"room"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+	Capacity        int       `json:"capacity" doc:"Seats for attendees, 0 when unlimited"`
+	Taken           int       `json:"taken" doc:"Seats taken by attendees"`
+	Free            *int      `json:"free,omitempty" doc:"Free seats, missing when the capacity is unlimited"`
+}
+
+type AgendaResponse struct {
+	Body struct {
+		Sessions []AgendaItem `json:"sessions"`
+	}
+}
+
+func newSessionItem(session models.Session) SessionItem {
+	item := SessionItem{Session: session, Taken: len(session.Signups)}
+	if session.Speaker != nil {
+		item.SpeakerUsername = session.Speaker.Username
+	}
+	if session.Capacity > 0 {
+		free := session.Capacity - len(session.Signups)
+		item.Free = &free
+	}
+	return item
+}
+
+func newSessionItems(sessions []models.Session) []SessionItem {
+	items := make([]SessionItem, len(sessions))
+	for i, s := range sessions {
+		items[i] = newSessionItem(s)
+	}
+	return items
+}
+
+func newAgendaItem(session models.Session) AgendaItem {
+	item := newSessionItem(session)
+	return AgendaItem{
+		ID:              session.ID,
+		Event:           session.Event,
+		Title:           session.Title,
+		Description:     session.Description,
+		SpeakerUsername: item.SpeakerUsername,
+		Room:            session.Room,
+		StartsAt:        session.StartsAt,
+		EndsAt:          session.EndsAt,
+		Capacity:        session.Capacity,
+		Taken:           item.Taken,
+		Free:            item.Free,
+	}
+}
+
+func findSession(db *gorm.DB, id uint) (models.Session, error) {
+	var session models.Session
+	if err := db.Preload("Speaker").Preload("Signups").First(&session, id).Error; err != nil {
+		return session, huma.Error404NotFound("Session not found")
+	}
+	return session, nil
+}
+
+func validateSessionSchedule(startsAt time.Time, endsAt time.Time, capacity int) error {
+	if startsAt.IsZero() || !startsAt.Before(endsAt) {
+		return huma.Error400BadRequest("Session must end after it starts")
+	}
+	if capacity < 0 {
+		return huma.Error400BadRequest("Capacity cannot be negative")
+	}
+	return nil
+}
+
+// sessionSpeaker looks up the speaker by username, an empty username means no speaker
+func sessionSpeaker(db *gorm.DB, username string) (*uint, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, nil
+	}
+	var user models.User
+	if err := db.Where("LOWER(username) = ?", strings.ToLower(username)).First(&user).Error; err != nil {
+		return nil, huma.Error404NotFound("User " + username + " not found")
+	}
+	return &user.ID, nil
+}
+
+func (h *SessionHandler) HandleAgenda(ctx context.Context, input *AgendaRequest) (*AgendaResponse, error) {
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []models.Session
+	err = h.db.Preload("Speaker").Preload("Signups").
+		Where("event_id = ? AND status = ?", event.ID, models.SessionAccepted).
+		Order("starts_at ASC, title ASC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch sessions: " + err.Error())
+	}
+
+	res := &AgendaResponse{}
+	res.Body.Sessions = make([]AgendaItem, len(sessions))
+	for i, s := range sessions {
+		res.Body.Sessions[i] = newAgendaItem(s)
+	}
+	return res, nil
+}
+
+func (h *SessionHandler) HandleCreateSession(ctx context.Context, input *CreateSessionRequest) (*SessionResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Body.Title) == "" {
+		return nil, huma.Error400BadRequest("Title is required")
+	}
+	if err := validateSessionSchedule(input.Body.StartsAt, input.Body.EndsAt, input.Body.Capacity); err != nil {
+		return nil, err
+	}
+	speakerID, err := sessionSpeaker(h.db, input.Body.Speaker)
+	if err != nil {
+		return nil, err
+	}
+
+	session := models.Session{
+		EventID:      event.ID,
+		Event:        event.Code,
+		Title:        strings.TrimSpace(input.Body.Title),
+		Description:  input.Body.Description,
+		SpeakerID:    speakerID,
+		Room:         input.Body.Room,
+		StartsAt:     input.Body.StartsAt,
+		EndsAt:       input.Body.EndsAt,
+		Capacity:     input.Body.Capacity,
+		Status:       models.SessionAccepted,
+		ReviewedByID: &org.ID,
+	}
+	if err := h.db.Create(&session).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create session: " + err.Error())
+	}
+
+	session, err = findSession(h.db, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+func (h *SessionHandler) HandleUpdateSession(ctx context.Context, input *UpdateSessionRequest) (*SessionResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Body.Title) == "" {
+		return nil, huma.Error400BadRequest("Title is required")
+	}
+	if err := validateSessionSchedule(input.Body.StartsAt, input.Body.EndsAt, input.Body.Capacity); err != nil {
+		return nil, err
+	}
+	speakerID, err := sessionSpeaker(h.db, input.Body.Speaker)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Title = strings.TrimSpace(input.Body.Title)
+	session.Description = input.Body.Description
+	session.SpeakerID = speakerID
+	session.Room = input.Body.Room
+	session.StartsAt = input.Body.StartsAt
+	session.EndsAt = input.Body.EndsAt
+	session.Capacity = input.Body.Capacity
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		registrations, err := sessionRegistrations(tx, session.ID)
+		if err != nil {
+			return huma.Error500InternalServerError("Failed to fetch session signups: " + err.Error())
+		}
+		if session.Capacity > 0 && session.Capacity < len(registrations) {
+			return huma.Error409Conflict(fmt.Sprintf("%d attendees are already signed up for the session", len(registrations)))
+		}
+		// A moved session must still fit the stay of everyone signed up
+		outside := 0
+		for _, registration := range registrations {
+			if !roster.WithinStay(session.StartsAt, session.EndsAt, registration.ArrivalDate, registration.DepartureDate) {
+				outside++
+			}
+		}
+		if outside > 0 {
+			return huma.Error409Conflict(fmt.Sprintf("%d attendees signed up for the session are not staying at the new time", outside))
+		}
+		if err := tx.Omit("Speaker", "Signups").Save(&session).Error; err != nil {
+			return huma.Error500InternalServerError("Failed to update session: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = findSession(h.db, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+func (h *SessionHandler) HandleDeleteSession(ctx context.Context, input *SessionIDRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", session.ID).Delete(&models.SessionSignup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&session).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete session: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *SessionHandler) HandleProposeSession(ctx context.Context, input *ProposeSessionRequest) (*SessionResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	var registration models.Registration
+	if err := h.db.Where("user_id = ? AND event_id = ? AND cancelled = ?", userID, event.ID, false).First(&registration).Error; err != nil {
+		return nil, huma.Error403Forbidden("Only attendees can propose sessions")
+	}
+	if strings.TrimSpace(input.Body.Title) == "" {
+		return nil, huma.Error400BadRequest("Title is required")
+	}
+
+	session := models.Session{
+		EventID:     event.ID,
+		Event:       event.Code,
+		Title:       strings.TrimSpace(input.Body.Title),
+		Description: input.Body.Description,
+		SpeakerID:   &userID,
+		Capacity:    input.Body.Capacity,
+		Status:      models.SessionProposed,
+	}
+	if input.Body.StartsAt != nil && input.Body.EndsAt != nil {
+		if err := validateSessionSchedule(*input.Body.StartsAt, *input.Body.EndsAt, session.Capacity); err != nil {
+			return nil, err
+		}
+		session.StartsAt = *input.Body.StartsAt
+		session.EndsAt = *input.Body.EndsAt
+	}
+	if err := h.db.Create(&session).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to propose session: " + err.Error())
+	}
+
+	session, err = findSession(h.db, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+func (h *SessionHandler) HandleListProposals(ctx context.Context, input *EventProposalsRequest) (*ListSessionsResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	query := h.db.Preload("Speaker").Preload("Signups").Where("event_id = ? AND status <> ?", event.ID, models.SessionAccepted)
+	isOrg, err := h.authHandler.IsOrg(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrg {
+		// Attendees only see their own proposals
+		query = query.Where("speaker_id = ?", userID)
+	}
+
+	var sessions []models.Session
+	if err := query.Order("created_at ASC").Find(&sessions).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch proposals: " + err.Error())
+	}
+
+	res := &ListSessionsResponse{}
+	res.Body.Sessions = newSessionItems(sessions)
+	return res, nil
+}
+
+func (h *SessionHandler) HandleAcceptSession(ctx context.Context, input *AcceptSessionRequest) (*SessionResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.SessionAccepted {
+		return nil, huma.Error409Conflict("Session is already accepted")
+	}
+
+	if input.Body.Room != "" {
+		session.Room = input.Body.Room
+	}
+	if input.Body.StartsAt != nil {
+		session.StartsAt = *input.Body.StartsAt
+	}
+	if input.Body.EndsAt != nil {
+		session.EndsAt = *input.Body.EndsAt
+	}
+	if input.Body.Capacity != nil {
+		session.Capacity = *input.Body.Capacity
+	}
+	if err := validateSessionSchedule(session.StartsAt, session.EndsAt, session.Capacity); err != nil {
+		return nil, err
+	}
+
+	session.Status = models.SessionAccepted
+	session.ReviewNote = input.Body.Note
+	session.ReviewedByID = &org.ID
+	if err := h.db.Omit("Speaker", "Signups").Save(&session).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to accept session: " + err.Error())
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+func (h *SessionHandler) HandleRejectSession(ctx context.Context, input *RejectSessionRequest) (*SessionResponse, error) {
+	org, err := h.authHandler.RequireOrg(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.SessionProposed {
+		return nil, huma.Error409Conflict("Only proposed sessions can be rejected")
+	}
+
+	session.Status = models.SessionRejected
+	session.ReviewNote = input.Body.Reason
+	session.ReviewedByID = &org.ID
+	if err := h.db.Omit("Speaker", "Signups").Save(&session).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to reject session: " + err.Error())
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+// signUpForSession validates the session's capacity and the registration's stay and stores the signup
+func signUpForSession(tx *gorm.DB, session models.Session, registration models.Registration) error {
+	if session.Status != models.SessionAccepted {
+		return huma.Error404NotFound("Session not found")
+	}
+	if !roster.WithinStay(session.StartsAt, session.EndsAt, registration.ArrivalDate, registration.DepartureDate) {
+		return huma.Error409Conflict("Session is outside of your stay")
+	}
+
+	// Count within the transaction, the preloaded signups may be stale by now
+	var signups []models.SessionSignup
+	if err := tx.Where("session_id = ?", session.ID).Find(&signups).Error; err != nil {
+		return huma.Error500InternalServerError("Failed to fetch session signups: " + err.Error())
+	}
+	for _, s := range signups {
+		if s.RegistrationID == registration.ID {
+			return huma.Error409Conflict("You are already signed up for the session")
+		}
+	}
+	if session.Capacity > 0 && len(signups) >= session.Capacity {
+		return huma.Error409Conflict("Session is full")
+	}
+
+	signup := models.SessionSignup{SessionID: session.ID, RegistrationID: registration.ID, UserID: registration.UserID}
+	if err := tx.Create(&signup).Error; err != nil {
+		if database.IsUniqueViolation(err) {
+			return huma.Error409Conflict("You are already signed up for the session")
+		}
+		return huma.Error500InternalServerError("Failed to sign up for the session: " + err.Error())
+	}
+	return nil
+}
+
+// sessionRegistrations loads the registrations signed up for the session
+func sessionRegistrations(tx *gorm.DB, sessionID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := tx.Joins("JOIN session_signups ON session_signups.registration_id = registrations.id").
+		Where("session_signups.session_id = ?", sessionID).
+		Find(&registrations).Error
+	return registrations, err
+}
+
+// releaseSessionsOutsideStay drops the session signups of the registration which no longer fit its stay
+func releaseSessionsOutsideStay(tx *gorm.DB, registration models.Registration) error {
+	var taken []models.Session
+	err := tx.Joins("JOIN session_signups ON session_signups.session_id = sessions.id").
+		Where("session_signups.registration_id = ?", registration.ID).
+		Find(&taken).Error
+	if err != nil {
+		return err
+	}
+
+	var outside []uint
+	for _, session := range taken {
+		if !roster.WithinStay(session.StartsAt, session.EndsAt, registration.ArrivalDate, registration.DepartureDate) {
+			outside = append(outside, session.ID)
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	return tx.Where("registration_id = ? AND session_id IN ?", registration.ID, outside).Delete(&models.SessionSignup{}).Error
+}
+
+func (h *SessionHandler) HandleSignUp(ctx context.Context, input *SessionIDRequest) (*SessionResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	registration, err := confirmedRegistration(h.db, userID, session.EventID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		return signUpForSession(tx, session, registration)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = findSession(h.db, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResponse{Body: newSessionItem(session)}, nil
+}
+
+func (h *SessionHandler) HandleWithdraw(ctx context.Context, input *SessionIDRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	result := h.db.Where("session_id = ? AND user_id = ?", input.ID, userID).Delete(&models.SessionSignup{})
+	if result.Error != nil {
+		return nil, huma.Error500InternalServerError("Failed to withdraw from the session: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, huma.Error404NotFound("You are not signed up for the session")
+	}
+	return nil, nil
+}
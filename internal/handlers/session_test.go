@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSessions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "session-event")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	register := func(username string, arrival time.Time) models.User {
+		user := models.User{DiscordID: "session-" + username, Username: username}
+		db.Create(&user)
+		db.Create(&models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+			ArrivalDate:   arrival,
+			DepartureDate: today.AddDate(0, 0, 3),
+		}})
+		return user
+	}
+	speaker := register("speaker", today.AddDate(0, 0, 1))
+	early := register("early", today.AddDate(0, 0, 1))
+	late := register("late", today.AddDate(0, 0, 2))
+	other := register("other", today.AddDate(0, 0, 1))
+
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	handler := NewSessionHandler(db, authHandler)
+	cookie := func(user models.User) string {
+		token, _ := authHandler.GenerateToken(user.ID)
+		return "auth_token=" + token
+	}
+	signUp := func(user models.User, id uint) (*SessionResponse, error) {
+		req := &SessionIDRequest{ID: id}
+		req.Cookie = cookie(user)
+		return handler.HandleSignUp(context.Background(), req)
+	}
+
+	propose := &ProposeSessionRequest{Code: event.Code}
+	propose.Cookie = cookie(speaker)
+	propose.Body.Title = "Soldering 101"
+	propose.Body.Capacity = 1
+	proposed, err := handler.HandleProposeSession(context.Background(), propose)
+	if err != nil {
+		t.Fatalf("HandleProposeSession failed: %v", err)
+	}
+	if proposed.Body.Status != models.SessionProposed || proposed.Body.SpeakerUsername != "speaker" {
+		t.Errorf("unexpected proposal %+v", proposed.Body)
+	}
+
+	list := &EventProposalsRequest{Code: event.Code}
+	list.Cookie = cookie(other)
+	if res, err := handler.HandleListProposals(context.Background(), list); err != nil || len(res.Body.Sessions) != 0 {
+		t.Errorf("expected attendees to see only their own proposals, got %v %v", res, err)
+	}
+	if _, err := signUp(early, proposed.Body.ID); err == nil {
+		t.Error("expected signup for a proposal to be rejected")
+	}
+
+	// Accepting is restricted to orgs
+	db.Model(&models.Session{}).Where("id = ?", proposed.Body.ID).Updates(map[string]any{
+		"status":    models.SessionAccepted,
+		"starts_at": today.AddDate(0, 0, 1).Add(14 * time.Hour),
+		"ends_at":   today.AddDate(0, 0, 1).Add(16 * time.Hour),
+	})
+
+	if _, err := signUp(late, proposed.Body.ID); err == nil {
+		t.Error("expected session outside of the stay to be rejected")
+	}
+	res, err := signUp(early, proposed.Body.ID)
+	if err != nil {
+		t.Fatalf("HandleSignUp failed: %v", err)
+	}
+	if res.Body.Taken != 1 || res.Body.Free == nil || *res.Body.Free != 0 {
+		t.Errorf("unexpected seats %+v", res.Body)
+	}
+	if _, err := signUp(other, proposed.Body.ID); err == nil {
+		t.Error("expected full session to be rejected")
+	}
+
+	agenda, err := handler.HandleAgenda(context.Background(), &AgendaRequest{Code: event.Code})
+	if err != nil {
+		t.Fatalf("HandleAgenda failed: %v", err)
+	}
+	if len(agenda.Body.Sessions) != 1 || agenda.Body.Sessions[0].Title != "Soldering 101" {
+		t.Errorf("unexpected agenda %+v", agenda.Body.Sessions)
+	}
+	public, _ := json.Marshal(agenda.Body)
+	if strings.Contains(string(public), "review_note") || strings.Contains(string(public), "reviewed_by_id") {
+		t.Errorf("expected the public agenda to leave out the review, got %s", public)
+	}
+
+	withdraw := &SessionIDRequest{ID: proposed.Body.ID}
+	withdraw.Cookie = cookie(early)
+	if _, err := handler.HandleWithdraw(context.Background(), withdraw); err != nil {
+		t.Fatalf("HandleWithdraw failed: %v", err)
+	}
+	if _, err := handler.HandleWithdraw(context.Background(), withdraw); err == nil {
+		t.Error("expected repeated withdrawal to fail")
+	}
+	if _, err := signUp(other, proposed.Body.ID); err != nil {
+		t.Errorf("expected the freed seat to be available: %v", err)
+	}
+}
+
+func TestSessionSignup_StaleAndOutsideStay(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "session-stay")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	org := models.User{DiscordID: "session-stay-org"}
+	db.Create(&org)
+	authHandler, orgCookie := orgAuth(t, db, testCfg, org)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+	sessions := NewSessionHandler(db, authHandler)
+
+	register := func(user models.User, departure int) models.Registration {
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := &RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = today.AddDate(0, 0, 1)
+		req.Body.DepartureDate = today.AddDate(0, 0, departure)
+		if _, err := handler.HandleRegister(context.Background(), req); err != nil {
+			t.Fatalf("HandleRegister failed: %v", err)
+		}
+		var registration models.Registration
+		db.Where("user_id = ?", user.ID).First(&registration)
+		return registration
+	}
+	conflict := func(err error) bool {
+		var statusErr huma.StatusError
+		return errors.As(err, &statusErr) && statusErr.GetStatus() == http.StatusConflict
+	}
+	first := models.User{DiscordID: "session-stay-first"}
+	second := models.User{DiscordID: "session-stay-second"}
+	db.Create(&first)
+	db.Create(&second)
+
+	early := models.Session{EventID: event.ID, Title: "Soldering", Status: models.SessionAccepted, Capacity: 1, StartsAt: today.AddDate(0, 0, 1).Add(14 * time.Hour), EndsAt: today.AddDate(0, 0, 1).Add(16 * time.Hour)}
+	late := models.Session{EventID: event.ID, Title: "Retro", Status: models.SessionAccepted, StartsAt: today.AddDate(0, 0, 3).Add(8 * time.Hour), EndsAt: today.AddDate(0, 0, 3).Add(9 * time.Hour)}
+	db.Create(&early)
+	db.Create(&late)
+
+	registration := register(first, 3)
+	if err := signUpForSession(db, early, registration); err != nil {
+		t.Fatalf("signUpForSession failed: %v", err)
+	}
+	if err := signUpForSession(db, late, registration); err != nil {
+		t.Fatalf("signUpForSession failed: %v", err)
+	}
+
+	// The session was loaded before anybody signed up
+	other := register(second, 3)
+	if err := signUpForSession(db, early, other); !conflict(err) {
+		t.Errorf("expected signups made after loading the session to count, got %v", err)
+	}
+	if err := signUpForSession(db, late, registration); !conflict(err) {
+		t.Errorf("expected repeated signup to conflict, got %v", err)
+	}
+
+	// Moving the session must keep it within the stay of its attendees
+	update := &UpdateSessionRequest{ID: late.ID}
+	update.Cookie = orgCookie
+	update.Body.Title = late.Title
+	update.Body.StartsAt = today.AddDate(0, 0, 5).Add(8 * time.Hour)
+	update.Body.EndsAt = today.AddDate(0, 0, 5).Add(9 * time.Hour)
+	if _, err := sessions.HandleUpdateSession(context.Background(), update); !conflict(err) {
+		t.Errorf("expected moving the session outside of the stay to conflict, got %v", err)
+	}
+	update.Body.StartsAt, update.Body.EndsAt = late.StartsAt, late.EndsAt
+	if _, err := sessions.HandleUpdateSession(context.Background(), update); err != nil {
+		t.Errorf("HandleUpdateSession failed: %v", err)
+	}
+
+	register(first, 2)
+	var signups []models.SessionSignup
+	db.Where("registration_id = ?", registration.ID).Find(&signups)
+	if len(signups) != 1 || signups[0].SessionID != early.ID {
+		t.Errorf("expected only the session within the shorter stay to remain, got %+v", signups)
+	}
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/roster"
+	"gorm.io/gorm"
+)
+
+type ShiftHandler struct {
+	db          *gorm.DB
+	authHandler *auth.AuthHandler
+}
+
+func NewShiftHandler(db *gorm.DB, authHandler *auth.AuthHandler) *ShiftHandler {
+	return &ShiftHandler{db: db, authHandler: authHandler}
+}
+
+type ShiftBody struct {
+	Task     string    `json:"task" doc:"What needs to be done, e.g. cooking dinner"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Required int       `json:"required" minimum:"1" doc:"People needed for the shift"`
+	Note     string    `json:"note,omitempty"`
+}
+
+type CreateShiftRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	Code           string `path:"code" doc:"Event code"`
+	Body           ShiftBody
+}
+
+type UpdateShiftRequest struct {
+	auth.AuthInput `doc:"Restricted to org users"`
+	ID             uint `path:"id" doc:"Shift ID"`
+	Body           ShiftBody
+}
+
+type ShiftIDRequest struct {
+	auth.AuthInput
+	ID uint `path:"id" doc:"Shift ID"`
+}
+
+type EventShiftsRequest struct {
+	auth.AuthInput
+	Code string `path:"code" doc:"Event code"`
+}
+
+type ShiftSignupRequest struct {
+	auth.AuthInput
+	ID   uint `path:"id" doc:"Shift ID"`
+	Body struct {
+		Username string `json:"username,omitempty" doc:"Attendee to put on the shift, defaults to the caller. Only orgs may sign up others."`
+	}
+}
+
+type ShiftSignupIDRequest struct {
+	auth.AuthInput
+	ID       uint `path:"id" doc:"Shift ID"`
+	SignupID uint `path:"signup_id" doc:"Shift signup ID"`
+}
+
+type ShiftVolunteer struct {
+	SignupID       uint   `json:"signup_id"`
+	RegistrationID uint   `json:"registration_id"`
+	Username       string `json:"username"`
+}
+
+type ShiftItem struct {
+	models.Shift
+	Free       int              `json:"free" doc:"People still needed"`
+	Volunteers []ShiftVolunteer `json:"volunteers"`
+}
+
+type ShiftResponse struct {
+	Body ShiftItem
+}
+
+type ListShiftsResponse struct {
+	Body struct {
+		Shifts []ShiftItem `json:"shifts"`
+	}
+}
+
+type ShiftReportResponse struct {
+	Body struct {
+		Duties []roster.Duty `json:"duties" doc:"Shifts per confirmed attendee, the busiest first"`
+	}
+}
+
+func newShiftItem(shift models.Shift) ShiftItem {
+	item := ShiftItem{Shift: shift, Free: shift.Required - len(shift.Signups), Volunteers: make([]ShiftVolunteer, len(shift.Signups))}
+	for i, s := range shift.Signups {
+		item.Volunteers[i] = ShiftVolunteer{SignupID: s.ID, RegistrationID: s.RegistrationID, Username: s.User.Username}
+	}
+	return item
+}
+
+func findShift(db *gorm.DB, id uint) (models.Shift, error) {
+	var shift models.Shift
+	if err := db.Preload("Signups.User").First(&shift, id).Error; err != nil {
+		return shift, huma.Error404NotFound("Shift not found")
+	}
+	return shift, nil
+}
+
+func validateShift(body ShiftBody) error {
+	if strings.TrimSpace(body.Task) == "" || body.Required < 1 {
+		return huma.Error400BadRequest("Task and at least one required person are needed")
+	}
+	if !body.StartsAt.Before(body.EndsAt) {
+		return huma.Error400BadRequest("Shift must end after it starts")
+	}
+	return nil
+}
+
+// signUpForShift validates the registration's stay, the free spots and overlapping shifts and stores the signup
+func signUpForShift(tx *gorm.DB, shift models.Shift, registration models.Registration, assignedByID uint) (models.ShiftSignup, error) {
+	signup := models.ShiftSignup{ShiftID: shift.ID, RegistrationID: registration.ID, UserID: registration.UserID, AssignedByID: assignedByID}
+	if !roster.WithinStay(shift.StartsAt, shift.EndsAt, registration.ArrivalDate, registration.DepartureDate) {
+		return signup, huma.Error409Conflict("Shift is outside of the stay of the attendee")
+	}
+
+	// Count within the transaction, the preloaded signups may be stale by now
+	var signups []models.ShiftSignup
+	if err := tx.Where("shift_id = ?", shift.ID).Find(&signups).Error; err != nil {
+		return signup, huma.Error500InternalServerError("Failed to fetch shift signups: " + err.Error())
+	}
+	for _, s := range signups {
+		if s.RegistrationID == registration.ID {
+			return signup, huma.Error409Conflict("Attendee is already signed up for the shift")
+		}
+	}
+	if len(signups) >= shift.Required {
+		return signup, huma.Error409Conflict("Shift is already full")
+	}
+
+	var taken []models.Shift
+	err := tx.Joins("JOIN shift_signups ON shift_signups.shift_id = shifts.id").
+		Where("shift_signups.registration_id = ? AND shifts.event_id = ?", registration.ID, shift.EventID).
+		Find(&taken).Error
+	if err != nil {
+		return signup, huma.Error500InternalServerError("Failed to fetch shifts: " + err.Error())
+	}
+	if conflicts := roster.Conflicts(shift, taken); len(conflicts) > 0 {
+		c := conflicts[0]
+		return signup, huma.Error409Conflict(fmt.Sprintf("Shift overlaps %s from %s to %s", c.Task, c.StartsAt.Format("2006-01-02 15:04"), c.EndsAt.Format("15:04")))
+	}
+
+	if err := tx.Create(&signup).Error; err != nil {
+		return signup, huma.Error500InternalServerError("Failed to sign up for the shift: " + err.Error())
+	}
+	return signup, nil
+}
+
+// releaseShiftsOutsideStay drops the shift signups of the registration which no longer fit its stay
+func releaseShiftsOutsideStay(tx *gorm.DB, registration models.Registration) error {
+	var taken []models.Shift
+	err := tx.Joins("JOIN shift_signups ON shift_signups.shift_id = shifts.id").
+		Where("shift_signups.registration_id = ?", registration.ID).
+		Find(&taken).Error
+	if err != nil {
+		return err
+	}
+
+	var outside []uint
+	for _, shift := range taken {
+		if !roster.WithinStay(shift.StartsAt, shift.EndsAt, registration.ArrivalDate, registration.DepartureDate) {
+			outside = append(outside, shift.ID)
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	return tx.Where("registration_id = ? AND shift_id IN ?", registration.ID, outside).Delete(&models.ShiftSignup{}).Error
+}
+
+func (h *ShiftHandler) HandleCreateShift(ctx context.Context, input *CreateShiftRequest) (*ShiftResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateShift(input.Body); err != nil {
+		return nil, err
+	}
+
+	shift := models.Shift{
+		EventID:  event.ID,
+		Event:    event.Code,
+		Task:     strings.TrimSpace(input.Body.Task),
+		StartsAt: input.Body.StartsAt,
+		EndsAt:   input.Body.EndsAt,
+		Required: input.Body.Required,
+		Note:     input.Body.Note,
+	}
+	if err := h.db.Create(&shift).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create shift: " + err.Error())
+	}
+	return &ShiftResponse{Body: newShiftItem(shift)}, nil
+}
+
+func (h *ShiftHandler) HandleUpdateShift(ctx context.Context, input *UpdateShiftRequest) (*ShiftResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	shift, err := findShift(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateShift(input.Body); err != nil {
+		return nil, err
+	}
+
+	shift.Task = strings.TrimSpace(input.Body.Task)
+	shift.Note = input.Body.Note
+	moved := !input.Body.StartsAt.Equal(shift.StartsAt) || !input.Body.EndsAt.Equal(shift.EndsAt)
+	shift.StartsAt = input.Body.StartsAt
+	shift.EndsAt = input.Body.EndsAt
+	shift.Required = input.Body.Required
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var signups int64
+		if err := tx.Model(&models.ShiftSignup{}).Where("shift_id = ?", shift.ID).Count(&signups).Error; err != nil {
+			return huma.Error500InternalServerError("Failed to fetch shift signups: " + err.Error())
+		}
+		if int64(shift.Required) < signups {
+			return huma.Error409Conflict(fmt.Sprintf("%d attendees are already signed up for the shift", signups))
+		}
+		if moved && signups > 0 {
+			return huma.Error409Conflict("Shift with volunteers cannot be moved, remove them first")
+		}
+		if err := tx.Omit("Signups").Save(&shift).Error; err != nil {
+			return huma.Error500InternalServerError("Failed to update shift: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err = findShift(h.db, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ShiftResponse{Body: newShiftItem(shift)}, nil
+}
+
+func (h *ShiftHandler) HandleDeleteShift(ctx context.Context, input *ShiftIDRequest) (*struct{}, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	shift, err := findShift(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shift_id = ?", shift.ID).Delete(&models.ShiftSignup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&shift).Error
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete shift: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *ShiftHandler) HandleListShifts(ctx context.Context, input *EventShiftsRequest) (*ListShiftsResponse, error) {
+	if _, err := h.authHandler.Authorize(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var shifts []models.Shift
+	if err := h.db.Preload("Signups.User").Where("event_id = ?", event.ID).Order("starts_at ASC").Find(&shifts).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch shifts: " + err.Error())
+	}
+
+	res := &ListShiftsResponse{}
+	res.Body.Shifts = make([]ShiftItem, len(shifts))
+	for i, shift := range shifts {
+		res.Body.Shifts[i] = newShiftItem(shift)
+	}
+	return res, nil
+}
+
+func (h *ShiftHandler) HandleSignUp(ctx context.Context, input *ShiftSignupRequest) (*ShiftResponse, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err := findShift(h.db, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	volunteerID := userID
+	if input.Body.Username != "" {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error403Forbidden("Only orgs can sign up others")
+		}
+		var user models.User
+		if err := h.db.Where("LOWER(username) = ?", strings.ToLower(strings.TrimSpace(input.Body.Username))).First(&user).Error; err != nil {
+			return nil, huma.Error404NotFound("User " + input.Body.Username + " not found")
+		}
+		volunteerID = user.ID
+	}
+
+	var registration models.Registration
+	if err := h.db.Where("user_id = ? AND event_id = ?", volunteerID, shift.EventID).First(&registration).Error; err != nil {
+		return nil, huma.Error403Forbidden("Attendee is not registered for this event")
+	}
+	if !registration.Confirmed() {
+		return nil, huma.Error409Conflict("Only confirmed registrations can sign up for shifts")
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		_, err := signUpForShift(tx, shift, registration, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err = findShift(h.db, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ShiftResponse{Body: newShiftItem(shift)}, nil
+}
+
+func (h *ShiftHandler) HandleLeaveShift(ctx context.Context, input *ShiftSignupIDRequest) (*struct{}, error) {
+	userID, err := h.authHandler.Authorize(ctx, input.Cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var signup models.ShiftSignup
+	if err := h.db.Where("id = ? AND shift_id = ?", input.SignupID, input.ID).First(&signup).Error; err != nil {
+		return nil, huma.Error404NotFound("Shift signup not found")
+	}
+	if signup.UserID != userID {
+		isOrg, err := h.authHandler.IsOrg(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrg {
+			return nil, huma.Error403Forbidden("Only the volunteer can leave the shift")
+		}
+	}
+
+	if err := h.db.Delete(&signup).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to leave the shift: " + err.Error())
+	}
+	return nil, nil
+}
+
+func (h *ShiftHandler) HandleShiftReport(ctx context.Context, input *EventShiftsRequest) (*ShiftReportResponse, error) {
+	if _, err := h.authHandler.RequireOrg(ctx, input.Cookie); err != nil {
+		return nil, err
+	}
+
+	event, err := findEvent(h.db, input.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var registrations []models.Registration
+	if err := h.db.Preload("User").Where("event_id = ?", event.ID).Find(&registrations).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch registrations: " + err.Error())
+	}
+	var shifts []models.Shift
+	if err := h.db.Preload("Signups").Where("event_id = ?", event.ID).Find(&shifts).Error; err != nil {
+		return nil, huma.Error500InternalServerError("Failed to fetch shifts: " + err.Error())
+	}
+
+	res := &ShiftReportResponse{}
+	res.Body.Duties = roster.Fairness(registrations, shifts)
+	return res, nil
+}
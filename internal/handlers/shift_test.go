@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestShiftSignup(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	event := createTestEvent(t, db, "shift-event")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	register := func(username string, arrival time.Time) models.User {
+		user := models.User{DiscordID: "shift-" + username, Username: username}
+		db.Create(&user)
+		db.Create(&models.Registration{UserID: user.ID, Event: event.Code, EventID: event.ID, RegistrationFields: models.RegistrationFields{
+			ArrivalDate:   arrival,
+			DepartureDate: today.AddDate(0, 0, 3),
+		}})
+		return user
+	}
+	cook := register("cook", today.AddDate(0, 0, 1))
+	helper := register("helper", today.AddDate(0, 0, 1))
+	late := register("late", today.AddDate(0, 0, 2))
+
+	shift := func(task string, day int, from int, to int, required int) models.Shift {
+		s := models.Shift{EventID: event.ID, Event: event.Code, Task: task, Required: required,
+			StartsAt: today.AddDate(0, 0, day).Add(time.Duration(from) * time.Hour),
+			EndsAt:   today.AddDate(0, 0, day).Add(time.Duration(to) * time.Hour),
+		}
+		db.Create(&s)
+		return s
+	}
+	dinner := shift("dinner", 1, 17, 19, 2)
+	dishes := shift("dishes", 1, 18, 20, 1)
+	firewood := shift("firewood", 2, 9, 10, 1)
+
+	authHandler := auth.NewAuthHandler(&config.Config{JWTSecret: "test-secret"}, db, nil)
+	handler := NewShiftHandler(db, authHandler)
+	signUp := func(user models.User, shift models.Shift, username string) (*ShiftResponse, error) {
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := &ShiftSignupRequest{ID: shift.ID}
+		req.Cookie = "auth_token=" + token
+		req.Body.Username = username
+		return handler.HandleSignUp(context.Background(), req)
+	}
+
+	res, err := signUp(cook, dinner, "")
+	if err != nil {
+		t.Fatalf("HandleSignUp failed: %v", err)
+	}
+	if res.Body.Free != 1 || len(res.Body.Volunteers) != 1 || res.Body.Volunteers[0].Username != "cook" {
+		t.Errorf("unexpected shift %+v", res.Body)
+	}
+	if _, err := signUp(cook, dinner, ""); err == nil {
+		t.Error("expected repeated signup to be rejected")
+	}
+	if _, err := signUp(cook, dishes, ""); err == nil {
+		t.Error("expected overlapping shift to be rejected")
+	}
+	if _, err := signUp(late, dinner, ""); err == nil {
+		t.Error("expected shift before the arrival to be rejected")
+	}
+	if _, err := signUp(helper, dinner, "cook"); err == nil {
+		t.Error("expected signing up others to be restricted to orgs")
+	}
+	if _, err := signUp(late, firewood, ""); err != nil {
+		t.Fatalf("HandleSignUp failed: %v", err)
+	}
+	if _, err := signUp(helper, firewood, ""); err == nil {
+		t.Error("expected full shift to be rejected")
+	}
+
+	leave := &ShiftSignupIDRequest{ID: dinner.ID, SignupID: res.Body.Volunteers[0].SignupID}
+	token, _ := authHandler.GenerateToken(helper.ID)
+	leave.Cookie = "auth_token=" + token
+	if _, err := handler.HandleLeaveShift(context.Background(), leave); err == nil {
+		t.Error("expected other attendees to be denied")
+	}
+	token, _ = authHandler.GenerateToken(cook.ID)
+	leave.Cookie = "auth_token=" + token
+	if _, err := handler.HandleLeaveShift(context.Background(), leave); err != nil {
+		t.Fatalf("HandleLeaveShift failed: %v", err)
+	}
+	if _, err := signUp(cook, dishes, ""); err != nil {
+		t.Errorf("expected the shift to be free after leaving the overlapping one: %v", err)
+	}
+}
+
+func TestShiftSignup_StaleAndOutsideStay(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "shift-stay")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	handler := NewRegistrationHandler(db, nil, nil, authHandler, testCfg)
+
+	register := func(user models.User, departure int) models.Registration {
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := &RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = today.AddDate(0, 0, 1)
+		req.Body.DepartureDate = today.AddDate(0, 0, departure)
+		if _, err := handler.HandleRegister(context.Background(), req); err != nil {
+			t.Fatalf("HandleRegister failed: %v", err)
+		}
+		var registration models.Registration
+		db.Where("user_id = ?", user.ID).First(&registration)
+		return registration
+	}
+	first := models.User{DiscordID: "shift-stay-first"}
+	second := models.User{DiscordID: "shift-stay-second"}
+	db.Create(&first)
+	db.Create(&second)
+
+	early := models.Shift{EventID: event.ID, Event: event.Code, Task: "setup", Required: 1, StartsAt: today.AddDate(0, 0, 1).Add(10 * time.Hour), EndsAt: today.AddDate(0, 0, 1).Add(12 * time.Hour)}
+	late := models.Shift{EventID: event.ID, Event: event.Code, Task: "cleanup", Required: 2, StartsAt: today.AddDate(0, 0, 3).Add(8 * time.Hour), EndsAt: today.AddDate(0, 0, 3).Add(9 * time.Hour)}
+	db.Create(&early)
+	db.Create(&late)
+
+	registration := register(first, 3)
+	if _, err := signUpForShift(db, early, registration, first.ID); err != nil {
+		t.Fatalf("signUpForShift failed: %v", err)
+	}
+	if _, err := signUpForShift(db, late, registration, first.ID); err != nil {
+		t.Fatalf("signUpForShift failed: %v", err)
+	}
+
+	// The shift was loaded before anybody signed up
+	other := register(second, 3)
+	if _, err := signUpForShift(db, early, other, second.ID); err == nil {
+		t.Error("expected signups made after loading the shift to count")
+	}
+
+	register(first, 2)
+	var signups []models.ShiftSignup
+	db.Where("registration_id = ?", registration.ID).Find(&signups)
+	if len(signups) != 1 || signups[0].ShiftID != early.ID {
+		t.Errorf("expected only the shift within the shorter stay to remain, got %+v", signups)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// saveHistory stores a snapshot of the registration's current fields
+func saveHistory(tx *gorm.DB, registration models.Registration) (models.RegistrationHistory, error) {
+	history := models.RegistrationHistory{
+		RegistrationID:     registration.ID,
+		UserID:             registration.UserID,
+		Event:              registration.Event,
+		EventID:            registration.EventID,
+		RegistrationFields: registration.RegistrationFields,
+		Model:              gorm.Model{CreatedAt: time.Now()}, // Ensure CreatedAt is set
+	}
+	err := tx.Create(&history).Error
+	return history, err
+}
+
+// eventOccupancy returns the headcount of confirmed registrations for the event,
+// leaving out the registration with excludeID
+func eventOccupancy(tx *gorm.DB, eventID uint, excludeID uint) (adults int, children int, err error) {
+	var registrations []models.Registration
+	if err := tx.Where("event_id = ? AND cancelled = ? AND waitlisted = ? AND id <> ?", eventID, false, false, excludeID).Find(&registrations).Error; err != nil {
+		return 0, 0, err
+	}
+	for _, r := range registrations {
+		adults += r.Adults()
+		children += r.Children()
+	}
+	return adults, children, nil
+}
+
+// fitsEvent reports whether the registration fits into the remaining event capacity
+func fitsEvent(tx *gorm.DB, event models.Event, registration models.Registration) (bool, error) {
+	adults, children, err := eventOccupancy(tx, event.ID, registration.ID)
+	if err != nil {
+		return false, err
+	}
+	return event.HasCapacity(adults+registration.Adults(), children+registration.Children()), nil
+}
+
+// waitlistAhead reports whether somebody joined the waitlist before the registration
+func waitlistAhead(tx *gorm.DB, event models.Event, registration models.Registration) (bool, error) {
+	query := tx.Model(&models.Registration{}).Where("event_id = ? AND waitlisted = ? AND cancelled = ? AND id <> ?", event.ID, true, false, registration.ID)
+	if registration.WaitlistedAt != nil {
+		query = query.Where("waitlisted_at < ?", *registration.WaitlistedAt)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// promoteWaitlisted confirms waitlisted registrations in the order they joined
+// the waitlist for as long as they fit into the event capacity
+func promoteWaitlisted(tx *gorm.DB, event models.Event) ([]models.Registration, error) {
+	var waitlisted []models.Registration
+	if err := tx.Where("event_id = ? AND waitlisted = ? AND cancelled = ?", event.ID, true, false).Order("waitlisted_at ASC, id ASC").Find(&waitlisted).Error; err != nil {
+		return nil, err
+	}
+
+	var promoted []models.Registration
+	for _, registration := range waitlisted {
+		fits, err := fitsEvent(tx, event, registration)
+		if err != nil {
+			return nil, err
+		}
+		if !fits {
+			// Strict FIFO, nobody skips the queue
+			break
+		}
+
+		registration.Waitlisted = false
+		registration.WaitlistedAt = nil
+		if err := tx.Save(&registration).Error; err != nil {
+			return nil, err
+		}
+		if _, err := saveHistory(tx, registration); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, registration)
+	}
+
+	return promoted, nil
+}
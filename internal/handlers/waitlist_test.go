@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/auth"
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandleRegister_Waitlist(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	testCfg := &config.Config{JWTSecret: "test-secret"}
+	event := createTestEvent(t, db, "full-event")
+	db.Model(&event).Updates(map[string]interface{}{"capacity_adults": 1, "capacity_children": 1})
+
+	authHandler := auth.NewAuthHandler(testCfg, db, nil)
+	notifier := &fakeNotifier{}
+	handler := NewRegistrationHandler(db, notifier, notifier, authHandler, testCfg)
+
+	register := func(t *testing.T, user models.User, children int, cancelled bool) *RegistrationResponse {
+		t.Helper()
+		token, _ := authHandler.GenerateToken(user.ID)
+		req := RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ArrivalDate = time.Now().Add(24 * time.Hour)
+		req.Body.DepartureDate = time.Now().Add(48 * time.Hour)
+		req.Body.ChildrenCount = children
+		req.Body.Cancelled = cancelled
+		resp, err := handler.HandleRegister(context.Background(), &req)
+		if err != nil {
+			t.Fatalf("HandleRegister failed: %v", err)
+		}
+		return resp
+	}
+
+	users := make([]models.User, 3)
+	for i := range users {
+		users[i] = models.User{DiscordID: "waitlist-user-" + string(rune('a'+i))}
+		db.Create(&users[i])
+	}
+
+	if resp := register(t, users[0], 0, false); resp.Body.Waitlisted {
+		t.Fatal("expected first registration to be confirmed")
+	}
+	if resp := register(t, users[1], 0, false); !resp.Body.Waitlisted {
+		t.Fatal("expected second registration to be waitlisted")
+	}
+	if resp := register(t, users[2], 0, false); !resp.Body.Waitlisted {
+		t.Fatal("expected third registration to be waitlisted")
+	}
+
+	t.Run("ConfirmedCannotExceedCapacity", func(t *testing.T) {
+		token, _ := authHandler.GenerateToken(users[0].ID)
+		req := RegistrationRequest{}
+		req.Cookie = "auth_token=" + token
+		req.Body.Event = event.Code
+		req.Body.ChildrenCount = 2
+		if _, err := handler.HandleRegister(context.Background(), &req); err == nil {
+			t.Error("expected capacity error, got nil")
+		}
+	})
+
+	t.Run("CancellationPromotesOldest", func(t *testing.T) {
+		register(t, users[0], 0, true)
+
+		var second, third models.Registration
+		db.Where("user_id = ?", users[1].ID).First(&second)
+		db.Where("user_id = ?", users[2].ID).First(&third)
+
+		if !second.Confirmed() {
+			t.Error("expected oldest waitlisted registration to be promoted")
+		}
+		if second.WaitlistedAt != nil {
+			t.Error("expected promoted registration to have no waitlisted_at")
+		}
+		if !third.Waitlisted {
+			t.Error("expected newer waitlisted registration to stay on the waitlist")
+		}
+
+		// Notifications go out through the outbox once the change is committed
+		notifier.deliver(t, db)
+		if len(notifier.promotions) != 1 || notifier.promotions[0].UserID != users[1].ID {
+			t.Errorf("expected one promotion notification for user %d, got %+v", users[1].ID, notifier.promotions)
+		}
+
+		var histories []models.RegistrationHistory
+		db.Where("registration_id = ?", second.ID).Order("id asc").Find(&histories)
+		if len(histories) != 2 {
+			t.Fatalf("expected 2 history entries for promoted registration, got %d", len(histories))
+		}
+		if !histories[0].Waitlisted || histories[1].Waitlisted {
+			t.Error("expected history to record the promotion from the waitlist")
+		}
+	})
+
+	t.Run("ReRegistrationJoinsBackOfQueue", func(t *testing.T) {
+		if resp := register(t, users[0], 0, false); !resp.Body.Waitlisted {
+			t.Error("expected re-registration to be waitlisted behind existing waitlist")
+		}
+	})
+}
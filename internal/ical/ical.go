@@ -0,0 +1,104 @@
+// Package ical writes RFC 5545 calendars for calendar clients subscribing to feeds
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProdID identifies the API as the producer of the calendars
+const ProdID = "-//GDG Garage//Garage Trip API//EN"
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Event is a single VEVENT, all day events span whole days and their end is the last day
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// Escape escapes a TEXT value
+func Escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// fold splits a content line into lines of at most 75 octets, continuation lines start with a space
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the length of continuation lines
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// Write writes the calendar with its events, stamped with the given time
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(l string) {
+		bw.WriteString(fold(l))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + ProdID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + Escape(name))
+	}
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp.UTC().Format(dateTimeFormat))
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:" + e.Start.Format(dateFormat))
+			// The end of all day events is exclusive
+			line("DTEND;VALUE=DATE:" + e.End.AddDate(0, 0, 1).Format(dateFormat))
+		} else {
+			line("DTSTART:" + e.Start.UTC().Format(dateTimeFormat))
+			line("DTEND:" + e.End.UTC().Format(dateTimeFormat))
+		}
+		line("SUMMARY:" + Escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + Escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION:" + Escape(e.Location))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
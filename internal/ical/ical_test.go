@@ -0,0 +1,51 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	stamp := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{UID: "registration-1@garage-trip", Summary: "Garage Trip", Start: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC), AllDay: true},
+		{UID: "session-2@garage-trip", Summary: "Soldering, part 1", Location: "Barn; upstairs", Start: time.Date(2025, 7, 2, 16, 0, 0, 0, time.FixedZone("CEST", 2*3600)), End: time.Date(2025, 7, 2, 18, 0, 0, 0, time.FixedZone("CEST", 2*3600))},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "Garage Trip", events, stamp); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"DTSTART;VALUE=DATE:20250701\r\nDTEND;VALUE=DATE:20250704\r\n",
+		"DTSTART:20250702T140000Z\r\n",
+		"SUMMARY:Soldering\\, part 1\r\n",
+		"LOCATION:Barn\\; upstairs\r\n",
+		"DTSTAMP:20250601T120000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("žluťoučký kůň ", 10)
+	folded := fold(line)
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > maxLineOctets {
+			t.Errorf("line longer than %d octets: %q", maxLineOctets, l)
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != line {
+		t.Error("expected unfolding to restore the line")
+	}
+	if Escape("a\\b\nc") != `a\\b\nc` {
+		t.Errorf("unexpected escaping %q", Escape("a\\b\nc"))
+	}
+}
@@ -0,0 +1,274 @@
+package lodging
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+const (
+	PreferenceRoom     = "room"
+	PreferenceRoommate = "roommate"
+	PreferenceQuiet    = "quiet_room"
+	PreferenceNoStairs = "no_stairs"
+)
+
+// Guest is a confirmed registration with its room preferences
+type Guest struct {
+	RegistrationID   uint
+	Username         string
+	Stay             Stay
+	Roommates        []uint // Registration IDs of preferred roommates
+	UnknownRoommates []string
+	Quiet            bool
+	NoStairs         bool
+}
+
+// RoomState is a room with the stays already assigned to it
+type RoomState struct {
+	Room  models.Room
+	Stays []Stay
+}
+
+// Placement puts a registration into a room for its whole stay
+type Placement struct {
+	RegistrationID uint
+	RoomID         uint
+}
+
+// Issue is a preference the room plan does not satisfy
+type Issue struct {
+	RegistrationID uint   `json:"registration_id"`
+	Username       string `json:"username"`
+	Preference     string `json:"preference" enum:"room,roommate,quiet_room,no_stairs"`
+	Detail         string `json:"detail"`
+}
+
+type allocation struct {
+	rooms  []RoomState
+	roomOf map[uint][]uint // Registration ID to the rooms it sleeps in
+}
+
+func (a *allocation) fits(room int, guests []Guest) bool {
+	stays := slices.Clone(a.rooms[room].Stays)
+	for _, g := range guests {
+		if g.NoStairs && a.rooms[room].Room.Stairs {
+			return false
+		}
+		stays = append(stays, g.Stay)
+	}
+	_, _, overbooked := Overbooked(a.rooms[room].Room.Beds, stays)
+	return !overbooked
+}
+
+// score rates placing the guests into the room, higher is better
+func (a *allocation) score(room int, guests []Guest) int {
+	r := a.rooms[room].Room
+	score := 0
+	needsNoStairs, wantsQuiet := false, false
+	for _, g := range guests {
+		for _, mate := range g.Roommates {
+			if slices.Contains(a.roomOf[mate], r.ID) {
+				score += 1000
+			}
+		}
+		if g.Quiet {
+			wantsQuiet = true
+			if r.Quiet {
+				score += 100
+			}
+		}
+		needsNoStairs = needsNoStairs || g.NoStairs
+	}
+	// Keep rooms without stairs and quiet rooms for those who need them
+	if !r.Stairs && !needsNoStairs {
+		score -= 20
+	}
+	if r.Quiet && !wantsQuiet {
+		score -= 10
+	}
+
+	// Prefer the tightest fit so big rooms stay available for big groups
+	occupancy := Occupancy(append(slices.Clone(a.rooms[room].Stays), stays(guests)...))
+	peak := 0
+	for _, people := range occupancy {
+		peak = max(peak, people)
+	}
+	return score - (r.Beds - peak)
+}
+
+func (a *allocation) best(guests []Guest) (int, bool) {
+	best, bestScore, found := 0, 0, false
+	for i := range a.rooms {
+		if !a.fits(i, guests) {
+			continue
+		}
+		if s := a.score(i, guests); !found || s > bestScore {
+			best, bestScore, found = i, s, true
+		}
+	}
+	return best, found
+}
+
+func (a *allocation) place(room int, guests []Guest) []Placement {
+	placements := make([]Placement, len(guests))
+	for i, g := range guests {
+		a.rooms[room].Stays = append(a.rooms[room].Stays, g.Stay)
+		a.roomOf[g.RegistrationID] = append(a.roomOf[g.RegistrationID], a.rooms[room].Room.ID)
+		placements[i] = Placement{RegistrationID: g.RegistrationID, RoomID: a.rooms[room].Room.ID}
+	}
+	return placements
+}
+
+func stays(guests []Guest) []Stay {
+	s := make([]Stay, len(guests))
+	for i, g := range guests {
+		s[i] = g.Stay
+	}
+	return s
+}
+
+// groups joins guests who want to share a room, directly or through others
+func groups(guests []Guest) [][]Guest {
+	parent := make(map[uint]uint, len(guests))
+	for _, g := range guests {
+		parent[g.RegistrationID] = g.RegistrationID
+	}
+	var find func(uint) uint
+	find = func(id uint) uint {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, g := range guests {
+		for _, mate := range g.Roommates {
+			if _, ok := parent[mate]; ok {
+				parent[find(mate)] = find(g.RegistrationID)
+			}
+		}
+	}
+
+	byRoot := make(map[uint][]Guest)
+	var roots []uint
+	for _, g := range guests {
+		root := find(g.RegistrationID)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], g)
+	}
+	result := make([][]Guest, len(roots))
+	for i, root := range roots {
+		result[i] = byRoot[root]
+	}
+	return result
+}
+
+func people(guests []Guest) int {
+	total := 0
+	for _, g := range guests {
+		total += g.Stay.People
+	}
+	return total
+}
+
+func needsNoStairs(guests []Guest) bool {
+	return slices.ContainsFunc(guests, func(g Guest) bool { return g.NoStairs })
+}
+
+// byConstraints orders the hardest to place first: guests needing a room without stairs, then larger parties
+func byConstraints(a, b []Guest) int {
+	if na, nb := needsNoStairs(a), needsNoStairs(b); na != nb {
+		if na {
+			return -1
+		}
+		return 1
+	}
+	if c := cmp.Compare(people(b), people(a)); c != 0 {
+		return c
+	}
+	return cmp.Compare(a[0].RegistrationID, b[0].RegistrationID)
+}
+
+// Allocate proposes rooms for the guests on top of the existing room stays. Groups of guests
+// who want to share a room are kept together when a room fits them all, otherwise they are
+// spread over rooms one by one. Guests needing a room without stairs never get one with stairs.
+// Guests who fit nowhere are returned as unplaced.
+func Allocate(rooms []RoomState, guests []Guest, assigned map[uint][]uint) (placements []Placement, unplaced []Guest) {
+	a := &allocation{rooms: make([]RoomState, len(rooms)), roomOf: make(map[uint][]uint)}
+	for i, r := range rooms {
+		a.rooms[i] = RoomState{Room: r.Room, Stays: slices.Clone(r.Stays)}
+	}
+	for id, roomIDs := range assigned {
+		a.roomOf[id] = slices.Clone(roomIDs)
+	}
+
+	all := groups(guests)
+	for _, g := range all {
+		slices.SortFunc(g, func(x, y Guest) int { return byConstraints([]Guest{x}, []Guest{y}) })
+	}
+	slices.SortFunc(all, byConstraints)
+
+	for _, group := range all {
+		if room, ok := a.best(group); ok {
+			placements = append(placements, a.place(room, group)...)
+			continue
+		}
+		for _, g := range group {
+			room, ok := a.best([]Guest{g})
+			if !ok {
+				unplaced = append(unplaced, g)
+				continue
+			}
+			placements = append(placements, a.place(room, []Guest{g})...)
+		}
+	}
+	return placements, unplaced
+}
+
+// Unsatisfied reports the preferences of the guests not met by the rooms they sleep in
+func Unsatisfied(rooms []models.Room, guests []Guest, roomOf map[uint][]uint) []Issue {
+	roomsByID := make(map[uint]models.Room, len(rooms))
+	for _, r := range rooms {
+		roomsByID[r.ID] = r
+	}
+	usernames := make(map[uint]string, len(guests))
+	for _, g := range guests {
+		usernames[g.RegistrationID] = g.Username
+	}
+
+	issues := []Issue{}
+	for _, g := range guests {
+		issue := func(preference string, format string, args ...any) {
+			issues = append(issues, Issue{RegistrationID: g.RegistrationID, Username: g.Username, Preference: preference, Detail: fmt.Sprintf(format, args...)})
+		}
+
+		own := roomOf[g.RegistrationID]
+		if len(own) == 0 {
+			if len(Nights(g.Stay.From, g.Stay.To)) > 0 {
+				issue(PreferenceRoom, "No room with enough free beds")
+			}
+			continue
+		}
+
+		for _, mate := range g.Roommates {
+			shared := slices.ContainsFunc(roomOf[mate], func(id uint) bool { return slices.Contains(own, id) })
+			if !shared {
+				issue(PreferenceRoommate, "Not in the same room as %s", usernames[mate])
+			}
+		}
+		for _, name := range g.UnknownRoommates {
+			issue(PreferenceRoommate, "%s is not registered for the event", name)
+		}
+		if g.Quiet && slices.ContainsFunc(own, func(id uint) bool { return !roomsByID[id].Quiet }) {
+			issue(PreferenceQuiet, "Not in a quiet room")
+		}
+		if g.NoStairs && slices.ContainsFunc(own, func(id uint) bool { return roomsByID[id].Stairs }) {
+			issue(PreferenceNoStairs, "In a room with stairs")
+		}
+	}
+	return issues
+}
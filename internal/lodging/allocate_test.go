@@ -0,0 +1,101 @@
+package lodging
+
+import (
+	"testing"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func guest(id uint, people int, roommates ...uint) Guest {
+	return Guest{RegistrationID: id, Stay: Stay{From: date(1), To: date(3), People: people}, Roommates: roommates}
+}
+
+func placedIn(placements []Placement) map[uint][]uint {
+	roomOf := make(map[uint][]uint)
+	for _, p := range placements {
+		roomOf[p.RegistrationID] = append(roomOf[p.RegistrationID], p.RoomID)
+	}
+	return roomOf
+}
+
+func TestAllocate_KeepsRoommatesTogether(t *testing.T) {
+	rooms := []RoomState{
+		{Room: models.Room{Name: "A", Beds: 2, Stairs: true}},
+		{Room: models.Room{Name: "B", Beds: 3, Stairs: true}},
+	}
+	rooms[0].Room.ID, rooms[1].Room.ID = 1, 2
+
+	guests := []Guest{guest(10, 1, 12), guest(11, 1), guest(12, 2)}
+	placements, unplaced := Allocate(rooms, guests, nil)
+	if len(unplaced) != 0 {
+		t.Fatalf("expected everybody to be placed, got %v unplaced", unplaced)
+	}
+
+	roomOf := placedIn(placements)
+	if roomOf[10][0] != 2 || roomOf[12][0] != 2 {
+		t.Errorf("expected roommates in the big room, got %v", roomOf)
+	}
+	if roomOf[11][0] != 1 {
+		t.Errorf("expected the single guest in the small room, got %v", roomOf)
+	}
+	if issues := Unsatisfied([]models.Room{rooms[0].Room, rooms[1].Room}, guests, roomOf); len(issues) != 0 {
+		t.Errorf("expected all preferences met, got %v", issues)
+	}
+}
+
+func TestAllocate_ConstraintsAndCapacity(t *testing.T) {
+	upstairs := models.Room{Name: "Upstairs", Beds: 2, Stairs: true, Quiet: true}
+	ground := models.Room{Name: "Ground", Beds: 1}
+	upstairs.ID, ground.ID = 1, 2
+	rooms := []RoomState{{Room: upstairs}, {Room: ground}}
+
+	accessible := guest(1, 1)
+	accessible.NoStairs = true
+	quiet := guest(2, 1)
+	quiet.Quiet = true
+	family := guest(3, 3)
+	noStairsToo := guest(4, 1)
+	noStairsToo.NoStairs = true
+
+	guests := []Guest{quiet, family, accessible, noStairsToo}
+	placements, unplaced := Allocate(rooms, guests, nil)
+	roomOf := placedIn(placements)
+
+	if len(roomOf[1]) != 1 || roomOf[1][0] != ground.ID {
+		t.Errorf("expected the guest needing no stairs on the ground floor, got %v", roomOf[1])
+	}
+	if len(roomOf[2]) != 1 || roomOf[2][0] != upstairs.ID {
+		t.Errorf("expected the quiet guest upstairs, got %v", roomOf[2])
+	}
+	if len(unplaced) != 2 {
+		t.Errorf("expected the family and the second guest needing no stairs to be unplaced, got %v", unplaced)
+	}
+	for _, p := range placements {
+		if p.RegistrationID == 4 && p.RoomID == upstairs.ID {
+			t.Error("guest needing no stairs must never be placed upstairs")
+		}
+	}
+
+	issues := Unsatisfied([]models.Room{upstairs, ground}, guests, roomOf)
+	if len(issues) != 2 || issues[0].Preference != PreferenceRoom {
+		t.Errorf("expected two unplaced guests reported, got %v", issues)
+	}
+}
+
+func TestAllocate_RespectsExistingStays(t *testing.T) {
+	room := models.Room{Name: "A", Beds: 2}
+	room.ID = 1
+	rooms := []RoomState{{Room: room, Stays: []Stay{{From: date(1), To: date(2), People: 2}}}}
+
+	// Full on the 1st, free from the 2nd
+	early := Guest{RegistrationID: 1, Stay: Stay{From: date(1), To: date(3), People: 1}}
+	late := Guest{RegistrationID: 2, Stay: Stay{From: date(2), To: date(4), People: 2}, Roommates: []uint{99}}
+	placements, unplaced := Allocate(rooms, []Guest{early, late}, map[uint][]uint{99: {1}})
+
+	if len(placements) != 1 || placements[0].RegistrationID != 2 {
+		t.Errorf("expected only the late guest to fit, got %v", placements)
+	}
+	if len(unplaced) != 1 || unplaced[0].RegistrationID != 1 {
+		t.Errorf("expected the early guest unplaced, got %v", unplaced)
+	}
+}
@@ -0,0 +1,77 @@
+// Package lodging computes room occupancy per night
+package lodging
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+// Day truncates the time to its calendar day, nights are identified by the day they start
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Nights lists the nights between from and to, the night starting on to is not included
+func Nights(from time.Time, to time.Time) []time.Time {
+	var nights []time.Time
+	for night := Day(from); night.Before(Day(to)); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+	return nights
+}
+
+// Stay is a group of people sleeping in a room for a range of nights
+type Stay struct {
+	From   time.Time
+	To     time.Time
+	People int
+}
+
+// AssignmentStay returns the nights the assignment actually occupies. The range is
+// limited by the registration's current stay and cancelled or waitlisted registrations
+// occupy nothing.
+func AssignmentStay(assignment models.RoomAssignment) Stay {
+	registration := assignment.Registration
+	if !registration.Confirmed() {
+		return Stay{}
+	}
+
+	from, to := Day(assignment.FromDate), Day(assignment.ToDate)
+	if arrival := Day(registration.ArrivalDate); arrival.After(from) {
+		from = arrival
+	}
+	if departure := Day(registration.DepartureDate); departure.Before(to) {
+		to = departure
+	}
+	return Stay{From: from, To: to, People: registration.Adults() + registration.Children()}
+}
+
+// Occupancy counts people per night
+func Occupancy(stays []Stay) map[time.Time]int {
+	occupancy := make(map[time.Time]int)
+	for _, s := range stays {
+		for _, night := range Nights(s.From, s.To) {
+			occupancy[night] += s.People
+		}
+	}
+	return occupancy
+}
+
+// Overbooked returns the first night when more people than beds sleep in the room
+func Overbooked(beds int, stays []Stay) (night time.Time, people int, overbooked bool) {
+	occupancy := Occupancy(stays)
+	nights := make([]time.Time, 0, len(occupancy))
+	for n := range occupancy {
+		nights = append(nights, n)
+	}
+	slices.SortFunc(nights, time.Time.Compare)
+
+	for _, n := range nights {
+		if occupancy[n] > beds {
+			return n, occupancy[n], true
+		}
+	}
+	return time.Time{}, 0, false
+}
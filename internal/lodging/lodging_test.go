@@ -0,0 +1,57 @@
+package lodging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func date(day int) time.Time {
+	return time.Date(2025, 7, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNights(t *testing.T) {
+	nights := Nights(date(1).Add(15*time.Hour), date(4))
+	if len(nights) != 3 || !nights[0].Equal(date(1)) || !nights[2].Equal(date(3)) {
+		t.Errorf("unexpected nights %v", nights)
+	}
+	if len(Nights(date(4), date(1))) != 0 {
+		t.Error("expected no nights for reversed range")
+	}
+}
+
+func TestAssignmentStay(t *testing.T) {
+	registration := models.Registration{RegistrationFields: models.RegistrationFields{
+		ArrivalDate:   date(2),
+		DepartureDate: date(4),
+		ChildrenCount: 2,
+	}}
+	assignment := models.RoomAssignment{FromDate: date(1), ToDate: date(5), Registration: registration}
+
+	stay := AssignmentStay(assignment)
+	if !stay.From.Equal(date(2)) || !stay.To.Equal(date(4)) || stay.People != 3 {
+		t.Errorf("expected stay limited to the registration with 3 people, got %+v", stay)
+	}
+
+	assignment.Registration.Cancelled = true
+	if stay := AssignmentStay(assignment); stay.People != 0 || len(Nights(stay.From, stay.To)) != 0 {
+		t.Errorf("expected cancelled registration to occupy nothing, got %+v", stay)
+	}
+}
+
+func TestOverbooked(t *testing.T) {
+	stays := []Stay{
+		{From: date(1), To: date(3), People: 2},
+		{From: date(3), To: date(5), People: 2},
+	}
+	if _, _, overbooked := Overbooked(2, stays); overbooked {
+		t.Error("expected consecutive stays to share the beds")
+	}
+
+	stays = append(stays, Stay{From: date(2), To: date(4), People: 1})
+	night, people, overbooked := Overbooked(2, stays)
+	if !overbooked || !night.Equal(date(2)) || people != 3 {
+		t.Errorf("expected overbooking on the 2nd with 3 people, got %v %d %v", night, people, overbooked)
+	}
+}
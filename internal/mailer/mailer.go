@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// Sender delivers plain text emails
+type Sender interface {
+	Send(to string, subject string, body string) error
+}
+
+// SMTPSender sends emails through an SMTP server, authentication is skipped without a username
+// so it can be pointed at a local test server
+type SMTPSender struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPSender(host string, port string, username string, password string, from string) *SMTPSender {
+	return &SMTPSender{addr: net.JoinHostPort(host, port), host: host, username: username, password: password, from: from}
+}
+
+func (s *SMTPSender) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+	if err := smtp.SendMail(s.addr, auth, s.from, []string{to}, Message(s.from, to, subject, body, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", to, err)
+	}
+	return nil
+}
+
+// Message formats a plain text email with CRLF line endings
+func Message(from string, to string, subject string, body string, date time.Time) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(bytes.ReplaceAll(bytes.ReplaceAll([]byte(body), []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n")))
+	return msg.Bytes()
+}
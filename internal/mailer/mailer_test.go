@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts a single message and returns its envelope and data
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	received := make(chan []string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				lines = append(lines, cmd)
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				received <- lines
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	sender := NewSMTPSender(host, port, "", "", "trip@example.com")
+	if err := sender.Send("guest@example.com", "Sign in", "Hello\nhttps://example.com/login"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	select {
+	case lines := <-received:
+		got := strings.Join(lines, "\n")
+		for _, want := range []string{"MAIL FROM:<trip@example.com>", "RCPT TO:<guest@example.com>", "Subject: Sign in", "Hello\nhttps://example.com/login"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected message to contain %q, got:\n%s", want, got)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not receive the message")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	msg := string(Message("a@example.com", "b@example.com", "Přihlášení", "one\ntwo", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)))
+	if !strings.Contains(msg, "Subject: =?utf-8?q?") {
+		t.Errorf("expected encoded subject, got %s", msg)
+	}
+	if !strings.HasSuffix(msg, "\r\n\r\none\r\ntwo") {
+		t.Errorf("expected CRLF line endings in the body, got %q", msg)
+	}
+}
@@ -0,0 +1,178 @@
+// Package meals computes which meals attendees eat and headcounts for the kitchen
+package meals
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+const (
+	Breakfast = "breakfast"
+	Lunch     = "lunch"
+	Dinner    = "dinner"
+)
+
+// Meals lists the meals of a day in the order they are served
+var Meals = []string{Breakfast, Lunch, Dinner}
+
+// Slot is a single meal on a day
+type Slot = models.MealSlot
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Contains reports whether the slot is in the list
+func Contains(slots []Slot, slot Slot) bool {
+	return slices.ContainsFunc(slots, func(s Slot) bool {
+		return s.Meal == slot.Meal && day(s.Day).Equal(day(slot.Day))
+	})
+}
+
+// Within keeps the slots served during the stay, normalized to whole days and without duplicates
+func Within(slots []Slot, arrival time.Time, departure time.Time) []Slot {
+	served := Served(arrival, departure)
+	var within []Slot
+	for _, s := range slots {
+		if Contains(served, s) && !Contains(within, s) {
+			within = append(within, Slot{Day: day(s.Day), Meal: s.Meal})
+		}
+	}
+	return within
+}
+
+// Changed returns the slots present in only one of the lists
+func Changed(a []Slot, b []Slot) []Slot {
+	var changed []Slot
+	for _, s := range a {
+		if !Contains(b, s) {
+			changed = append(changed, s)
+		}
+	}
+	for _, s := range b {
+		if !Contains(a, s) {
+			changed = append(changed, s)
+		}
+	}
+	return changed
+}
+
+// Served lists the meals of a stay: dinner on the arrival day, all meals on full days and
+// breakfast on the departure day. Attendees arriving and leaving on the same day get lunch.
+func Served(arrival time.Time, departure time.Time) []Slot {
+	first, last := day(arrival), day(departure)
+	if last.Before(first) {
+		return nil
+	}
+	if first.Equal(last) {
+		return []Slot{{Day: first, Meal: Lunch}}
+	}
+
+	slots := []Slot{{Day: first, Meal: Dinner}}
+	for d := first.AddDate(0, 0, 1); d.Before(last); d = d.AddDate(0, 0, 1) {
+		for _, meal := range Meals {
+			slots = append(slots, Slot{Day: d, Meal: meal})
+		}
+	}
+	return append(slots, Slot{Day: last, Meal: Breakfast})
+}
+
+// Diner is a party eating together, dietary tags apply to all of its people
+type Diner struct {
+	Adults    int
+	Children  int
+	Tags      []string
+	Arrival   time.Time
+	Departure time.Time
+	Skipped   []Slot
+}
+
+// RegistrationDiners returns the parties of a registration, the attendee with their unnamed
+// children and each companion with their own dietary tags
+func RegistrationDiners(registration models.Registration) []Diner {
+	diners := []Diner{{
+		Adults:    1,
+		Children:  registration.ChildrenCount,
+		Tags:      registration.DietaryTags,
+		Arrival:   registration.ArrivalDate,
+		Departure: registration.DepartureDate,
+		Skipped:   registration.SkippedMeals,
+	}}
+	for _, c := range registration.Companions {
+		diner := Diner{Adults: 1, Tags: c.DietaryTags, Arrival: registration.ArrivalDate, Departure: registration.DepartureDate, Skipped: registration.SkippedMeals}
+		if c.Child() {
+			diner.Adults, diner.Children = 0, 1
+		}
+		diners = append(diners, diner)
+	}
+	return diners
+}
+
+// Headcount is the number of people eating a meal
+type Headcount struct {
+	Slot
+	Adults   int            `json:"adults"`
+	Children int            `json:"children"`
+	Total    int            `json:"total"`
+	Tags     map[string]int `json:"tags" doc:"People per dietary tag"`
+}
+
+// Report counts the people eating each meal, ordered by day and meal. Skipped meals are not counted.
+func Report(diners []Diner) []Headcount {
+	counts := make(map[Slot]*Headcount)
+	for _, d := range diners {
+		for _, slot := range Served(d.Arrival, d.Departure) {
+			if Contains(d.Skipped, slot) {
+				continue
+			}
+			c, ok := counts[slot]
+			if !ok {
+				c = &Headcount{Slot: slot, Tags: make(map[string]int)}
+				counts[slot] = c
+			}
+			c.Adults += d.Adults
+			c.Children += d.Children
+			c.Total += d.Adults + d.Children
+			for _, tag := range d.Tags {
+				c.Tags[tag] += d.Adults + d.Children
+			}
+		}
+	}
+
+	rows := make([]Headcount, 0, len(counts))
+	for _, c := range counts {
+		rows = append(rows, *c)
+	}
+	slices.SortFunc(rows, func(a, b Headcount) int {
+		if c := a.Day.Compare(b.Day); c != 0 {
+			return c
+		}
+		return slices.Index(Meals, a.Meal) - slices.Index(Meals, b.Meal)
+	})
+	return rows
+}
+
+// WriteCSV writes the report with a column for each dietary tag
+func WriteCSV(w io.Writer, rows []Headcount) error {
+	out := csv.NewWriter(w)
+	header := append([]string{"date", "meal", "adults", "children", "total"}, models.DietaryTags...)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{r.Day.Format("2006-01-02"), r.Meal, strconv.Itoa(r.Adults), strconv.Itoa(r.Children), strconv.Itoa(r.Total)}
+		for _, tag := range models.DietaryTags {
+			record = append(record, strconv.Itoa(r.Tags[tag]))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
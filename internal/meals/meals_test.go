@@ -0,0 +1,117 @@
+package meals
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func date(d int) time.Time {
+	return time.Date(2025, 7, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestServed(t *testing.T) {
+	slots := Served(date(1).Add(18*time.Hour), date(3))
+	want := []Slot{
+		{Day: date(1), Meal: Dinner},
+		{Day: date(2), Meal: Breakfast}, {Day: date(2), Meal: Lunch}, {Day: date(2), Meal: Dinner},
+		{Day: date(3), Meal: Breakfast},
+	}
+	if len(slots) != len(want) {
+		t.Fatalf("expected %d meals, got %v", len(want), slots)
+	}
+	for i := range want {
+		if !slots[i].Day.Equal(want[i].Day) || slots[i].Meal != want[i].Meal {
+			t.Errorf("meal %d: expected %v, got %v", i, want[i], slots[i])
+		}
+	}
+
+	if day := Served(date(1), date(1)); len(day) != 1 || day[0].Meal != Lunch {
+		t.Errorf("expected lunch for a single day visit, got %v", day)
+	}
+	if len(Served(date(3), date(1))) != 0 {
+		t.Error("expected no meals for reversed dates")
+	}
+}
+
+func TestReport(t *testing.T) {
+	diners := []Diner{
+		{Adults: 1, Children: 2, Tags: []string{models.DietVegetarian}, Arrival: date(1), Departure: date(2)},
+		{Adults: 1, Tags: []string{models.DietVegan, models.DietNutAllergy}, Arrival: date(1), Departure: date(3)},
+	}
+	rows := Report(diners)
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 meals, got %d", len(rows))
+	}
+
+	dinner := rows[0]
+	if dinner.Meal != Dinner || dinner.Adults != 2 || dinner.Children != 2 || dinner.Total != 4 {
+		t.Errorf("unexpected first dinner %+v", dinner)
+	}
+	if dinner.Tags[models.DietVegetarian] != 3 || dinner.Tags[models.DietVegan] != 1 {
+		t.Errorf("unexpected tag counts %v", dinner.Tags)
+	}
+	if last := rows[4]; !last.Day.Equal(date(3)) || last.Meal != Breakfast || last.Total != 1 {
+		t.Errorf("unexpected last meal %+v", last)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, rows); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "date,meal,adults,children,total,vegetarian,vegan,gluten_free,lactose_free,nut_allergy" {
+		t.Errorf("unexpected header %s", lines[0])
+	}
+	if lines[1] != "2025-07-01,dinner,2,2,4,3,1,0,0,1" {
+		t.Errorf("unexpected first row %s", lines[1])
+	}
+}
+
+func TestSkippedMeals(t *testing.T) {
+	skipped := []Slot{
+		{Day: date(2).Add(12 * time.Hour), Meal: Lunch},
+		{Day: date(2), Meal: Lunch},
+		{Day: date(5), Meal: Dinner},
+	}
+	within := Within(skipped, date(1), date(3))
+	if len(within) != 1 || !within[0].Day.Equal(date(2)) {
+		t.Errorf("expected a single lunch within the stay, got %v", within)
+	}
+	if changed := Changed(within, []Slot{{Day: date(2), Meal: Dinner}}); len(changed) != 2 {
+		t.Errorf("expected both meals to change, got %v", changed)
+	}
+
+	rows := Report([]Diner{{Adults: 1, Arrival: date(1), Departure: date(3), Skipped: within}})
+	if len(rows) != 4 || rows[2].Meal != Dinner {
+		t.Errorf("expected the skipped lunch to be excluded, got %+v", rows)
+	}
+}
+
+func TestRegistrationDiners(t *testing.T) {
+	age := 6
+	registration := models.Registration{RegistrationFields: models.RegistrationFields{
+		ArrivalDate:   date(1),
+		DepartureDate: date(2),
+		DietaryTags:   []string{models.DietVegetarian},
+		ChildrenCount: 1,
+		Companions: []models.Companion{
+			{Name: "Bob", Type: models.CompanionAdult, DietaryTags: []string{models.DietVegan}},
+			{Name: "Eve", Type: models.CompanionChild, Age: &age},
+		},
+	}}
+	rows := Report(RegistrationDiners(registration))
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 meals, got %d", len(rows))
+	}
+	dinner := rows[0]
+	if dinner.Adults != 2 || dinner.Children != 2 || dinner.Total != 4 {
+		t.Errorf("unexpected dinner %+v", dinner)
+	}
+	if dinner.Tags[models.DietVegetarian] != 2 || dinner.Tags[models.DietVegan] != 1 {
+		t.Errorf("expected companions to keep their own tags, got %v", dinner.Tags)
+	}
+}
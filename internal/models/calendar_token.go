@@ -0,0 +1,12 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// CalendarToken authenticates the iCalendar feeds of a user, calendar clients cannot send the auth cookie
+type CalendarToken struct {
+	gorm.Model
+	UserID uint   `json:"user_id" gorm:"uniqueIndex"`
+	Token  string `json:"-" gorm:"uniqueIndex"`
+}
@@ -0,0 +1,22 @@
+package models
+
+const (
+	CompanionAdult = "adult"
+	CompanionChild = "child"
+)
+
+// AdultAge is the age from which companions count as adults
+const AdultAge = 18
+
+// Companion is a person without their own account joining the event on a registration
+type Companion struct {
+	Name        string   `json:"name" doc:"Unique within the registration"`
+	Type        string   `json:"type" enum:"adult,child"`
+	Age         *int     `json:"age,omitempty" minimum:"0" maximum:"120" doc:"Age at the event, required for children"`
+	DietaryTags []string `json:"dietary_tags,omitempty" enum:"vegetarian,vegan,gluten_free,lactose_free,nut_allergy"`
+}
+
+// Child reports whether the companion counts as a child
+func (c Companion) Child() bool {
+	return c.Type == CompanionChild
+}
@@ -0,0 +1,12 @@
+package models
+
+const (
+	DietVegetarian  = "vegetarian"
+	DietVegan       = "vegan"
+	DietGlutenFree  = "gluten_free"
+	DietLactoseFree = "lactose_free"
+	DietNutAllergy  = "nut_allergy"
+)
+
+// DietaryTags lists the supported dietary tags, anything else goes to the free text food restrictions
+var DietaryTags = []string{DietVegetarian, DietVegan, DietGlutenFree, DietLactoseFree, DietNutAllergy}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	DiscordChangePaidRoleAdded          = "paid_role_added"
+	DiscordChangePaidRoleRemoved        = "paid_role_removed"
+	DiscordChangeAchievementRoleAdded   = "achievement_role_added"
+	DiscordChangeAchievementRoleRemoved = "achievement_role_removed"
+	DiscordChangeRoleDeleted            = "role_deleted"
+	DiscordChangeProfileUpdated         = "profile_updated"
+	DiscordChangeMemberJoined           = "member_joined"
+	DiscordChangeMemberLeft             = "member_left"
+)
+
+// DiscordChange records a change of local state caused by something done directly in Discord
+type DiscordChange struct {
+	gorm.Model
+	Kind          string `json:"kind" gorm:"index"`
+	DiscordID     string `json:"discord_id,omitempty" gorm:"index"`
+	UserID        *uint  `json:"user_id,omitempty" gorm:"index"`
+	RoleID        string `json:"role_id,omitempty"`
+	Event         string `json:"event,omitempty"`
+	AchievementID *uint  `json:"achievement_id,omitempty"`
+	PaymentID     *uint  `json:"payment_id,omitempty"`
+	Detail        string `json:"detail"`
+}
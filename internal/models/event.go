@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const DefaultCurrency = "CZK"
+
+const (
+	EventStatusDraft  = "draft"
+	EventStatusOpen   = "open"
+	EventStatusClosed = "closed"
+)
+
+type Event struct {
+	gorm.Model
+	Code                 string       `json:"code" gorm:"uniqueIndex:idx_events_live_code,where:deleted_at IS NULL"` // Codes of deleted events can be reused
+	Title                string       `json:"title"`
+	Location             string       `json:"location"`
+	StartDate            time.Time    `json:"start_date"`
+	EndDate              time.Time    `json:"end_date"`
+	RegistrationOpensAt  *time.Time   `json:"registration_opens_at"`
+	RegistrationClosesAt *time.Time   `json:"registration_closes_at"`
+	ChangesFrozenAt      *time.Time   `json:"changes_frozen_at"`
+	Status               string       `json:"status" gorm:"default:draft"`
+	CapacityAdults       int          `json:"capacity_adults"`   // 0 means unlimited
+	CapacityChildren     int          `json:"capacity_children"` // 0 means unlimited
+	MealCutoffHours      int          `json:"meal_cutoff_hours"` // Meal choices of a day close this many hours before it starts
+	Pricing              EventPricing `json:"pricing" gorm:"embedded;embeddedPrefix:price_"`
+}
+
+// EventPricing holds the rules for computing what attendees owe, amounts are in minor currency units
+type EventPricing struct {
+	Currency                 string     `json:"currency"`
+	FixedFee                 int64      `json:"fixed_fee"` // Charged once per registration
+	AdultPerNight            int64      `json:"adult_per_night"`
+	ChildPerNight            int64      `json:"child_per_night"`
+	EarlyBirdUntil           *time.Time `json:"early_bird_until"` // Registrations created before get the discount
+	EarlyBirdDiscountPercent int        `json:"early_bird_discount_percent"`
+}
+
+// PaidRole returns the name of the Discord role marking paid attendees
+func (e Event) PaidRole() string {
+	return e.Code + "::paid"
+}
+
+// RegistrationOpen reports whether the event accepts registrations at the given time
+func (e Event) RegistrationOpen(at time.Time) bool {
+	if e.Status != EventStatusOpen {
+		return false
+	}
+	if e.RegistrationOpensAt != nil && at.Before(*e.RegistrationOpensAt) {
+		return false
+	}
+	return !e.RegistrationClosed(at)
+}
+
+// RegistrationClosed reports whether the registration deadline has passed
+func (e Event) RegistrationClosed(at time.Time) bool {
+	return e.RegistrationClosesAt != nil && at.After(*e.RegistrationClosesAt)
+}
+
+// ChangesFrozen reports whether attendees can no longer change their registration
+func (e Event) ChangesFrozen(at time.Time) bool {
+	return e.ChangesFrozenAt != nil && at.After(*e.ChangesFrozenAt)
+}
+
+// MealChangesClosed reports whether attendees can no longer change their meals of the day
+func (e Event) MealChangesClosed(day time.Time, at time.Time) bool {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return !at.Before(start.Add(-time.Duration(e.MealCutoffHours) * time.Hour))
+}
+
+// HasCapacity reports whether the given headcount fits within the event limits
+func (e Event) HasCapacity(adults int, children int) bool {
+	if e.CapacityAdults > 0 && adults > e.CapacityAdults {
+		return false
+	}
+	if e.CapacityChildren > 0 && children > e.CapacityChildren {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	QuestionTypeText         = "text"
+	QuestionTypeNumber       = "number"
+	QuestionTypeBoolean      = "boolean"
+	QuestionTypeSingleChoice = "single_choice"
+	QuestionTypeMultiChoice  = "multi_choice"
+)
+
+// EventQuestion is an org defined question asked on registration for an event
+type EventQuestion struct {
+	gorm.Model
+	EventID  uint     `json:"event_id" gorm:"uniqueIndex:idx_event_question"`
+	Key      string   `json:"key" gorm:"uniqueIndex:idx_event_question"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" gorm:"serializer:json"` // Allowed values for choice questions
+	Position int      `json:"position"`
+}
+
+// Answers maps question keys to the attendee's answers
+type Answers map[string]any
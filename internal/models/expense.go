@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Expense is something an attendee paid for a group of attendees during the event
+type Expense struct {
+	gorm.Model
+	EventID     uint           `json:"event_id" gorm:"index"`
+	Event       string         `json:"event"` // Event code
+	PayerID     uint           `json:"payer_id"`
+	Payer       User           `json:"-" gorm:"foreignKey:PayerID"`
+	Amount      int64          `json:"amount"` // In minor currency units
+	Currency    string         `json:"currency"`
+	Description string         `json:"description"`
+	SpentAt     time.Time      `json:"spent_at"`
+	CreatedByID uint           `json:"created_by_id"`
+	Shares      []ExpenseShare `json:"-" gorm:"foreignKey:ExpenseID"`
+}
+
+// ExpenseShare is a participant of an expense, the amount is split by the number of shares
+type ExpenseShare struct {
+	ID        uint  `json:"-" gorm:"primarykey"`
+	ExpenseID uint  `json:"-" gorm:"index"`
+	UserID    uint  `json:"user_id"`
+	User      User  `json:"-" gorm:"foreignKey:UserID"`
+	Shares    int   `json:"shares"`
+	Amount    int64 `json:"amount"` // Part of the expense owed by the participant, computed on save
+}
+
+// Transfer settles shared expenses between two users, it is computed and never stored
+type Transfer struct {
+	FromID       uint   `json:"from_id"`
+	FromUsername string `json:"from_username"`
+	ToID         uint   `json:"to_id"`
+	ToUsername   string `json:"to_username"`
+	Amount       int64  `json:"amount"` // In minor currency units
+}
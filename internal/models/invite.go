@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invite lets guests without Discord create an account and register for the event
+type Invite struct {
+	gorm.Model
+	EventID     uint       `json:"event_id" gorm:"index"`
+	Event       string     `json:"event"` // Event code
+	Token       string     `json:"token" gorm:"uniqueIndex"`
+	MaxUses     int        `json:"max_uses"`
+	Uses        int        `json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Note        string     `json:"note"`
+	CreatedByID uint       `json:"created_by_id"`
+}
+
+// Usable reports whether the invite can still create a guest account at the given time
+func (i Invite) Usable(at time.Time) bool {
+	return i.Uses < i.MaxUses && (i.ExpiresAt == nil || at.Before(*i.ExpiresAt))
+}
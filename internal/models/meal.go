@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// MealSlot is a single meal on a day
+type MealSlot struct {
+	Day  time.Time `json:"day" doc:"Midnight UTC of the day"`
+	Meal string    `json:"meal" enum:"breakfast,lunch,dinner"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed" // Gave up after the maximum number of attempts or on a permanent error, can be replayed by orgs
+)
+
+// OutboxItem is a notification or Discord role change written in the same transaction as the change causing it
+type OutboxItem struct {
+	gorm.Model
+	Kind          string     `json:"kind" gorm:"index"`
+	Channel       string     `json:"channel,omitempty"` // Notifier delivering a notification, set by the worker for each configured one
+	Payload       string     `json:"payload"`           // JSON encoded arguments of the side effect
+	Status        string     `json:"status" gorm:"index:idx_outbox_due"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_due"`
+	LastError     string     `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
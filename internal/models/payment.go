@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodCash         = "cash"
+	PaymentMethodCard         = "card"
+	PaymentMethodOther        = "other"
+)
+
+type Payment struct {
+	gorm.Model
+	RegistrationID    uint       `json:"registration_id" gorm:"index"`
+	Amount            int64      `json:"amount"` // In minor currency units
+	Currency          string     `json:"currency"`
+	Method            string     `json:"method"`
+	Reference         string     `json:"reference" gorm:"index"` // e.g. bank transaction ID
+	Note              string     `json:"note"`
+	ReceivedAt        time.Time  `json:"received_at"`
+	RecordedByID      uint       `json:"recorded_by_id"`
+	RecordedBy        User       `json:"recorded_by" gorm:"foreignKey:RecordedByID"`
+	VoidedAt          *time.Time `json:"voided_at"`
+	VoidedByID        *uint      `json:"voided_by_id"`
+	VoidReason        string     `json:"void_reason"`
+	BankTransactionID string     `json:"bank_transaction_id,omitempty" gorm:"uniqueIndex:idx_payments_bank_transaction,where:bank_transaction_id <> ''"` // Set on imported payments, no transaction is recorded twice
+}
@@ -1,24 +1,71 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type RegistrationFields struct {
-	ArrivalDate      time.Time `json:"arrival_date"`
-	DepartureDate    time.Time `json:"departure_date"`
-	FoodRestrictions string    `json:"food_restrictions"`
-	ChildrenCount    int       `json:"children_count"`
-	Cancelled        bool      `json:"cancelled"`
-	Note             string    `json:"note"`
+	ArrivalDate      time.Time   `json:"arrival_date"`
+	DepartureDate    time.Time   `json:"departure_date"`
+	FoodRestrictions string      `json:"food_restrictions"`
+	DietaryTags      []string    `json:"dietary_tags" gorm:"serializer:json"`
+	ChildrenCount    int         `json:"children_count"` // Unnamed children sharing the dietary tags of the attendee
+	Cancelled        bool        `json:"cancelled"`
+	Waitlisted       bool        `json:"waitlisted"`
+	Note             string      `json:"note"`
+	Answers          Answers     `json:"answers" gorm:"serializer:json"`
+	Price            int64       `json:"price"`                            // Computed on save, in minor currency units
+	Roommates        []string    `json:"roommates" gorm:"serializer:json"` // Usernames of preferred roommates
+	QuietRoom        bool        `json:"quiet_room"`
+	NoStairs         bool        `json:"no_stairs"`
+	SkippedMeals     []MealSlot  `json:"skipped_meals" gorm:"serializer:json"` // Meals of the stay the attendees opted out of
+	Companions       []Companion `json:"companions" gorm:"serializer:json"`
+}
+
+// Adults returns the number of adults covered by the registration
+func (f RegistrationFields) Adults() int {
+	adults := 1
+	for _, c := range f.Companions {
+		if !c.Child() {
+			adults++
+		}
+	}
+	return adults
+}
+
+// Children returns the number of children covered by the registration
+func (f RegistrationFields) Children() int {
+	children := f.ChildrenCount
+	for _, c := range f.Companions {
+		if c.Child() {
+			children++
+		}
+	}
+	return children
+}
+
+// Confirmed reports whether the registration holds a spot at the event
+func (f RegistrationFields) Confirmed() bool {
+	return !f.Cancelled && !f.Waitlisted
 }
 
 type Registration struct {
 	gorm.Model
 	UserID             uint   `json:"user_id" gorm:"uniqueIndex:idx_user_event"`
-	Event              string `json:"event" gorm:"uniqueIndex:idx_user_event"`
+	Event              string `json:"event" gorm:"uniqueIndex:idx_user_event"` // Event code
+	EventID            uint   `json:"event_id" gorm:"index"`
 	User               User   `gorm:"foreignKey:UserID"`
 	RegistrationFields `gorm:"embedded"`
+	WaitlistedAt       *time.Time `json:"waitlisted_at,omitempty"`
+	CheckedInAt        *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInByID      *uint      `json:"checked_in_by_id,omitempty"`
+	CheckedInBy        *User      `json:"checked_in_by,omitempty" gorm:"foreignKey:CheckedInByID"`
+}
+
+// VariableSymbol returns the stable symbol identifying bank transfers for the registration
+func (r Registration) VariableSymbol() string {
+	return strconv.FormatUint(uint64(r.ID), 10)
 }
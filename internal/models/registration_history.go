@@ -9,5 +9,6 @@ type RegistrationHistory struct {
 	RegistrationID     uint   `json:"registration_id"`
 	UserID             uint   `json:"user_id"`
 	Event              string `json:"event"`
+	EventID            uint   `json:"event_id"`
 	RegistrationFields `gorm:"embedded"`
 }
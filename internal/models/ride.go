@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RideRequestPending   = "pending"
+	RideRequestAccepted  = "accepted"
+	RideRequestRejected  = "rejected"
+	RideRequestWithdrawn = "withdrawn"
+)
+
+// Ride is a car driven to or from the event by an attendee offering free seats
+type Ride struct {
+	gorm.Model
+	EventID              uint          `json:"event_id" gorm:"index"`
+	Event                string        `json:"event"` // Event code
+	DriverRegistrationID uint          `json:"driver_registration_id" gorm:"index"`
+	DriverID             uint          `json:"driver_id"`
+	Driver               User          `json:"-" gorm:"foreignKey:DriverID"`
+	Origin               string        `json:"origin"`
+	DepartsAt            time.Time     `json:"departs_at"`
+	Seats                int           `json:"seats"` // Seats offered to passengers
+	Note                 string        `json:"note"`
+	Requests             []RideRequest `json:"-" gorm:"foreignKey:RideID"`
+}
+
+// TakenSeats returns the seats taken by accepted passengers
+func (r Ride) TakenSeats() int {
+	taken := 0
+	for _, req := range r.Requests {
+		if req.Status == RideRequestAccepted {
+			taken += req.Seats
+		}
+	}
+	return taken
+}
+
+// FreeSeats returns the seats still offered, Requests must be loaded
+func (r Ride) FreeSeats() int {
+	return r.Seats - r.TakenSeats()
+}
+
+type RideRequest struct {
+	gorm.Model
+	RideID         uint   `json:"ride_id" gorm:"index"`
+	RegistrationID uint   `json:"registration_id" gorm:"index"`
+	PassengerID    uint   `json:"passenger_id"`
+	Passenger      User   `json:"-" gorm:"foreignKey:PassengerID"`
+	Seats          int    `json:"seats"` // The passenger and the children travelling along
+	Status         string `json:"status"`
+	Note           string `json:"note"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Room struct {
+	gorm.Model
+	EventID uint   `json:"event_id" gorm:"uniqueIndex:idx_event_room"`
+	Name    string `json:"name" gorm:"uniqueIndex:idx_event_room"`
+	Beds    int    `json:"beds"`
+	Quiet   bool   `json:"quiet"`
+	Stairs  bool   `json:"stairs"` // Reaching the room requires climbing stairs
+	Note    string `json:"note"`
+}
+
+// RoomAssignment places a registration with its children into a room for the nights from FromDate until ToDate
+type RoomAssignment struct {
+	gorm.Model
+	RoomID         uint         `json:"room_id" gorm:"index"`
+	Room           Room         `json:"room" gorm:"foreignKey:RoomID"`
+	RegistrationID uint         `json:"registration_id" gorm:"index"`
+	Registration   Registration `json:"-" gorm:"foreignKey:RegistrationID"`
+	FromDate       time.Time    `json:"from_date"` // Day of the first night
+	ToDate         time.Time    `json:"to_date"`   // Day of departure, the night before is the last one
+	AssignedByID   uint         `json:"assigned_by_id"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SessionProposed = "proposed"
+	SessionAccepted = "accepted"
+	SessionRejected = "rejected"
+)
+
+// Session is a talk or workshop at the event, sessions proposed by speakers are on the agenda once accepted
+type Session struct {
+	gorm.Model
+	EventID      uint            `json:"event_id" gorm:"index"`
+	Event        string          `json:"event"` // Event code
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	SpeakerID    *uint           `json:"speaker_id"`
+	Speaker      *User           `json:"-" gorm:"foreignKey:SpeakerID"`
+	Room         string          `json:"room"` // Where the session takes place
+	StartsAt     time.Time       `json:"starts_at"`
+	EndsAt       time.Time       `json:"ends_at"`
+	Capacity     int             `json:"capacity"` // Seats for attendees, 0 when unlimited
+	Status       string          `json:"status"`
+	ReviewNote   string          `json:"review_note"`
+	ReviewedByID *uint           `json:"reviewed_by_id"`
+	Signups      []SessionSignup `json:"-" gorm:"foreignKey:SessionID"`
+}
+
+// Scheduled reports whether the session has a time slot
+func (s Session) Scheduled() bool {
+	return !s.StartsAt.IsZero() && s.StartsAt.Before(s.EndsAt)
+}
+
+// SessionSignup is an attendee taking a seat at a session, signups are hard deleted so attendees can sign up again
+type SessionSignup struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"created_at"`
+	SessionID      uint      `json:"session_id" gorm:"uniqueIndex:idx_session_registration"`
+	RegistrationID uint      `json:"registration_id" gorm:"uniqueIndex:idx_session_registration"`
+	UserID         uint      `json:"user_id"`
+	User           User      `json:"-" gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shift is a volunteer duty at the event, e.g. cooking a dinner or chopping firewood
+type Shift struct {
+	gorm.Model
+	EventID  uint          `json:"event_id" gorm:"index"`
+	Event    string        `json:"event"` // Event code
+	Task     string        `json:"task"`
+	StartsAt time.Time     `json:"starts_at"`
+	EndsAt   time.Time     `json:"ends_at"`
+	Required int           `json:"required"` // People needed for the shift
+	Note     string        `json:"note"`
+	Signups  []ShiftSignup `json:"-" gorm:"foreignKey:ShiftID"`
+}
+
+// Duration returns how long the shift takes
+func (s Shift) Duration() time.Duration {
+	return s.EndsAt.Sub(s.StartsAt)
+}
+
+// ShiftSignup is an attendee volunteering for a shift, signups are hard deleted so attendees can sign up again
+type ShiftSignup struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"created_at"`
+	ShiftID        uint      `json:"shift_id" gorm:"uniqueIndex:idx_shift_registration"`
+	RegistrationID uint      `json:"registration_id" gorm:"uniqueIndex:idx_shift_registration"`
+	UserID         uint      `json:"user_id"`
+	User           User      `json:"-" gorm:"foreignKey:UserID"`
+	AssignedByID   uint      `json:"assigned_by_id"`
+}
@@ -1,13 +1,18 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	DiscordID string `gorm:"uniqueIndex"`
-	Username  string
-	Email     string
-	Avatar    string
+	DiscordID   string `gorm:"uniqueIndex:idx_users_linked_discord_id,where:discord_id <> ''"` // Empty for guests
+	Username    string
+	Email       string
+	Avatar      string
+	Guest       bool       `gorm:"index"` // Created through an invite, without a Discord account
+	InviteID    *uint      // Invite the guest account was created with
+	LeftGuildAt *time.Time // Set while the user is not a member of the Discord guild
 }
@@ -0,0 +1,190 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+type capturedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// standIn records the requests of a channel and answers them with the status
+func standIn(t *testing.T, status int) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{method: r.Method, path: r.URL.EscapedPath(), header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *fakeSender) Send(to string, subject string, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, to+"|"+subject+"|"+body)
+	return nil
+}
+
+var testMessage = Message{Kind: KindRegistration, Event: "gt-2026", Title: "Registration Update: gt-2026", Text: "User: alice\nStatus: <new>"}
+
+func TestSlackChannel(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	if err := NewSlackChannel(server.URL+"/hook").Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 || got[0].method != http.MethodPost || got[0].path != "/hook" {
+		t.Fatalf("unexpected requests %+v", got)
+	}
+	var payload map[string]string
+	json.Unmarshal(got[0].body, &payload)
+	if payload["text"] != "*Registration Update: gt-2026*\nUser: alice\nStatus: <new>" {
+		t.Errorf("unexpected slack text %q", payload["text"])
+	}
+}
+
+func TestMatrixChannel(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	channel := NewMatrixChannel(server.URL+"/", "secret-token", "!room:example.org")
+	// A retry of the same message reuses the transaction ID
+	for i := 0; i < 2; i++ {
+		if err := channel.Send(context.Background(), testMessage); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	got := requests()
+	if len(got) != 2 || got[0].method != http.MethodPut || got[1].path != got[0].path {
+		t.Fatalf("unexpected requests %+v", got)
+	}
+	if !strings.HasPrefix(got[0].path, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+		t.Errorf("unexpected matrix path %s", got[0].path)
+	}
+	if got[0].header.Get("Authorization") != "Bearer secret-token" {
+		t.Errorf("unexpected authorization %q", got[0].header.Get("Authorization"))
+	}
+	var payload map[string]string
+	json.Unmarshal(got[0].body, &payload)
+	if payload["msgtype"] != "m.text" || !strings.Contains(payload["formatted_body"], "Status: &lt;new&gt;") {
+		t.Errorf("unexpected matrix payload %v", payload)
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+	server, requests := standIn(t, http.StatusNoContent)
+	if err := NewWebhookChannel(server.URL, "webhook-secret").Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("expected one request, got %d", len(got))
+	}
+	if got[0].header.Get(SignatureHeader) != Sign("webhook-secret", got[0].body) {
+		t.Errorf("unexpected signature %q", got[0].header.Get(SignatureHeader))
+	}
+	var msg Message
+	if err := json.Unmarshal(got[0].body, &msg); err != nil || msg != testMessage {
+		t.Errorf("expected the message as JSON, got %s", got[0].body)
+	}
+
+	failing, _ := standIn(t, http.StatusInternalServerError)
+	if err := NewWebhookChannel(failing.URL, "").Send(context.Background(), testMessage); err == nil {
+		t.Error("expected error status to fail the send")
+	}
+}
+
+func TestEmailChannel(t *testing.T) {
+	sender := &fakeSender{}
+	if err := NewEmailChannel(sender, []string{"a@example.com", "b@example.com"}).Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(sender.sent) != 2 || !strings.HasPrefix(sender.sent[1], "b@example.com|Registration Update: gt-2026|User: alice") {
+		t.Errorf("unexpected emails %v", sender.sent)
+	}
+}
+
+// slowChannel takes a while to send so concurrent delivery can be observed
+type slowChannel struct {
+	delay time.Duration
+	err   error
+}
+
+func (c slowChannel) Name() string {
+	return "slow"
+}
+
+func (c slowChannel) Send(ctx context.Context, msg Message) error {
+	time.Sleep(c.delay)
+	return c.err
+}
+
+func TestFanoutNotifier(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	failure := errors.New("backend down")
+	fanout := NewFanoutNotifier(nil,
+		NewSlackChannel(server.URL),
+		NewWebhookChannel(server.URL, ""),
+		slowChannel{delay: 200 * time.Millisecond},
+		slowChannel{delay: 200 * time.Millisecond, err: failure},
+	)
+
+	started := time.Now()
+	user := models.User{Username: "alice"}
+	err := fanout.NotifyRegistration(user, models.Registration{Event: "gt-2026"})
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "slow: backend down") {
+		t.Errorf("expected the failing channel to be reported, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 350*time.Millisecond {
+		t.Errorf("expected channels to be notified concurrently, took %v", elapsed)
+	}
+	if len(requests()) != 2 {
+		t.Errorf("expected the other channels to get the message, got %d requests", len(requests()))
+	}
+
+	if err := fanout.NotifyAchievement(user, models.Achievement{Name: "Night Owl"}, user, false); !errors.Is(err, failure) {
+		t.Errorf("expected achievements to fan out as well, got %v", err)
+	}
+
+	// Without Discord the announcements go to the channels and direct messages are skipped
+	before := len(requests())
+	if err := fanout.NotifySettlement(models.Event{Code: "gt-2026", Title: "Garage Trip"}, "CZK", nil); !errors.Is(err, failure) {
+		t.Errorf("expected settlements to fan out as well, got %v", err)
+	}
+	if len(requests()) != before+2 {
+		t.Errorf("expected the settlement on the channels, got %d requests", len(requests())-before)
+	}
+	if err := fanout.NotifyRideCancelled(user, models.Ride{Event: "gt-2026"}); err != nil {
+		t.Errorf("expected direct messages to be skipped without Discord, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"strings"
+
+	"github.com/gdg-garage/garage-trip-api/internal/config"
+	"github.com/gdg-garage/garage-trip-api/internal/mailer"
+)
+
+// ChannelsFromConfig creates the channels configured besides Discord, email needs the sender
+func ChannelsFromConfig(cfg *config.Config, sender mailer.Sender) []Channel {
+	var channels []Channel
+	if cfg.NotifyEmailTo != "" && sender != nil {
+		var recipients []string
+		for _, to := range strings.Split(cfg.NotifyEmailTo, ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				recipients = append(recipients, to)
+			}
+		}
+		channels = append(channels, NewEmailChannel(sender, recipients))
+	}
+	if cfg.SlackWebhookURL != "" {
+		channels = append(channels, NewSlackChannel(cfg.SlackWebhookURL))
+	}
+	if cfg.MatrixHomeserverURL != "" && cfg.MatrixAccessToken != "" && cfg.MatrixRoomID != "" {
+		channels = append(channels, NewMatrixChannel(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken, cfg.MatrixRoomID))
+	}
+	if cfg.NotifyWebhookURL != "" {
+		channels = append(channels, NewWebhookChannel(cfg.NotifyWebhookURL, cfg.NotifyWebhookSecret))
+	}
+	return channels
+}
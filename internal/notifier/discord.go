@@ -5,24 +5,15 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-api/internal/guildcache"
 	"github.com/gdg-garage/garage-trip-api/internal/models"
 )
 
-type Notifier interface {
-
-	// CreateRole Create a new role in guild
-	CreateRole(name string) (string, error)
-	// GrantRole Grant a role to a user
-	GrantRole(userID string, roleID string) error
-	// NotifyAchievement Send a message about the achievement
-	NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error
-	// NotifyRegistration Notify about registration changes
-	NotifyRegistration(user models.User, registration models.Registration) error
-	// HasRole Check if a user has a role
-	HasRole(userID string, roleID string) (bool, error)
-}
+// DiscordChannel is the channel name of the Discord notifier
+const DiscordChannel = "discord"
 
 type DiscordNotifier struct {
 	session                *discordgo.Session
@@ -30,11 +21,13 @@ type DiscordNotifier struct {
 	registrationsChannelID string
 	guildID                string
 	achievementPrefix      string
+	roles                  *guildcache.Cache
 }
 
-func NewDiscordNotifier(session *discordgo.Session, achievementsChannelID string, registrationsChannelID string, guildID string, achievementPrefix string) *DiscordNotifier {
+func NewDiscordNotifier(session *discordgo.Session, roles *guildcache.Cache, achievementsChannelID string, registrationsChannelID string, guildID string, achievementPrefix string) *DiscordNotifier {
 	return &DiscordNotifier{
 		session:                session,
+		roles:                  roles,
 		achievementsChannelID:  achievementsChannelID,
 		registrationsChannelID: registrationsChannelID,
 		guildID:                guildID,
@@ -53,6 +46,7 @@ func (n *DiscordNotifier) CreateRole(name string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to create role: %w", err)
 	}
+	n.roles.InvalidateRoles()
 	return role.ID, nil
 }
 
@@ -65,26 +59,57 @@ func (n *DiscordNotifier) GrantRole(userID string, roleID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to grant role: %w", err)
 	}
+	n.roles.InvalidateMember(userID)
 	return nil
 }
 
-func (n *DiscordNotifier) HasRole(userID string, roleID string) (bool, error) {
+func (n *DiscordNotifier) GrantRoleByName(userID string, roleName string) error {
 	if n.session == nil || n.guildID == "" {
-		return false, fmt.Errorf("discord session is nil or guildID is empty")
+		return fmt.Errorf("discord session is nil or guildID is empty")
 	}
 
-	member, err := n.session.GuildMember(n.guildID, userID)
+	roleID, err := n.findRoleID(roleName)
 	if err != nil {
-		return false, fmt.Errorf("failed to get guild member: %w", err)
+		return err
 	}
+	return n.GrantRole(userID, roleID)
+}
 
-	for _, id := range member.Roles {
-		if id == roleID {
-			return true, nil
-		}
+func (n *DiscordNotifier) RemoveRoleByName(userID string, roleName string) error {
+	if n.session == nil || n.guildID == "" {
+		return fmt.Errorf("discord session is nil or guildID is empty")
 	}
 
-	return false, nil
+	roleID, err := n.findRoleID(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = n.session.GuildMemberRoleRemove(n.guildID, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to remove role: %w", err)
+	}
+	n.roles.InvalidateMember(userID)
+	return nil
+}
+
+// findRoleID looks up the ID of a guild role by its name
+func (n *DiscordNotifier) findRoleID(roleName string) (string, error) {
+	roleID, err := n.roles.RoleID(roleName)
+	if err != nil {
+		return "", err
+	}
+	if roleID == "" {
+		return "", fmt.Errorf("role %s not found in guild %s", roleName, n.guildID)
+	}
+	return roleID, nil
+}
+
+func (n *DiscordNotifier) HasRole(userID string, roleID string) (bool, error) {
+	if n.session == nil || n.guildID == "" {
+		return false, fmt.Errorf("discord session is nil or guildID is empty")
+	}
+	return n.roles.HasRole(userID, roleID)
 }
 
 func (n *DiscordNotifier) NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error {
@@ -160,57 +185,28 @@ func (n *DiscordNotifier) NotifyRegistration(user models.User, registration mode
 		return fmt.Errorf("discord registrations channel ID is empty")
 	}
 
-	// Role Management
-	if n.guildID != "" {
-		roleName := registration.Event
-		roles, err := n.session.GuildRoles(n.guildID)
-		if err != nil {
-			log.Printf("Failed to fetch guild roles: %v", err)
-		} else {
-			var roleID string
-			for _, r := range roles {
-				if r.Name == roleName {
-					roleID = r.ID
-					break
-				}
-			}
-
-			if roleID != "" {
-				if registration.Cancelled {
-					err = n.session.GuildMemberRoleRemove(n.guildID, user.DiscordID, roleID)
-					if err != nil {
-						log.Printf("Failed to remove role: %v", err)
-					}
-				} else {
-					err = n.session.GuildMemberRoleAdd(n.guildID, user.DiscordID, roleID)
-					if err != nil {
-						log.Printf("Failed to add role: %v", err)
-					}
-				}
-			} else {
-				log.Printf("Role %s not found in guild %s", roleName, n.guildID)
-			}
-		}
-	}
-
-	status := "registered/updated registration"
-	if registration.Cancelled {
-		status = "cancelled registration 😢 👎"
-	}
+	n.syncEventRole(user, registration)
 
 	noteStr := ""
 	if registration.Note != "" {
 		noteStr = fmt.Sprintf("\n**Note:** %s", registration.Note)
 	}
 
-	message := fmt.Sprintf("🎉 **Registration Update: %s**\n**User:** %s (<@%s>)\n**Status:** %s\n**Dates:** %s - %s\n**Children:** %d\n**Food Restrictions:** %s%s",
+	companionsStr := ""
+	if len(registration.Companions) > 0 {
+		companionsStr = fmt.Sprintf("\n**Companions:** %s", companionNames(registration))
+	}
+
+	message := fmt.Sprintf("🎉 **Registration Update: %s**\n**User:** %s (<@%s>)\n**Status:** %s\n**Dates:** %s - %s\n**Adults:** %d\n**Children:** %d%s\n**Food Restrictions:** %s%s",
 		registration.Event,
 		user.Username,
 		user.DiscordID,
-		status,
+		registrationStatus(registration),
 		registration.ArrivalDate.Format("2006-01-02"),
 		registration.DepartureDate.Format("2006-01-02"),
-		registration.ChildrenCount,
+		registration.Adults(),
+		registration.Children(),
+		companionsStr,
 		registration.FoodRestrictions,
 		noteStr,
 	)
@@ -223,3 +219,175 @@ func (n *DiscordNotifier) NotifyRegistration(user models.User, registration mode
 
 	return nil
 }
+
+func (n *DiscordNotifier) NotifyPayment(user models.User, registration models.Registration, payment models.Payment) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session is nil")
+	}
+	if n.registrationsChannelID == "" {
+		return fmt.Errorf("discord registrations channel ID is empty")
+	}
+
+	message := fmt.Sprintf("💰 **Payment Received: %s**\n**User:** %s (<@%s>)\n**Amount:** %s\n**Method:** %s",
+		registration.Event,
+		user.Username,
+		user.DiscordID,
+		formatAmount(payment.Amount, payment.Currency),
+		payment.Method,
+	)
+
+	_, err := n.session.ChannelMessageSend(n.registrationsChannelID, message)
+	if err != nil {
+		log.Printf("Failed to send discord message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (n *DiscordNotifier) NotifyWaitlistPromotion(user models.User, registration models.Registration) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session is nil")
+	}
+
+	n.syncEventRole(user, registration)
+
+	dm, err := n.session.UserChannelCreate(user.DiscordID)
+	if err != nil {
+		log.Printf("Failed to open DM channel: %v", err)
+	} else {
+		_, err = n.session.ChannelMessageSend(dm.ID, fmt.Sprintf("🎉 A spot opened up for **%s** and you are in! Your registration is no longer on the waitlist.", registration.Event))
+		if err != nil {
+			log.Printf("Failed to send DM: %v", err)
+		}
+	}
+
+	if n.registrationsChannelID == "" {
+		return fmt.Errorf("discord registrations channel ID is empty")
+	}
+
+	message := fmt.Sprintf("🎟️ **Waitlist Update: %s**\n**User:** %s (<@%s>)\n**Status:** promoted from the waitlist",
+		registration.Event,
+		user.Username,
+		user.DiscordID,
+	)
+
+	_, err = n.session.ChannelMessageSend(n.registrationsChannelID, message)
+	if err != nil {
+		log.Printf("Failed to send discord message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (n *DiscordNotifier) NotifyRideFull(driver models.User, ride models.Ride) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session is nil")
+	}
+	if n.registrationsChannelID == "" {
+		return fmt.Errorf("discord registrations channel ID is empty")
+	}
+
+	message := fmt.Sprintf("🚗 **Carpool Update: %s**\n**Driver:** %s (<@%s>)\n**From:** %s\n**Departs:** %s\n**Status:** all %d seats are taken",
+		ride.Event,
+		driver.Username,
+		driver.DiscordID,
+		ride.Origin,
+		ride.DepartsAt.Format("2006-01-02 15:04"),
+		ride.Seats,
+	)
+
+	_, err := n.session.ChannelMessageSend(n.registrationsChannelID, message)
+	if err != nil {
+		log.Printf("Failed to send discord message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (n *DiscordNotifier) NotifyRideLeft(driver models.User, passenger models.User, ride models.Ride) error {
+	return n.sendDM(driver.DiscordID, fmt.Sprintf("🚗 %s is no longer riding with you from %s to **%s** (%s), their seats are free again.",
+		passenger.Username,
+		ride.Origin,
+		ride.Event,
+		ride.DepartsAt.Format("2006-01-02 15:04"),
+	))
+}
+
+func (n *DiscordNotifier) NotifyRideCancelled(passenger models.User, ride models.Ride) error {
+	return n.sendDM(passenger.DiscordID, fmt.Sprintf("🚗 Your ride from %s to **%s** (%s) was cancelled, please look for another one.",
+		ride.Origin,
+		ride.Event,
+		ride.DepartsAt.Format("2006-01-02 15:04"),
+	))
+}
+
+func (n *DiscordNotifier) NotifySettlement(event models.Event, currency string, transfers []models.Transfer) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session is nil")
+	}
+	if n.registrationsChannelID == "" {
+		return fmt.Errorf("discord registrations channel ID is empty")
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "💸 **Settle Up: %s**", event.Title)
+	if len(transfers) == 0 {
+		message.WriteString("\nEverybody is even, nothing to pay 🎉")
+	}
+	for _, t := range transfers {
+		fmt.Fprintf(&message, "\n**%s** → **%s**: %s", t.FromUsername, t.ToUsername, formatAmount(t.Amount, currency))
+	}
+
+	_, err := n.session.ChannelMessageSend(n.registrationsChannelID, message.String())
+	if err != nil {
+		log.Printf("Failed to send discord message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// sendDM sends a direct message to the user
+func (n *DiscordNotifier) sendDM(discordID string, message string) error {
+	if n.session == nil {
+		return fmt.Errorf("discord session is nil")
+	}
+
+	dm, err := n.session.UserChannelCreate(discordID)
+	if err != nil {
+		return fmt.Errorf("failed to open DM channel: %w", err)
+	}
+	if _, err := n.session.ChannelMessageSend(dm.ID, message); err != nil {
+		return fmt.Errorf("failed to send DM: %w", err)
+	}
+	return nil
+}
+
+// syncEventRole grants the event role to confirmed attendees and removes it otherwise
+func (n *DiscordNotifier) syncEventRole(user models.User, registration models.Registration) {
+	if n.guildID == "" {
+		return
+	}
+
+	roleID, err := n.findRoleID(registration.Event)
+	if err != nil {
+		log.Printf("Failed to find event role: %v", err)
+		return
+	}
+
+	if registration.Confirmed() {
+		err = n.session.GuildMemberRoleAdd(n.guildID, user.DiscordID, roleID)
+		if err != nil {
+			log.Printf("Failed to add role: %v", err)
+		}
+	} else {
+		err = n.session.GuildMemberRoleRemove(n.guildID, user.DiscordID, roleID)
+		if err != nil {
+			log.Printf("Failed to remove role: %v", err)
+		}
+	}
+	n.roles.InvalidateMember(user.DiscordID)
+}
@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gdg-garage/garage-trip-api/internal/mailer"
+)
+
+// EmailChannel mails every message to a fixed list of recipients
+type EmailChannel struct {
+	sender     mailer.Sender
+	recipients []string
+}
+
+func NewEmailChannel(sender mailer.Sender, recipients []string) *EmailChannel {
+	return &EmailChannel{sender: sender, recipients: recipients}
+}
+
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, to := range c.recipients {
+		errs = append(errs, c.sender.Send(to, msg.Title, msg.Text))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+// ChannelNotifier formats the notifications for a channel
+type ChannelNotifier struct {
+	Channel Channel
+}
+
+func (m ChannelNotifier) send(msg Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), HTTPTimeout)
+	defer cancel()
+	if err := m.Channel.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", m.Channel.Name(), err)
+	}
+	return nil
+}
+
+func (m ChannelNotifier) NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error {
+	return m.send(achievementMessage(user, achievement, grantor, showGrantor))
+}
+
+func (m ChannelNotifier) NotifyRegistration(user models.User, registration models.Registration) error {
+	return m.send(registrationMessage(user, registration))
+}
+
+func (m ChannelNotifier) NotifyPayment(user models.User, registration models.Registration, payment models.Payment) error {
+	return m.send(paymentMessage(user, registration, payment))
+}
+
+func (m ChannelNotifier) NotifyWaitlistPromotion(user models.User, registration models.Registration) error {
+	return m.send(waitlistPromotionMessage(user, registration))
+}
+
+func (m ChannelNotifier) NotifyRideFull(driver models.User, ride models.Ride) error {
+	return m.send(rideFullMessage(driver, ride))
+}
+
+// NotifyRideLeft is a direct message on Discord, channels are shared so they skip it
+func (m ChannelNotifier) NotifyRideLeft(driver models.User, passenger models.User, ride models.Ride) error {
+	return nil
+}
+
+// NotifyRideCancelled is a direct message on Discord, channels are shared so they skip it
+func (m ChannelNotifier) NotifyRideCancelled(passenger models.User, ride models.Ride) error {
+	return nil
+}
+
+func (m ChannelNotifier) NotifySettlement(event models.Event, currency string, transfers []models.Transfer) error {
+	return m.send(settlementMessage(event, currency, transfers))
+}
+
+// FanoutNotifier sends the notifications through Discord and all channels concurrently
+type FanoutNotifier struct {
+	names     []string
+	notifiers []Notifier
+}
+
+// NewFanoutNotifier combines the optional Discord notifier with the channels
+func NewFanoutNotifier(discord Notifier, channels ...Channel) *FanoutNotifier {
+	f := &FanoutNotifier{}
+	if discord != nil {
+		f.names = append(f.names, DiscordChannel)
+		f.notifiers = append(f.notifiers, discord)
+	}
+	for _, c := range channels {
+		f.names = append(f.names, c.Name())
+		f.notifiers = append(f.notifiers, ChannelNotifier{Channel: c})
+	}
+	return f
+}
+
+// Notifiers returns the combined notifiers keyed by their channel name
+func (f *FanoutNotifier) Notifiers() map[string]Notifier {
+	notifiers := make(map[string]Notifier, len(f.notifiers))
+	for i, n := range f.notifiers {
+		notifiers[f.names[i]] = n
+	}
+	return notifiers
+}
+
+// fanout calls all notifiers at once and waits for them, a failing one does not stop the others
+func (f *FanoutNotifier) fanout(notify func(Notifier) error) error {
+	errs := make([]error, len(f.notifiers))
+	var wg sync.WaitGroup
+	for i, n := range f.notifiers {
+		wg.Go(func() {
+			errs[i] = notify(n)
+		})
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil {
+		log.Printf("Failed to deliver notification: %v", err)
+	}
+	return err
+}
+
+func (f *FanoutNotifier) NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyAchievement(user, achievement, grantor, showGrantor)
+	})
+}
+
+func (f *FanoutNotifier) NotifyRegistration(user models.User, registration models.Registration) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyRegistration(user, registration)
+	})
+}
+
+func (f *FanoutNotifier) NotifyPayment(user models.User, registration models.Registration, payment models.Payment) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyPayment(user, registration, payment)
+	})
+}
+
+func (f *FanoutNotifier) NotifyWaitlistPromotion(user models.User, registration models.Registration) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyWaitlistPromotion(user, registration)
+	})
+}
+
+func (f *FanoutNotifier) NotifyRideFull(driver models.User, ride models.Ride) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyRideFull(driver, ride)
+	})
+}
+
+func (f *FanoutNotifier) NotifyRideLeft(driver models.User, passenger models.User, ride models.Ride) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyRideLeft(driver, passenger, ride)
+	})
+}
+
+func (f *FanoutNotifier) NotifyRideCancelled(passenger models.User, ride models.Ride) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifyRideCancelled(passenger, ride)
+	})
+}
+
+func (f *FanoutNotifier) NotifySettlement(event models.Event, currency string, transfers []models.Transfer) error {
+	return f.fanout(func(n Notifier) error {
+		return n.NotifySettlement(event, currency, transfers)
+	})
+}
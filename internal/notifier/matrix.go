@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MatrixChannel sends messages to a room through the Matrix client-server API
+type MatrixChannel struct {
+	homeserverURL string
+	accessToken   string
+	roomID        string
+	client        *http.Client
+}
+
+func NewMatrixChannel(homeserverURL string, accessToken string, roomID string) *MatrixChannel {
+	return &MatrixChannel{
+		homeserverURL: strings.TrimRight(homeserverURL, "/"),
+		accessToken:   accessToken,
+		roomID:        roomID,
+		client:        &http.Client{Timeout: HTTPTimeout},
+	}
+}
+
+func (c *MatrixChannel) Name() string {
+	return "matrix"
+}
+
+func (c *MatrixChannel) Send(ctx context.Context, msg Message) error {
+	endpoint := c.homeserverURL + "/_matrix/client/v3/rooms/" + url.PathEscape(c.roomID) + "/send/m.room.message/" + matrixTxnID(msg)
+
+	payload := map[string]string{
+		"msgtype":        "m.text",
+		"body":           msg.Title + "\n" + msg.Text,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": "<strong>" + html.EscapeString(msg.Title) + "</strong><br>" + strings.ReplaceAll(html.EscapeString(msg.Text), "\n", "<br>"),
+	}
+	return sendJSON(ctx, c.client, http.MethodPut, endpoint, payload, map[string]string{"Authorization": "Bearer " + c.accessToken})
+}
+
+// matrixTxnID derives the transaction ID from the message, so the homeserver ignores an outbox retry
+// of a message it already accepted
+func matrixTxnID(msg Message) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{msg.Kind, msg.Event, msg.Title, msg.Text, msg.ImageURL}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
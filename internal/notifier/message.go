@@ -0,0 +1,111 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+const (
+	KindAchievement       = "achievement"
+	KindRegistration      = "registration"
+	KindPayment           = "payment"
+	KindWaitlistPromotion = "waitlist_promotion"
+	KindRideFull          = "ride_full"
+	KindSettlement        = "settlement"
+)
+
+// Message is a notification independent of the platform delivering it
+type Message struct {
+	Kind     string `json:"kind"`
+	Event    string `json:"event,omitempty"` // Event code
+	Title    string `json:"title"`
+	Text     string `json:"text"` // Plain text, one fact per line
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// Channel delivers messages to a single backend
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// registrationStatus describes the state of the registration for announcements
+func registrationStatus(registration models.Registration) string {
+	if registration.Cancelled {
+		return "cancelled registration 😢 👎"
+	}
+	if registration.Waitlisted {
+		return "joined the waitlist ⏳"
+	}
+	return "registered/updated registration"
+}
+
+func companionNames(registration models.Registration) string {
+	names := make([]string, len(registration.Companions))
+	for i, c := range registration.Companions {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func achievementMessage(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) Message {
+	text := fmt.Sprintf("%s has unlocked the %s achievement!", user.Username, achievement.Name)
+	if showGrantor {
+		text += "\nGranted by: " + grantor.Username
+	}
+	msg := Message{Kind: KindAchievement, Title: "Achievement Unlocked! 🏆", Text: text}
+	if strings.HasPrefix(achievement.Image, "http") {
+		msg.ImageURL = achievement.Image
+	}
+	return msg
+}
+
+func registrationMessage(user models.User, registration models.Registration) Message {
+	var text strings.Builder
+	fmt.Fprintf(&text, "User: %s\n", user.Username)
+	fmt.Fprintf(&text, "Status: %s\n", registrationStatus(registration))
+	fmt.Fprintf(&text, "Dates: %s - %s\n", registration.ArrivalDate.Format("2006-01-02"), registration.DepartureDate.Format("2006-01-02"))
+	fmt.Fprintf(&text, "Adults: %d\nChildren: %d", registration.Adults(), registration.Children())
+	if len(registration.Companions) > 0 {
+		fmt.Fprintf(&text, "\nCompanions: %s", companionNames(registration))
+	}
+	fmt.Fprintf(&text, "\nFood Restrictions: %s", registration.FoodRestrictions)
+	if registration.Note != "" {
+		fmt.Fprintf(&text, "\nNote: %s", registration.Note)
+	}
+	return Message{Kind: KindRegistration, Event: registration.Event, Title: "Registration Update: " + registration.Event, Text: text.String()}
+}
+
+// formatAmount renders minor currency units like 1234.50 CZK
+func formatAmount(amount int64, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", amount/100, amount%100, currency)
+}
+
+func paymentMessage(user models.User, registration models.Registration, payment models.Payment) Message {
+	text := fmt.Sprintf("User: %s\nAmount: %s\nMethod: %s", user.Username, formatAmount(payment.Amount, payment.Currency), payment.Method)
+	return Message{Kind: KindPayment, Event: registration.Event, Title: "Payment Received: " + registration.Event, Text: text}
+}
+
+func waitlistPromotionMessage(user models.User, registration models.Registration) Message {
+	text := fmt.Sprintf("User: %s\nStatus: promoted from the waitlist", user.Username)
+	return Message{Kind: KindWaitlistPromotion, Event: registration.Event, Title: "Waitlist Update: " + registration.Event, Text: text}
+}
+
+func rideFullMessage(driver models.User, ride models.Ride) Message {
+	text := fmt.Sprintf("Driver: %s\nFrom: %s\nDeparts: %s\nStatus: all %d seats are taken", driver.Username, ride.Origin, ride.DepartsAt.Format("2006-01-02 15:04"), ride.Seats)
+	return Message{Kind: KindRideFull, Event: ride.Event, Title: "Carpool Update: " + ride.Event, Text: text}
+}
+
+func settlementMessage(event models.Event, currency string, transfers []models.Transfer) Message {
+	lines := make([]string, len(transfers))
+	for i, t := range transfers {
+		lines[i] = fmt.Sprintf("%s -> %s: %s", t.FromUsername, t.ToUsername, formatAmount(t.Amount, currency))
+	}
+	if len(transfers) == 0 {
+		lines = []string{"Everybody is even, nothing to pay"}
+	}
+	return Message{Kind: KindSettlement, Event: event.Code, Title: "Settle Up: " + event.Title, Text: strings.Join(lines, "\n")}
+}
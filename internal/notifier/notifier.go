@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+// Notifier announces activity to the attendees, implemented by Discord and all channel backends
+type Notifier interface {
+	// NotifyAchievement Send a message about the achievement
+	NotifyAchievement(user models.User, achievement models.Achievement, grantor models.User, showGrantor bool) error
+	// NotifyRegistration Notify about registration changes
+	NotifyRegistration(user models.User, registration models.Registration) error
+	// NotifyPayment Announce a payment matched to a registration
+	NotifyPayment(user models.User, registration models.Registration, payment models.Payment) error
+	// NotifyWaitlistPromotion Let the user know their waitlisted registration got a spot
+	NotifyWaitlistPromotion(user models.User, registration models.Registration) error
+	// NotifyRideFull Announce a ride with no free seats left
+	NotifyRideFull(driver models.User, ride models.Ride) error
+	// NotifyRideLeft Let the driver know a passenger left their ride
+	NotifyRideLeft(driver models.User, passenger models.User, ride models.Ride) error
+	// NotifyRideCancelled Let a passenger know their ride was cancelled
+	NotifyRideCancelled(passenger models.User, ride models.Ride) error
+	// NotifySettlement Post the transfers settling the shared expenses of an event
+	NotifySettlement(event models.Event, currency string, transfers []models.Transfer) error
+}
+
+// RoleManager manages the roles of the guild members, implemented by Discord only
+type RoleManager interface {
+	// CreateRole Create a new role in guild
+	CreateRole(name string) (string, error)
+	// GrantRole Grant a role to a user
+	GrantRole(userID string, roleID string) error
+	// GrantRoleByName Grant a role identified by its name to a user
+	GrantRoleByName(userID string, roleName string) error
+	// RemoveRoleByName Remove a role identified by its name from a user
+	RemoveRoleByName(userID string, roleName string) error
+	// HasRole Check if a user has a role
+	HasRole(userID string, roleID string) (bool, error)
+}
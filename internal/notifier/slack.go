@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+)
+
+// SlackChannel posts to a Slack-compatible incoming webhook, Mattermost and Rocket.Chat accept the same payload
+type SlackChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewSlackChannel(webhookURL string) *SlackChannel {
+	return &SlackChannel{webhookURL: webhookURL, client: &http.Client{Timeout: HTTPTimeout}}
+}
+
+func (c *SlackChannel) Name() string {
+	return "slack"
+}
+
+func (c *SlackChannel) Send(ctx context.Context, msg Message) error {
+	payload := map[string]string{"text": "*" + msg.Title + "*\n" + msg.Text}
+	return sendJSON(ctx, c.client, http.MethodPost, c.webhookURL, payload, nil)
+}
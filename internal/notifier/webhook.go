@@ -0,0 +1,48 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the webhook body when a secret is configured
+const SignatureHeader = "X-Garage-Signature-256"
+
+// WebhookChannel posts every message as JSON to a generic HTTP endpoint
+type WebhookChannel struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookChannel(url string, secret string) *WebhookChannel {
+	return &WebhookChannel{url: url, secret: secret, client: &http.Client{Timeout: HTTPTimeout}}
+}
+
+func (c *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Sign returns the signature of the body in the form sha256=<hex>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	var headers map[string]string
+	if c.secret != "" {
+		headers = map[string]string{SignatureHeader: Sign(c.secret, body)}
+	}
+	return send(ctx, c.client, http.MethodPost, c.url, body, headers)
+}
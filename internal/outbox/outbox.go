@@ -0,0 +1,140 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	KindNotifyRegistration      = "notify_registration"
+	KindNotifyWaitlistPromotion = "notify_waitlist_promotion"
+	KindNotifyAchievement       = "notify_achievement"
+	KindNotifyPayment           = "notify_payment"
+	KindNotifyRideFull          = "notify_ride_full"
+	KindNotifyRideLeft          = "notify_ride_left"
+	KindNotifyRideCancelled     = "notify_ride_cancelled"
+	KindNotifySettlement        = "notify_settlement"
+	KindGrantRole               = "grant_role"
+	KindRemoveRole              = "remove_role"
+)
+
+// Payloads only hold IDs, the worker loads the rest when delivering
+
+// RegistrationNotification is the payload of registration notifications, the history entry is the snapshot of the change
+type RegistrationNotification struct {
+	HistoryID uint `json:"history_id"`
+}
+
+type WaitlistPromotionNotification struct {
+	RegistrationID uint `json:"registration_id"`
+}
+
+type AchievementNotification struct {
+	GrantID uint `json:"grant_id"`
+}
+
+type PaymentNotification struct {
+	PaymentID uint `json:"payment_id"`
+}
+
+// RideNotification is the payload of carpool notifications, the passenger is set for rides left or cancelled
+type RideNotification struct {
+	RideID      uint `json:"ride_id"`
+	PassengerID uint `json:"passenger_id,omitempty"`
+}
+
+// SettlementNotification is the payload of settlement notifications, the transfers are computed on delivery
+type SettlementNotification struct {
+	EventID uint `json:"event_id"`
+}
+
+// RoleChange is the payload of role grants and removals, roles are identified by their ID or by their name
+type RoleChange struct {
+	DiscordID string `json:"discord_id"`
+	RoleID    string `json:"role_id,omitempty"`
+	RoleName  string `json:"role_name,omitempty"`
+}
+
+// Enqueue stores the side effect for delivery by the worker, pass the transaction of the change causing it
+func Enqueue(tx *gorm.DB, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", kind, err)
+	}
+	return tx.Create(&models.OutboxItem{
+		Kind:          kind,
+		Payload:       string(data),
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+func NotifyRegistration(tx *gorm.DB, historyID uint) error {
+	return Enqueue(tx, KindNotifyRegistration, RegistrationNotification{HistoryID: historyID})
+}
+
+func NotifyWaitlistPromotion(tx *gorm.DB, registrationID uint) error {
+	return Enqueue(tx, KindNotifyWaitlistPromotion, WaitlistPromotionNotification{RegistrationID: registrationID})
+}
+
+func NotifyAchievement(tx *gorm.DB, grantID uint) error {
+	return Enqueue(tx, KindNotifyAchievement, AchievementNotification{GrantID: grantID})
+}
+
+func NotifyPayment(tx *gorm.DB, paymentID uint) error {
+	return Enqueue(tx, KindNotifyPayment, PaymentNotification{PaymentID: paymentID})
+}
+
+func NotifyRideFull(tx *gorm.DB, rideID uint) error {
+	return Enqueue(tx, KindNotifyRideFull, RideNotification{RideID: rideID})
+}
+
+func NotifyRideLeft(tx *gorm.DB, rideID uint, passengerID uint) error {
+	return Enqueue(tx, KindNotifyRideLeft, RideNotification{RideID: rideID, PassengerID: passengerID})
+}
+
+func NotifyRideCancelled(tx *gorm.DB, rideID uint, passengerID uint) error {
+	return Enqueue(tx, KindNotifyRideCancelled, RideNotification{RideID: rideID, PassengerID: passengerID})
+}
+
+func NotifySettlement(tx *gorm.DB, eventID uint) error {
+	return Enqueue(tx, KindNotifySettlement, SettlementNotification{EventID: eventID})
+}
+
+func GrantRole(tx *gorm.DB, discordID string, roleID string) error {
+	return Enqueue(tx, KindGrantRole, RoleChange{DiscordID: discordID, RoleID: roleID})
+}
+
+func GrantRoleByName(tx *gorm.DB, discordID string, roleName string) error {
+	return Enqueue(tx, KindGrantRole, RoleChange{DiscordID: discordID, RoleName: roleName})
+}
+
+func RemoveRoleByName(tx *gorm.DB, discordID string, roleName string) error {
+	return Enqueue(tx, KindRemoveRole, RoleChange{DiscordID: discordID, RoleName: roleName})
+}
+
+// Replay schedules a delivered or failed item for another round of attempts
+func Replay(db *gorm.DB, item *models.OutboxItem) error {
+	item.Status = models.OutboxPending
+	item.Attempts = 0
+	item.NextAttemptAt = time.Now()
+	item.DeliveredAt = nil
+	return db.Model(item).Select("Status", "Attempts", "NextAttemptAt", "DeliveredAt").Updates(item).Error
+}
+
+// RoleGrantUndelivered reports whether granting the role is still waiting in the outbox or was given up on
+func RoleGrantUndelivered(db *gorm.DB, discordID string, roleID string) (bool, error) {
+	data, err := json.Marshal(RoleChange{DiscordID: discordID, RoleID: roleID})
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = db.Model(&models.OutboxItem{}).
+		Where("kind = ? AND payload = ? AND status <> ?", KindGrantRole, string(data), models.OutboxDelivered).
+		Count(&count).Error
+	return count > 0, err
+}
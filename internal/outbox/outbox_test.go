@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/database"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// flakyNotifier fails while down and records what it delivered otherwise
+type flakyNotifier struct {
+	notifier.Notifier
+	notifier.RoleManager
+	down          bool
+	registrations []models.Registration
+	roles         []string
+}
+
+func (f *flakyNotifier) NotifyRegistration(user models.User, registration models.Registration) error {
+	if f.down {
+		return errors.New("discord unavailable")
+	}
+	f.registrations = append(f.registrations, registration)
+	return nil
+}
+
+func (f *flakyNotifier) GrantRole(discordID string, roleID string) error {
+	if f.down {
+		return errors.New("discord unavailable")
+	}
+	f.roles = append(f.roles, discordID+":"+roleID)
+	return nil
+}
+
+func (f *flakyNotifier) GrantRoleByName(discordID string, roleName string) error {
+	if f.down {
+		return errors.New("discord unavailable")
+	}
+	f.roles = append(f.roles, "+"+discordID+":"+roleName)
+	return nil
+}
+
+func (f *flakyNotifier) RemoveRoleByName(discordID string, roleName string) error {
+	if f.down {
+		return errors.New("discord unavailable")
+	}
+	f.roles = append(f.roles, "-"+discordID+":"+roleName)
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 7: 32 * time.Minute, 8: time.Hour, 20: time.Hour} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestWorker(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "outbox-user", Username: "alice"}
+	db.Create(&user)
+	history := models.RegistrationHistory{RegistrationID: 1, UserID: user.ID, Event: "gt-2026"}
+	db.Create(&history)
+
+	// Nothing is written when the transaction rolls back
+	db.Transaction(func(tx *gorm.DB) error {
+		NotifyRegistration(tx, history.ID)
+		return errors.New("rollback")
+	})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := NotifyRegistration(tx, history.ID); err != nil {
+			return err
+		}
+		return GrantRole(tx, user.DiscordID, "role-1")
+	})
+	if err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	n := &flakyNotifier{down: true}
+	worker := NewWorker(db, map[string]notifier.Notifier{notifier.DiscordChannel: n}, n)
+	now := time.Now()
+
+	if delivered, err := worker.ProcessDue(now); err != nil || delivered != 0 {
+		t.Fatalf("expected no deliveries while Discord is down, got %d %v", delivered, err)
+	}
+	var items []models.OutboxItem
+	db.Order("id ASC").Find(&items)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 outbox items, got %d", len(items))
+	}
+	for _, item := range items {
+		if item.Status != models.OutboxPending || item.Attempts != 1 || item.LastError != "discord unavailable" {
+			t.Errorf("unexpected item after failure %+v", item)
+		}
+		if !item.NextAttemptAt.Equal(now.Add(BaseDelay)) {
+			t.Errorf("expected retry after %v, got %v", BaseDelay, item.NextAttemptAt.Sub(now))
+		}
+	}
+
+	// Items are not retried before their backoff elapses
+	n.down = false
+	if delivered, _ := worker.ProcessDue(now.Add(time.Second)); delivered != 0 {
+		t.Errorf("expected nothing due yet, delivered %d", delivered)
+	}
+	if delivered, err := worker.ProcessDue(now.Add(BaseDelay)); err != nil || delivered != 2 {
+		t.Fatalf("expected both items delivered, got %d %v", delivered, err)
+	}
+	if len(n.registrations) != 1 || n.registrations[0].Event != "gt-2026" || n.registrations[0].ID != 1 || len(n.roles) != 1 || n.roles[0] != "outbox-user:role-1" {
+		t.Errorf("unexpected deliveries %+v %v", n.registrations, n.roles)
+	}
+	var delivered models.OutboxItem
+	db.First(&delivered, items[0].ID)
+	if delivered.Status != models.OutboxDelivered || delivered.DeliveredAt == nil || delivered.Channel != notifier.DiscordChannel {
+		t.Errorf("expected delivered item, got %+v", delivered)
+	}
+
+	// Items are given up on after the maximum number of attempts and can be replayed
+	n.down = true
+	if err := NotifyRegistration(db, history.ID); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	at := time.Now()
+	for i := 0; i < MaxAttempts; i++ {
+		worker.ProcessDue(at)
+		at = at.Add(MaxDelay)
+	}
+	var failed models.OutboxItem
+	db.Last(&failed)
+	if failed.Status != models.OutboxFailed || failed.Attempts != MaxAttempts {
+		t.Fatalf("expected item to fail after %d attempts, got %+v", MaxAttempts, failed)
+	}
+	worker.ProcessDue(at)
+	db.First(&failed, failed.ID)
+	if failed.Attempts != MaxAttempts {
+		t.Errorf("expected failed item to be left alone, got %d attempts", failed.Attempts)
+	}
+
+	if err := Replay(db, &failed); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	n.down = false
+	if delivered, err := worker.ProcessDue(time.Now()); err != nil || delivered != 1 {
+		t.Fatalf("expected the replayed item to be delivered, got %d %v", delivered, err)
+	}
+	db.First(&failed, failed.ID)
+	if failed.Status != models.OutboxDelivered || failed.Attempts != 1 {
+		t.Errorf("unexpected replayed item %+v", failed)
+	}
+}
+
+func TestWorker_Channels(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	user := models.User{DiscordID: "channel-user", Username: "bob"}
+	db.Create(&user)
+	history := models.RegistrationHistory{RegistrationID: 1, UserID: user.ID, Event: "gt-2026"}
+	db.Create(&history)
+	NotifyRegistration(db, history.ID)
+	GrantRoleByName(db, user.DiscordID, "gt-2026::paid")
+
+	// Each channel gets its own item, a failing one is retried without resending to the others
+	discord := &flakyNotifier{}
+	slack := &flakyNotifier{down: true}
+	worker := NewWorker(db, map[string]notifier.Notifier{notifier.DiscordChannel: discord, "slack": slack}, nil)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		worker.ProcessDue(now)
+		now = now.Add(MaxDelay)
+	}
+	slack.down = false
+	worker.ProcessDue(now)
+	if len(discord.registrations) != 1 || len(slack.registrations) != 1 {
+		t.Errorf("expected one registration per channel, got discord %d slack %d", len(discord.registrations), len(slack.registrations))
+	}
+
+	var items []models.OutboxItem
+	db.Where("kind = ?", KindNotifyRegistration).Order("channel ASC").Find(&items)
+	if len(items) != 2 || items[0].Channel != notifier.DiscordChannel || items[0].Attempts != 1 || items[1].Channel != "slack" || items[1].Status != models.OutboxDelivered {
+		t.Errorf("unexpected items per channel %+v", items)
+	}
+
+	// Role changes without Discord are given up on right away
+	var role models.OutboxItem
+	db.Where("kind = ?", KindGrantRole).First(&role)
+	if role.Status != models.OutboxFailed || role.Attempts != 1 {
+		t.Errorf("expected the role change to fail without Discord, got %+v", role)
+	}
+}
+
+func TestWorker_RoleChangesInOrder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	database.Migrate(db)
+
+	GrantRoleByName(db, "member", "gt-2026::paid")
+	RemoveRoleByName(db, "member", "gt-2026::paid")
+	GrantRoleByName(db, "other", "gt-2026::paid")
+
+	n := &flakyNotifier{down: true}
+	worker := NewWorker(db, map[string]notifier.Notifier{notifier.DiscordChannel: n}, n)
+	now := time.Now()
+	worker.ProcessDue(now)
+
+	// The removal waits for the grant before it, the other member is not held up
+	var removal models.OutboxItem
+	db.Where("kind = ?", KindRemoveRole).First(&removal)
+	if removal.Attempts != 0 {
+		t.Errorf("expected the removal to wait for the grant, got %d attempts", removal.Attempts)
+	}
+
+	n.down = false
+	worker.ProcessDue(now.Add(BaseDelay))
+	want := []string{"+member:gt-2026::paid", "-member:gt-2026::paid", "+other:gt-2026::paid"}
+	if !slices.Equal(n.roles, want) {
+		t.Errorf("expected role changes %v, got %v", want, n.roles)
+	}
+}
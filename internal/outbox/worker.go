@@ -0,0 +1,308 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/billing"
+	"github.com/gdg-garage/garage-trip-api/internal/expenses"
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+	"github.com/gdg-garage/garage-trip-api/internal/notifier"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttempts is the number of deliveries tried before an item is marked failed
+	MaxAttempts = 8
+	BaseDelay   = 30 * time.Second
+	MaxDelay    = time.Hour
+)
+
+// Backoff returns the delay before the next attempt, doubling after each failed one
+func Backoff(attempts int) time.Duration {
+	delay := BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxDelay {
+			return MaxDelay
+		}
+	}
+	return delay
+}
+
+// Worker delivers the pending outbox items
+type Worker struct {
+	db        *gorm.DB
+	notifiers map[string]notifier.Notifier // Keyed by channel name
+	channels  []string
+	roles     notifier.RoleManager
+	Interval  time.Duration
+	BatchSize int
+}
+
+// NewWorker delivers notifications to every notifier and role changes through the role manager, which is nil without Discord
+func NewWorker(db *gorm.DB, notifiers map[string]notifier.Notifier, roles notifier.RoleManager) *Worker {
+	return &Worker{
+		db:        db,
+		notifiers: notifiers,
+		channels:  slices.Sorted(maps.Keys(notifiers)),
+		roles:     roles,
+		Interval:  5 * time.Second,
+		BatchSize: 50,
+	}
+}
+
+// permanentError is a failure which retrying does not fix, the item is marked failed right away
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Run processes due items until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.ProcessDue(time.Now()); err != nil {
+			log.Printf("Failed to process outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue tries to deliver the items due at the given time in the order they were written
+// and returns how many were delivered
+func (w *Worker) ProcessDue(now time.Time) (int, error) {
+	var items []models.OutboxItem
+	err := w.db.Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+		Order("id ASC").
+		Limit(w.BatchSize).
+		Find(&items).Error
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, item := range items {
+		if isRoleChange(item.Kind) {
+			waiting, err := w.waitsForEarlierRoleChange(item)
+			if err != nil {
+				return delivered, err
+			}
+			if waiting {
+				continue
+			}
+		} else if item.Channel == "" {
+			if err := w.splitByChannel(&item, now); err != nil {
+				return delivered, err
+			}
+		}
+
+		item.Attempts++
+		if err := w.deliver(item); err != nil {
+			item.LastError = err.Error()
+			var permanent permanentError
+			if errors.As(err, &permanent) || item.Attempts >= MaxAttempts {
+				item.Status = models.OutboxFailed
+				log.Printf("Giving up on outbox item %d (%s) after %d attempts: %v", item.ID, item.Kind, item.Attempts, err)
+			} else {
+				item.NextAttemptAt = now.Add(Backoff(item.Attempts))
+			}
+		} else {
+			item.Status = models.OutboxDelivered
+			item.DeliveredAt = &now
+			delivered++
+		}
+		if err := w.db.Model(&item).Select("Status", "Attempts", "NextAttemptAt", "LastError", "DeliveredAt").Updates(&item).Error; err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func isRoleChange(kind string) bool {
+	return kind == KindGrantRole || kind == KindRemoveRole
+}
+
+// splitByChannel assigns the notification to the first channel and copies it for the others,
+// so a failing channel is retried alone and the others do not get the message twice
+func (w *Worker) splitByChannel(item *models.OutboxItem, now time.Time) error {
+	if len(w.channels) == 0 {
+		return nil
+	}
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		for _, channel := range w.channels[1:] {
+			err := tx.Create(&models.OutboxItem{
+				Kind:          item.Kind,
+				Channel:       channel,
+				Payload:       item.Payload,
+				Status:        models.OutboxPending,
+				NextAttemptAt: now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		item.Channel = w.channels[0]
+		return tx.Model(item).Update("channel", item.Channel).Error
+	})
+}
+
+// waitsForEarlierRoleChange keeps the role changes of a member in order, a later one waits while an earlier one is retried
+func (w *Worker) waitsForEarlierRoleChange(item models.OutboxItem) (bool, error) {
+	var count int64
+	err := w.db.Model(&models.OutboxItem{}).
+		Where("kind IN ? AND payload = ? AND status = ? AND id < ?", []string{KindGrantRole, KindRemoveRole}, item.Payload, models.OutboxPending, item.ID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// load fetches a record referenced by a payload, including deleted ones, a missing record is a permanent error
+func load(query *gorm.DB, dest any, id uint) error {
+	err := query.Unscoped().First(dest, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return permanentError{fmt.Errorf("%T %d not found", dest, id)}
+	}
+	return err
+}
+
+func decode(item models.OutboxItem, payload any) error {
+	if err := json.Unmarshal([]byte(item.Payload), payload); err != nil {
+		return permanentError{fmt.Errorf("invalid payload: %w", err)}
+	}
+	return nil
+}
+
+func (w *Worker) deliver(item models.OutboxItem) error {
+	if isRoleChange(item.Kind) {
+		return w.deliverRoleChange(item)
+	}
+
+	n, ok := w.notifiers[item.Channel]
+	if !ok {
+		return permanentError{fmt.Errorf("notification channel %q is not configured", item.Channel)}
+	}
+
+	switch item.Kind {
+	case KindNotifyRegistration:
+		var p RegistrationNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var history models.RegistrationHistory
+		if err := load(w.db, &history, p.HistoryID); err != nil {
+			return err
+		}
+		var user models.User
+		if err := load(w.db, &user, history.UserID); err != nil {
+			return err
+		}
+		registration := models.Registration{UserID: history.UserID, Event: history.Event, EventID: history.EventID, RegistrationFields: history.RegistrationFields}
+		registration.ID = history.RegistrationID
+		return n.NotifyRegistration(user, registration)
+	case KindNotifyWaitlistPromotion:
+		var p WaitlistPromotionNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var registration models.Registration
+		if err := load(w.db.Preload("User"), &registration, p.RegistrationID); err != nil {
+			return err
+		}
+		return n.NotifyWaitlistPromotion(registration.User, registration)
+	case KindNotifyAchievement:
+		var p AchievementNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var grant models.AchievementGrant
+		if err := load(w.db.Preload("Achievement").Preload("User").Preload("GrantedBy"), &grant, p.GrantID); err != nil {
+			return err
+		}
+		return n.NotifyAchievement(grant.User, grant.Achievement, grant.GrantedBy, grant.UserID != grant.GrantedByID)
+	case KindNotifyPayment:
+		var p PaymentNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var payment models.Payment
+		if err := load(w.db, &payment, p.PaymentID); err != nil {
+			return err
+		}
+		var registration models.Registration
+		if err := load(w.db.Preload("User"), &registration, payment.RegistrationID); err != nil {
+			return err
+		}
+		return n.NotifyPayment(registration.User, registration, payment)
+	case KindNotifyRideFull, KindNotifyRideLeft, KindNotifyRideCancelled:
+		var p RideNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var ride models.Ride
+		if err := load(w.db.Preload("Driver"), &ride, p.RideID); err != nil {
+			return err
+		}
+		if item.Kind == KindNotifyRideFull {
+			return n.NotifyRideFull(ride.Driver, ride)
+		}
+		var passenger models.User
+		if err := load(w.db, &passenger, p.PassengerID); err != nil {
+			return err
+		}
+		if item.Kind == KindNotifyRideLeft {
+			return n.NotifyRideLeft(ride.Driver, passenger, ride)
+		}
+		return n.NotifyRideCancelled(passenger, ride)
+	case KindNotifySettlement:
+		var p SettlementNotification
+		if err := decode(item, &p); err != nil {
+			return err
+		}
+		var event models.Event
+		if err := load(w.db, &event, p.EventID); err != nil {
+			return err
+		}
+		list, err := expenses.Load(w.db, event.ID)
+		if err != nil {
+			return err
+		}
+		return n.NotifySettlement(event, billing.Currency(event), expenses.Settle(expenses.Balances(list)))
+	}
+	return permanentError{fmt.Errorf("unknown outbox item kind %s", item.Kind)}
+}
+
+func (w *Worker) deliverRoleChange(item models.OutboxItem) error {
+	if w.roles == nil {
+		return permanentError{errors.New("discord is not configured")}
+	}
+	var p RoleChange
+	if err := decode(item, &p); err != nil {
+		return err
+	}
+	switch {
+	case item.Kind == KindRemoveRole:
+		return w.roles.RemoveRoleByName(p.DiscordID, p.RoleName)
+	case p.RoleID != "":
+		return w.roles.GrantRole(p.DiscordID, p.RoleID)
+	default:
+		return w.roles.GrantRoleByName(p.DiscordID, p.RoleName)
+	}
+}
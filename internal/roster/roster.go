@@ -0,0 +1,87 @@
+// Package roster checks volunteer shift signups and reports how duties are shared
+package roster
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// WithinStay reports whether the time window fits the stay. Stays are compared by whole days,
+// from the start of the arrival day to the end of the departure day.
+func WithinStay(from time.Time, to time.Time, arrival time.Time, departure time.Time) bool {
+	return !from.Before(day(arrival)) && !to.After(day(departure).AddDate(0, 0, 1))
+}
+
+// Overlaps reports whether the shifts share any time, shifts only touching each other do not overlap
+func Overlaps(a models.Shift, b models.Shift) bool {
+	return a.StartsAt.Before(b.EndsAt) && b.StartsAt.Before(a.EndsAt)
+}
+
+// Conflicts returns the other shifts overlapping the shift
+func Conflicts(shift models.Shift, others []models.Shift) []models.Shift {
+	var conflicts []models.Shift
+	for _, o := range others {
+		if o.ID != shift.ID && Overlaps(shift, o) {
+			conflicts = append(conflicts, o)
+		}
+	}
+	return conflicts
+}
+
+// Duty is the share of the shifts done by a single attendee
+type Duty struct {
+	RegistrationID uint    `json:"registration_id"`
+	Username       string  `json:"username"`
+	Shifts         int     `json:"shifts"`
+	Hours          float64 `json:"hours"`
+	StayDays       int     `json:"stay_days" doc:"Days spent at the event, arrival and departure included"`
+}
+
+// Fairness counts the shifts of the confirmed registrations, users must be loaded. Attendees without
+// a shift are listed too, the busiest come first.
+func Fairness(registrations []models.Registration, shifts []models.Shift) []Duty {
+	byRegistration := make(map[uint]*Duty)
+	var duties []*Duty
+	for _, r := range registrations {
+		if !r.Confirmed() {
+			continue
+		}
+		d := &Duty{
+			RegistrationID: r.ID,
+			Username:       r.User.Username,
+			StayDays:       int(day(r.DepartureDate).Sub(day(r.ArrivalDate)).Hours()/24) + 1,
+		}
+		byRegistration[r.ID] = d
+		duties = append(duties, d)
+	}
+
+	for _, s := range shifts {
+		for _, signup := range s.Signups {
+			if d, ok := byRegistration[signup.RegistrationID]; ok {
+				d.Shifts++
+				d.Hours += s.Duration().Hours()
+			}
+		}
+	}
+
+	slices.SortFunc(duties, func(a *Duty, b *Duty) int {
+		return cmp.Or(
+			cmp.Compare(b.Shifts, a.Shifts),
+			cmp.Compare(b.Hours, a.Hours),
+			strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username)),
+		)
+	})
+	report := make([]Duty, len(duties))
+	for i, d := range duties {
+		report[i] = *d
+	}
+	return report
+}
@@ -0,0 +1,72 @@
+package roster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-api/internal/models"
+)
+
+func at(d int, hour int) time.Time {
+	return time.Date(2025, 7, d, hour, 0, 0, 0, time.UTC)
+}
+
+func TestWithinStay(t *testing.T) {
+	dinner := models.Shift{StartsAt: at(1, 17), EndsAt: at(1, 19)}
+	if !WithinStay(dinner.StartsAt, dinner.EndsAt, at(1, 18), at(3, 0)) {
+		t.Error("expected shift on the arrival day to fit")
+	}
+	if !WithinStay(at(3, 8), at(3, 10), at(1, 0), at(3, 0)) {
+		t.Error("expected shift on the departure day to fit")
+	}
+	if WithinStay(dinner.StartsAt, dinner.EndsAt, at(2, 0), at(3, 0)) {
+		t.Error("expected shift before the arrival to be rejected")
+	}
+	if WithinStay(at(3, 23), at(4, 1), at(1, 0), at(3, 0)) {
+		t.Error("expected shift past the departure day to be rejected")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	shift := models.Shift{StartsAt: at(1, 17), EndsAt: at(1, 19)}
+	shift.ID = 1
+	before := models.Shift{StartsAt: at(1, 15), EndsAt: at(1, 17)}
+	during := models.Shift{StartsAt: at(1, 18), EndsAt: at(1, 20)}
+	if Overlaps(shift, before) {
+		t.Error("expected touching shifts not to overlap")
+	}
+	if c := Conflicts(shift, []models.Shift{before, during, shift}); len(c) != 1 || !c[0].StartsAt.Equal(during.StartsAt) {
+		t.Errorf("expected a single conflict, got %v", c)
+	}
+}
+
+func TestFairness(t *testing.T) {
+	registration := func(id uint, username string, fields models.RegistrationFields) models.Registration {
+		r := models.Registration{User: models.User{Username: username}, RegistrationFields: fields}
+		r.ID = id
+		r.ArrivalDate = at(1, 0)
+		r.DepartureDate = at(3, 0)
+		return r
+	}
+	registrations := []models.Registration{
+		registration(1, "bob", models.RegistrationFields{}),
+		registration(2, "alice", models.RegistrationFields{}),
+		registration(3, "carol", models.RegistrationFields{}),
+		registration(4, "dave", models.RegistrationFields{Cancelled: true}),
+	}
+	shifts := []models.Shift{
+		{StartsAt: at(1, 17), EndsAt: at(1, 19), Signups: []models.ShiftSignup{{RegistrationID: 1}, {RegistrationID: 2}}},
+		{StartsAt: at(2, 8), EndsAt: at(2, 9), Signups: []models.ShiftSignup{{RegistrationID: 1}, {RegistrationID: 4}}},
+	}
+
+	report := Fairness(registrations, shifts)
+	if len(report) != 3 {
+		t.Fatalf("expected confirmed attendees only, got %+v", report)
+	}
+	if report[0].Username != "bob" || report[0].Shifts != 2 || report[0].Hours != 3 || report[0].StayDays != 3 {
+		t.Errorf("unexpected busiest attendee %+v", report[0])
+	}
+	if report[2].Username != "carol" || report[2].Shifts != 0 {
+		t.Errorf("expected attendee without shifts last, got %+v", report[2])
+	}
+}